@@ -0,0 +1,214 @@
+// Package adaptive implements a codec that chooses the smallest encoding for each
+// block of values.
+//
+// Every block is encoded with each of a fixed set of codecs, simple8b, its RLE
+// variant and the fixed width codec of this package, and the smallest output is
+// kept.  The set does not depend on which packages a program imports, so the same
+// values always encode to the same bytes.  A column of values that changes
+// character, such as runs of constants followed by noisy counters, is then stored
+// using the best codec for each part.
+//
+// EncodeAll accepts further registered codecs to try as well.  Codec, which is
+// used through the registry of the encoding package, has no way to be given them
+// and always uses the fixed set.  Blocks are decoded with the registered codec
+// named in their header, whichever codecs encoded them.
+package adaptive
+
+// The encoded form is a sequence of blocks, each starting with a header holding
+// the ID of the codec used, the number of values and the length of the encoded
+// values in bytes:
+//
+// ┌──────────┬──────────┬──────────┬─────────────────────────────────┐
+// │ Codec ID │  Count   │  Length  │          Encoded values         │
+// ├──────────┼──────────┼──────────┼─────────────────────────────────┤
+// │  1 byte  │ uvarint  │ uvarint  │         Length bytes            │
+// └──────────┴──────────┴──────────┴─────────────────────────────────┘
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jwilder/encoding"
+	"github.com/jwilder/encoding/simple8b"
+)
+
+// BlockSize is the number of values in each block.
+const BlockSize = 1024
+
+const maxHeaderLen = 1 + 2*binary.MaxVarintLen64
+
+func init() {
+	encoding.Register(FixedCodec{})
+	encoding.Register(Codec{})
+}
+
+// Codec implements encoding.Codec by encoding each block of values with the
+// default codec giving the smallest output.
+type Codec struct{}
+
+// Name returns "adaptive".
+func (Codec) Name() string { return "adaptive" }
+
+// ID returns encoding.Adaptive.
+func (Codec) ID() byte { return encoding.Adaptive }
+
+// MaxEncodedLen returns the maximum number of bytes used to encode n values.  The
+// fixed width codec accepts any values, so no block is larger than its output.
+func (Codec) MaxEncodedLen(n int) int {
+	blocks := (n + BlockSize - 1) / BlockSize
+	return blocks*(maxHeaderLen+FixedCodec{}.MaxEncodedLen(0)) + 8*n
+}
+
+// Encode appends the encoded blocks of src to dst.
+func (Codec) Encode(dst []byte, src []uint64) ([]byte, error) {
+	return encode(dst, src, defaultCodecs)
+}
+
+// Decode writes the values of the blocks in src to dst.  It returns the number of
+// values written or an error.
+func (Codec) Decode(dst []uint64, src []byte) (int, error) {
+	return DecodeAll(dst, src)
+}
+
+// defaultCodecs are the codecs tried for every block.  Changing them changes the
+// encoded output, so they are fixed rather than taken from the registry.
+var defaultCodecs = []encoding.Codec{
+	simple8b.Codec{},
+	simple8b.RLECodec{},
+	FixedCodec{},
+}
+
+// EncodeAll returns the blocks of src, each encoded with the codec giving the
+// smallest output.  The default codecs are simple8b, simple8b RLE and fixed width;
+// extra codecs are tried as well and must be registered so the blocks they encode
+// can be decoded.
+func EncodeAll(src []uint64, extra ...encoding.Codec) ([]byte, error) {
+	codecs := defaultCodecs
+	if len(extra) > 0 {
+		codecs = append(codecs[:len(codecs):len(codecs)], extra...)
+		for _, c := range extra {
+			if c.ID() == encoding.Adaptive {
+				return nil, fmt.Errorf("nested adaptive codec")
+			}
+			if r, ok := encoding.Lookup(c.ID()); !ok || r.Name() != c.Name() {
+				return nil, fmt.Errorf("codec not registered: %s", c.Name())
+			}
+		}
+	}
+	return encode(nil, src, codecs)
+}
+
+func encode(dst []byte, src []uint64, codecs []encoding.Codec) ([]byte, error) {
+	var best, scratch []byte
+	for len(src) > 0 {
+		n := BlockSize
+		if len(src) < n {
+			n = len(src)
+		}
+
+		var id byte
+		for _, c := range codecs {
+			b, err := c.Encode(scratch[:0], src[:n])
+			if err != nil {
+				// The values are out of the range of the codec.
+				continue
+			}
+			if id == 0 || len(b) < len(best) {
+				id = c.ID()
+				best, scratch = b, best
+			} else {
+				scratch = b
+			}
+		}
+		if id == 0 {
+			return nil, fmt.Errorf("no codec can encode block")
+		}
+
+		var b [binary.MaxVarintLen64]byte
+		dst = append(dst, id)
+		dst = append(dst, b[:binary.PutUvarint(b[:], uint64(n))]...)
+		dst = append(dst, b[:binary.PutUvarint(b[:], uint64(len(best)))]...)
+		dst = append(dst, best...)
+		src = src[n:]
+	}
+	return dst, nil
+}
+
+// DecodeAll writes the values of the blocks in src to dst, decoding each with the
+// codec named in its header.  It returns the number of values written or an error.
+func DecodeAll(dst []uint64, src []byte) (int, error) {
+	j := 0
+	for len(src) > 0 {
+		c, count, b, rest, err := readBlock(src)
+		if err != nil {
+			return 0, err
+		}
+		if count > len(dst)-j {
+			return 0, fmt.Errorf("dst too small: need %d values, have %d", j+count, len(dst))
+		}
+
+		n, err := c.Decode(dst[j:j+count], b)
+		if err != nil {
+			return 0, err
+		}
+		if n != count {
+			return 0, fmt.Errorf("block decoded %d values, header has %d", n, count)
+		}
+		j += n
+		src = rest
+	}
+	return j, nil
+}
+
+// CountBytes returns the number of values encoded in the byte slice
+func CountBytes(src []byte) (int, error) {
+	var total int
+	for len(src) > 0 {
+		_, count, _, rest, err := readBlock(src)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+		src = rest
+	}
+	return total, nil
+}
+
+// Codecs returns the IDs of the codecs used by each block of src, in order.
+func Codecs(src []byte) ([]byte, error) {
+	var ids []byte
+	for len(src) > 0 {
+		c, _, _, rest, err := readBlock(src)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, c.ID())
+		src = rest
+	}
+	return ids, nil
+}
+
+// readBlock returns the codec, value count and encoded values of the first block
+// in src and the bytes following it.
+func readBlock(src []byte) (c encoding.Codec, count int, b, rest []byte, err error) {
+	c, src, err = encoding.ReadHeader(src)
+	if err != nil {
+		return nil, 0, nil, nil, err
+	}
+	if c.ID() == encoding.Adaptive {
+		return nil, 0, nil, nil, fmt.Errorf("nested adaptive block")
+	}
+
+	v, n := binary.Uvarint(src)
+	if n <= 0 || v > BlockSize {
+		return nil, 0, nil, nil, fmt.Errorf("invalid block count")
+	}
+	count = int(v)
+	src = src[n:]
+
+	l, n := binary.Uvarint(src)
+	if n <= 0 || l > uint64(len(src)-n) {
+		return nil, 0, nil, nil, fmt.Errorf("invalid block length")
+	}
+	src = src[n:]
+	return c, count, src[:l], src[l:], nil
+}
@@ -0,0 +1,312 @@
+package adaptive_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/jwilder/encoding"
+	"github.com/jwilder/encoding/adaptive"
+	"github.com/jwilder/encoding/simple8b"
+)
+
+func roundTrip(t *testing.T, in []uint64) []byte {
+	b, err := adaptive.EncodeAll(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if max := (adaptive.Codec{}).MaxEncodedLen(len(in)); len(b) > max {
+		t.Fatalf("Encoded len %d exceeds max %d", len(b), max)
+	}
+
+	if n, err := adaptive.CountBytes(b); err != nil || n != len(in) {
+		t.Fatalf("Count mismatch: got %v %v, exp %v", n, err, len(in))
+	}
+
+	out := make([]uint64, len(in))
+	n, err := adaptive.DecodeAll(out, b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("Len mismatch: got %v, exp %v", n, len(in))
+	}
+	for i := range in {
+		if out[i] != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], out[i])
+		}
+	}
+	return b
+}
+
+func Test_Encode_NoValues(t *testing.T) {
+	b := roundTrip(t, nil)
+	if len(b) != 0 {
+		t.Fatalf("Encoded len mismatch: got %v, exp 0", len(b))
+	}
+}
+
+func Test_Encode_PicksPerBlock(t *testing.T) {
+	var in []uint64
+
+	// A block of a constant, then small noisy values, then values too large for
+	// simple8b.
+	for i := 0; i < adaptive.BlockSize; i++ {
+		in = append(in, 42)
+	}
+	for i := 0; i < adaptive.BlockSize; i++ {
+		in = append(in, uint64(rand.Intn(1000)))
+	}
+	for i := 0; i < adaptive.BlockSize/2; i++ {
+		in = append(in, rand.Uint64())
+	}
+
+	b := roundTrip(t, in)
+	ids, err := adaptive.Codecs(b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	exp := []byte{encoding.Simple8bRLE, encoding.Fixed, encoding.Fixed}
+	if len(ids) != len(exp) {
+		t.Fatalf("Blocks mismatch: got %v, exp %v", ids, exp)
+	}
+	for i := range exp {
+		if ids[i] != exp[i] {
+			t.Fatalf("Block %d codec = %d, exp %d", i, ids[i], exp[i])
+		}
+	}
+}
+
+func Test_Encode_NotLarger(t *testing.T) {
+	in := make([]uint64, 5000)
+	for i := range in {
+		if i/500%2 == 0 {
+			in[i] = uint64(i / 100)
+		} else {
+			in[i] = uint64(rand.Int63n(1 << uint(rand.Intn(40))))
+		}
+	}
+
+	b := roundTrip(t, in)
+	s8b, _ := simple8b.Codec{}.Encode(nil, in)
+	if len(b) > len(s8b) {
+		t.Fatalf("Adaptive len %d larger than simple8b %d", len(b), len(s8b))
+	}
+}
+
+// constCodec encodes blocks holding a single repeated value as that value, which
+// is smaller than any of the default codecs manage.
+type constCodec struct{}
+
+func (constCodec) Name() string            { return "adaptive-test-const" }
+func (constCodec) ID() byte                { return 200 }
+func (constCodec) MaxEncodedLen(n int) int { return binary.MaxVarintLen64 }
+
+func (constCodec) Encode(dst []byte, src []uint64) ([]byte, error) {
+	for _, v := range src {
+		if v != src[0] {
+			return nil, fmt.Errorf("values not constant")
+		}
+	}
+	var b [binary.MaxVarintLen64]byte
+	return append(dst, b[:binary.PutUvarint(b[:], src[0])]...), nil
+}
+
+func (constCodec) Decode(dst []uint64, src []byte) (int, error) {
+	v, n := binary.Uvarint(src)
+	if n <= 0 || n != len(src) {
+		return 0, fmt.Errorf("invalid value")
+	}
+	for i := range dst {
+		dst[i] = v
+	}
+	return len(dst), nil
+}
+
+func init() {
+	encoding.Register(constCodec{})
+}
+
+func Test_Encode_DefaultCodecs(t *testing.T) {
+	in := make([]uint64, 2*adaptive.BlockSize)
+	for i := range in {
+		in[i] = 1 << 40
+	}
+
+	// The registered test codec would win every block, but is only tried when
+	// passed explicitly.
+	ids, err := adaptive.Codecs(roundTrip(t, in))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i, id := range ids {
+		if id == (constCodec{}).ID() {
+			t.Fatalf("Block %d codec = %d, exp a default codec", i, id)
+		}
+	}
+
+	b, err := adaptive.EncodeAll(in, constCodec{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ids, err = adaptive.Codecs(b); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i, id := range ids {
+		if id != (constCodec{}).ID() {
+			t.Fatalf("Block %d codec = %d, exp %d", i, id, (constCodec{}).ID())
+		}
+	}
+
+	out := make([]uint64, len(in))
+	n, err := adaptive.DecodeAll(out, b)
+	if err != nil || n != len(in) {
+		t.Fatalf("Decode mismatch: got %v %v, exp %v", n, err, len(in))
+	}
+	for i := range in {
+		if out[i] != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], out[i])
+		}
+	}
+}
+
+func Test_Encode_ExtraCodecInvalid(t *testing.T) {
+	in := []uint64{1, 2, 3}
+	if _, err := adaptive.EncodeAll(in, adaptive.Codec{}); err == nil {
+		t.Fatalf("Expected error for nested adaptive codec, got nil")
+	}
+	if _, err := adaptive.EncodeAll(in, unregisteredCodec{}); err == nil {
+		t.Fatalf("Expected error for unregistered codec, got nil")
+	}
+}
+
+// unregisteredCodec shares the ID of the test codec under another name.
+type unregisteredCodec struct{ constCodec }
+
+func (unregisteredCodec) Name() string { return "adaptive-test-unregistered" }
+
+func Test_Codec_Registered(t *testing.T) {
+	in := []uint64{1, 2, 3, 1 << 63}
+	b, err := encoding.Encode(adaptive.Codec{}, nil, in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	out := make([]uint64, len(in))
+	n, err := encoding.Decode(out, b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) || out[3] != 1<<63 {
+		t.Fatalf("Decoded mismatch: got %v", out[:n])
+	}
+}
+
+func Test_FixedCodec(t *testing.T) {
+	for width := 0; width <= 64; width++ {
+		in := make([]uint64, 37)
+		for i := range in {
+			in[i] = rand.Uint64() >> uint(64-width)
+			if width == 0 {
+				in[i] = 0
+			}
+		}
+
+		c := adaptive.FixedCodec{}
+		b, err := c.Encode(nil, in)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(b) > c.MaxEncodedLen(len(in)) {
+			t.Fatalf("width %d: encoded len %d exceeds max", width, len(b))
+		}
+
+		out := make([]uint64, len(in))
+		n, err := c.Decode(out, b)
+		if err != nil {
+			t.Fatalf("width %d: unexpected error: %v", width, err)
+		}
+		if n != len(in) {
+			t.Fatalf("width %d: len mismatch: got %v, exp %v", width, n, len(in))
+		}
+		for i := range in {
+			if out[i] != in[i] {
+				t.Fatalf("width %d: decoded[%d] != %v, got %v", width, i, in[i], out[i])
+			}
+		}
+
+		if len(b) > 2 {
+			if _, err := c.Decode(out, b[:len(b)-1]); err == nil {
+				t.Fatalf("width %d: expected error for truncated input, got nil", width)
+			}
+		}
+	}
+}
+
+func Test_Decode_Invalid(t *testing.T) {
+	in := make([]uint64, 3000)
+	for i := range in {
+		in[i] = uint64(i)
+	}
+	b, _ := adaptive.EncodeAll(in)
+
+	if _, err := adaptive.DecodeAll(make([]uint64, 2999), b); err == nil {
+		t.Fatalf("Expected error for short dst, got nil")
+	}
+	if _, err := adaptive.DecodeAll(make([]uint64, 3000), b[:len(b)-1]); err == nil {
+		t.Fatalf("Expected error for truncated input, got nil")
+	}
+
+	b[0] = 0
+	if _, err := adaptive.DecodeAll(make([]uint64, 3000), b); err == nil {
+		t.Fatalf("Expected error for invalid codec, got nil")
+	}
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	f.Add([]byte(nil))
+	for _, n := range []int{3, 1000} {
+		in := make([]uint64, n)
+		for i := range in {
+			in[i] = uint64(i*i) % 5000
+		}
+		b, _ := adaptive.EncodeAll(in)
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := adaptive.CountBytes(b)
+		if err != nil || n > 1<<20 {
+			return
+		}
+
+		dst := make([]uint64, n)
+		got, err := adaptive.DecodeAll(dst, b)
+		if err != nil {
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAll mismatch: got %v, exp %v", got, n)
+		}
+		if n > 0 {
+			if _, err := adaptive.DecodeAll(make([]uint64, n-1), b); err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+		}
+	})
+}
+
+func BenchmarkEncodeAll(b *testing.B) {
+	x := make([]uint64, 4096)
+	for i := range x {
+		x[i] = uint64(rand.Intn(1000))
+	}
+
+	b.SetBytes(int64(len(x) * 8))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		adaptive.EncodeAll(x)
+	}
+}
@@ -0,0 +1,116 @@
+package adaptive
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/jwilder/encoding"
+)
+
+// FixedCodec implements encoding.Codec by storing every value at the bit width of
+// the largest one.  It accepts any uint64 and its size is known in advance, which
+// makes it the fallback for values that no other codec packs well.
+//
+// The encoded form is a byte holding the bit width, the number of values as a
+// uvarint and then the values packed least significant bit first.
+type FixedCodec struct{}
+
+// Name returns "fixed".
+func (FixedCodec) Name() string { return "fixed" }
+
+// ID returns encoding.Fixed.
+func (FixedCodec) ID() byte { return encoding.Fixed }
+
+// MaxEncodedLen returns the maximum number of bytes used to encode n values.
+func (FixedCodec) MaxEncodedLen(n int) int {
+	return 1 + binary.MaxVarintLen64 + 8*n
+}
+
+// Encode appends the packed values of src to dst.
+func (FixedCodec) Encode(dst []byte, src []uint64) ([]byte, error) {
+	var max uint64
+	for _, v := range src {
+		max |= v
+	}
+	width := uint(bits.Len64(max))
+
+	var b [binary.MaxVarintLen64]byte
+	dst = append(dst, byte(width))
+	dst = append(dst, b[:binary.PutUvarint(b[:], uint64(len(src)))]...)
+
+	// Bits are added to acc and whole bytes moved to dst as they fill, so at most 7
+	// bits are pending and up to 56 more can be added at a time.
+	var acc uint64
+	var n uint
+	put := func(v uint64, width uint) {
+		acc |= v << n
+		for n += width; n >= 8; n -= 8 {
+			dst = append(dst, byte(acc))
+			acc >>= 8
+		}
+	}
+
+	for _, v := range src {
+		if width > 56 {
+			put(v&(1<<32-1), 32)
+			put(v>>32, width-32)
+		} else {
+			put(v, width)
+		}
+	}
+	if n > 0 {
+		dst = append(dst, byte(acc))
+	}
+	return dst, nil
+}
+
+// Decode writes the packed values in src to dst.  It returns the number of values
+// written or an error.
+func (FixedCodec) Decode(dst []uint64, src []byte) (int, error) {
+	if len(src) == 0 {
+		return 0, fmt.Errorf("header truncated")
+	}
+	width := uint(src[0])
+	if width > 64 {
+		return 0, fmt.Errorf("invalid bit width: %d", width)
+	}
+
+	count, h := binary.Uvarint(src[1:])
+	if h <= 0 {
+		return 0, fmt.Errorf("invalid value count")
+	}
+	src = src[1+h:]
+
+	if count > uint64(len(dst)) {
+		return 0, fmt.Errorf("dst too small: need %d values, have %d", count, len(dst))
+	}
+	n := int(count)
+	if need := (uint64(n)*uint64(width) + 7) / 8; uint64(len(src)) != need {
+		return 0, fmt.Errorf("invalid packed len: %d bytes, need %d", len(src), need)
+	}
+
+	var acc uint64
+	var m uint
+	get := func(width uint) uint64 {
+		for m < width {
+			acc |= uint64(src[0]) << m
+			src = src[1:]
+			m += 8
+		}
+		v := acc & (1<<width - 1)
+		acc >>= width
+		m -= width
+		return v
+	}
+
+	for i := range dst[:n] {
+		if width > 56 {
+			lo := get(32)
+			dst[i] = lo | get(width-32)<<32
+		} else {
+			dst[i] = get(width)
+		}
+	}
+	return n, nil
+}
@@ -70,3 +70,38 @@ func ZigZagEncode64(x int64) uint64 {
 func ZigZagDecode64(v uint64) int64 {
 	return int64((v >> 1) ^ uint64((int64(v&1)<<63)>>63))
 }
+
+func ZigZagEncode32(x int32) uint32 {
+	return uint32(uint32(x<<1) ^ uint32((int32(x) >> 31)))
+}
+
+func ZigZagDecode32(v uint32) int32 {
+	return int32((v >> 1) ^ uint32((int32(v&1)<<31)>>31))
+}
+
+// PopCount64 returns the number of set bits in x.
+func PopCount64(x uint64) int {
+	x = x - (x>>1)&0x5555555555555555
+	x = x&0x3333333333333333 + (x>>2)&0x3333333333333333
+	x = (x + x>>4) & 0x0f0f0f0f0f0f0f0f
+	return int(x * 0x0101010101010101 >> 56)
+}
+
+// Select64 returns the position of the k'th set bit of x, counting from zero at
+// the least significant bit.  It returns 64 if x has k or fewer set bits.
+func Select64(x uint64, k int) int {
+	// Find the byte holding the bit using the running count of each byte, then
+	// clear the lower set bits of that byte.
+	for shift := uint(0); shift < 64; shift += 8 {
+		b := x >> shift & 0xff
+		c := PopCount64(b)
+		if k < c {
+			for ; k > 0; k-- {
+				b &= b - 1
+			}
+			return int(shift) + msb64(b&-b)
+		}
+		k -= c
+	}
+	return 64
+}
@@ -0,0 +1,327 @@
+// Package boolean implements an encoding for columns of boolean values.
+//
+// Values are stored as a dense bitset using one bit per value, or as a sequence of
+// run lengths when the values form long runs.  The encoder picks whichever is
+// smaller, and the number of true values can be counted from either form without
+// decoding the values.
+package boolean
+
+// The encoded form starts with a mode byte and the number of values as a uvarint.
+//
+// A bitset follows as ⌈n/8⌉ bytes holding value i in bit i%8 of byte i/8.
+//
+// A run-length encoding follows as a byte holding the first value and the lengths
+// of the runs of alternating values as uvarints.
+//
+// ┌──────────┬──────────┬──────────────────────────────────────────────────┐
+// │   Mode   │  Count   │                      Values                      │
+// ├──────────┼──────────┼──────────────────────────────────────────────────┤
+// │  1 byte  │ uvarint  │ 0: bitset bytes                                  │
+// │          │          │ 1: first value byte, uvarint run lengths         │
+// └──────────┴──────────┴──────────────────────────────────────────────────┘
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/jwilder/encoding/bitops"
+)
+
+const (
+	modeBitset = 0
+	modeRLE    = 1
+)
+
+// Encoder converts a stream of booleans to a compressed byte slice.
+type Encoder struct {
+	// bitset of the values written
+	bits []byte
+
+	// number of values written
+	n int
+
+	// first value, encoded length of the completed runs, and the value and
+	// length of the open run
+	first  bool
+	rleLen int
+	cur    bool
+	run    uint64
+
+	// encoded header and values returned by Bytes
+	out []byte
+}
+
+// NewEncoder returns an Encoder able to convert booleans to compressed byte slices
+func NewEncoder() *Encoder {
+	return &Encoder{
+		bits: make([]byte, 0, 128),
+	}
+}
+
+// Reset clears the encoder so it can be reused.
+func (e *Encoder) Reset() {
+	e.bits = e.bits[:0]
+	e.n = 0
+	e.rleLen = 0
+	e.run = 0
+}
+
+// Write adds v to the encoder.
+func (e *Encoder) Write(v bool) error {
+	if e.n%8 == 0 {
+		e.bits = append(e.bits, 0)
+	}
+	if v {
+		e.bits[e.n/8] |= 1 << uint(e.n%8)
+	}
+
+	switch {
+	case e.n == 0:
+		e.first, e.cur, e.run = v, v, 1
+	case v == e.cur:
+		e.run += 1
+	default:
+		var b [binary.MaxVarintLen64]byte
+		e.rleLen += binary.PutUvarint(b[:], e.run)
+		e.cur, e.run = v, 1
+	}
+
+	e.n += 1
+	return nil
+}
+
+// Bytes returns the encoded values written to the encoder, using run lengths if
+// they are smaller than the bitset.  Values may continue to be written after
+// calling Bytes.
+func (e *Encoder) Bytes() ([]byte, error) {
+	var b [binary.MaxVarintLen64]byte
+
+	rleLen := 1 + e.rleLen
+	if e.n > 0 {
+		rleLen += binary.PutUvarint(b[:], e.run)
+	}
+
+	e.out = e.out[:0]
+	if rleLen >= len(e.bits) {
+		e.out = append(e.out, modeBitset)
+		e.out = append(e.out, b[:binary.PutUvarint(b[:], uint64(e.n))]...)
+		e.out = append(e.out, e.bits...)
+		return e.out, nil
+	}
+
+	e.out = append(e.out, modeRLE)
+	e.out = append(e.out, b[:binary.PutUvarint(b[:], uint64(e.n))]...)
+	if e.first {
+		e.out = append(e.out, 1)
+	} else {
+		e.out = append(e.out, 0)
+	}
+
+	// The runs are read back from the bitset rather than kept while writing.
+	v, run := e.first, uint64(0)
+	for i := 0; i < e.n; i++ {
+		if (e.bits[i/8]>>uint(i%8)&1 == 1) != v {
+			e.out = append(e.out, b[:binary.PutUvarint(b[:], run)]...)
+			v, run = !v, 0
+		}
+		run += 1
+	}
+	e.out = append(e.out, b[:binary.PutUvarint(b[:], run)]...)
+	return e.out, nil
+}
+
+// Decoder converts a compressed byte slice to a stream of booleans.
+type Decoder struct {
+	mode  byte
+	bytes []byte
+
+	// index of the current value and number of values
+	i int
+	n int
+
+	// value and remaining length of the current run
+	v   bool
+	run uint64
+
+	err error
+}
+
+// NewDecoder returns a Decoder from a byte slice
+func NewDecoder(b []byte) *Decoder {
+	d := &Decoder{}
+	d.SetBytes(b)
+	return d
+}
+
+// SetBytes resets the decoder to read from b.
+func (d *Decoder) SetBytes(b []byte) {
+	d.i = -1
+	d.run = 0
+	d.err = nil
+
+	mode, count, b, err := readHeader(b)
+	if err != nil {
+		d.bytes = nil
+		d.n = 0
+		d.err = err
+		return
+	}
+	d.mode, d.n, d.bytes = mode, count, b
+
+	if mode == modeRLE && count > 0 {
+		// The first run is toggled to the first value when it is read.
+		d.v = b[0] != 1
+		d.bytes = b[1:]
+	}
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *Decoder) Next() bool {
+	if d.err != nil || d.i+1 >= d.n {
+		return false
+	}
+	d.i += 1
+
+	if d.mode == modeRLE {
+		if d.run == 0 {
+			run, n := binary.Uvarint(d.bytes)
+			if n <= 0 || run == 0 || run > uint64(d.n-d.i) {
+				d.err = fmt.Errorf("invalid run length")
+				return false
+			}
+			d.bytes = d.bytes[n:]
+			d.v, d.run = !d.v, run
+		}
+		d.run -= 1
+	}
+	return true
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (d *Decoder) Read() bool {
+	if d.mode == modeBitset {
+		return d.bytes[d.i/8]>>uint(d.i%8)&1 == 1
+	}
+	return d.v
+}
+
+// Err returns the first error encountered while decoding.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// EncodeAll returns the compressed form of the values in src.
+func EncodeAll(src []bool) ([]byte, error) {
+	enc := NewEncoder()
+	for _, v := range src {
+		if err := enc.Write(v); err != nil {
+			return nil, err
+		}
+	}
+	return enc.Bytes()
+}
+
+// DecodeAll writes the uncompressed values from src to dst.  It returns the number
+// of values written or an error.
+func DecodeAll(dst []bool, src []byte) (int, error) {
+	_, count, _, err := readHeader(src)
+	if err != nil {
+		return 0, err
+	}
+	if len(dst) < count {
+		return 0, fmt.Errorf("dst too small: need %d values, have %d", count, len(dst))
+	}
+
+	d := NewDecoder(src)
+	i := 0
+	for d.Next() {
+		dst[i] = d.Read()
+		i++
+	}
+	if d.Err() != nil {
+		return 0, d.Err()
+	}
+	return i, nil
+}
+
+// CountBytes returns the number of values encoded in the byte slice
+func CountBytes(b []byte) (int, error) {
+	_, count, _, err := readHeader(b)
+	return count, err
+}
+
+// CountTrue returns the number of true values encoded in the byte slice, without
+// decoding them.
+func CountTrue(b []byte) (int, error) {
+	mode, count, b, err := readHeader(b)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+
+	if mode == modeBitset {
+		// Bits of the last byte past count are padding and are not counted.
+		last := b[len(b)-1]
+		if r := count % 8; r != 0 {
+			last &= 1<<uint(r) - 1
+		}
+		b = b[:len(b)-1]
+
+		total := bitops.PopCount64(uint64(last))
+		for ; len(b) >= 8; b = b[8:] {
+			total += bitops.PopCount64(binary.LittleEndian.Uint64(b))
+		}
+		for _, v := range b {
+			total += bitops.PopCount64(uint64(v))
+		}
+		return total, nil
+	}
+
+	// Sum the runs of true values, which alternate with the false runs.
+	v := b[0] == 1
+	b = b[1:]
+	var total, seen uint64
+	for seen < uint64(count) {
+		run, n := binary.Uvarint(b)
+		if n <= 0 || run == 0 || run > uint64(count)-seen {
+			return 0, fmt.Errorf("invalid run length")
+		}
+		b = b[n:]
+		if v {
+			total += run
+		}
+		seen += run
+		v = !v
+	}
+	return int(total), nil
+}
+
+// readHeader returns the mode and value count from b and the remaining bytes.
+func readHeader(b []byte) (mode byte, count int, rest []byte, err error) {
+	if len(b) == 0 {
+		return modeBitset, 0, nil, nil
+	}
+
+	mode = b[0]
+	v, n := binary.Uvarint(b[1:])
+	if n <= 0 || v > math.MaxInt32 {
+		return 0, 0, nil, fmt.Errorf("invalid value count")
+	}
+	count = int(v)
+	b = b[1+n:]
+
+	switch mode {
+	case modeBitset:
+		if need := (count + 7) / 8; len(b) != need {
+			return 0, 0, nil, fmt.Errorf("invalid bitset len: %d bytes, need %d", len(b), need)
+		}
+	case modeRLE:
+		if count > 0 && (len(b) == 0 || b[0] > 1) {
+			return 0, 0, nil, fmt.Errorf("invalid first value")
+		}
+	default:
+		return 0, 0, nil, fmt.Errorf("invalid mode: %d", mode)
+	}
+	return mode, count, b, nil
+}
@@ -0,0 +1,285 @@
+package boolean_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/jwilder/encoding/boolean"
+)
+
+func testRoundTrip(t *testing.T, values []bool) []byte {
+	b, err := boolean.EncodeAll(values)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n, err := boolean.CountBytes(b); err != nil || n != len(values) {
+		t.Fatalf("Count mismatch: got %v %v, exp %v", n, err, len(values))
+	}
+
+	var trues int
+	for _, v := range values {
+		if v {
+			trues++
+		}
+	}
+	if n, err := boolean.CountTrue(b); err != nil || n != trues {
+		t.Fatalf("CountTrue mismatch: got %v %v, exp %v", n, err, trues)
+	}
+
+	got := make([]bool, len(values))
+	n, err := boolean.DecodeAll(got, b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(values) {
+		t.Fatalf("Len mismatch: got %v, exp %v", n, len(values))
+	}
+	for i := range values {
+		if got[i] != values[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, values[i], got[i])
+		}
+	}
+	return b
+}
+
+func Test_Encode_NoValues(t *testing.T) {
+	testRoundTrip(t, nil)
+}
+
+func Test_Encode_Bitset(t *testing.T) {
+	for _, n := range []int{1, 7, 8, 9, 63, 64, 65, 1000} {
+		values := make([]bool, n)
+		for i := range values {
+			values[i] = i%3 == 0
+		}
+
+		b := testRoundTrip(t, values)
+
+		// Alternating runs are smaller as a bitset, using one bit per value.
+		exp := 2 + (n+7)/8
+		if n >= 128 {
+			// The count uses a second uvarint byte.
+			exp++
+		}
+		if len(b) != exp {
+			t.Fatalf("Encoded len mismatch for %d values: got %v, exp %v", n, len(b), exp)
+		}
+	}
+}
+
+func Test_Encode_Runs(t *testing.T) {
+	values := make([]bool, 10000)
+	for i := 5000; i < 5010; i++ {
+		values[i] = true
+	}
+
+	b := testRoundTrip(t, values)
+
+	// The header, first value and three runs of 5000, 10 and 4990 values.
+	if exp := 3 + 1 + 2 + 1 + 2; len(b) != exp {
+		t.Fatalf("Encoded len mismatch: got %v, exp %v", len(b), exp)
+	}
+}
+
+func Test_Encode_AllTrue(t *testing.T) {
+	values := make([]bool, 1000)
+	for i := range values {
+		values[i] = true
+	}
+	testRoundTrip(t, values)
+}
+
+func Test_Encode_Random(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, p := range []float64{0.5, 0.01, 0.999} {
+		values := make([]bool, 5000)
+		for i := range values {
+			values[i] = rng.Float64() < p
+		}
+		testRoundTrip(t, values)
+	}
+}
+
+func Test_Encoder_Decoder(t *testing.T) {
+	values := make([]bool, 300)
+	for i := 100; i < 200; i++ {
+		values[i] = true
+	}
+
+	enc := boolean.NewEncoder()
+	for _, v := range values {
+		if err := enc.Write(v); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	b, err := enc.Bytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := boolean.NewDecoder(b)
+	i := 0
+	for dec.Next() {
+		if v := dec.Read(); v != values[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, values[i], v)
+		}
+		i++
+	}
+	if dec.Err() != nil {
+		t.Fatalf("Unexpected error: %v", dec.Err())
+	}
+	if i != len(values) {
+		t.Fatalf("Len mismatch: got %v, exp %v", i, len(values))
+	}
+
+	enc.Reset()
+	enc.Write(true)
+	b, _ = enc.Bytes()
+	dec.SetBytes(b)
+	if !dec.Next() {
+		t.Fatalf("Expected value after reset")
+	}
+	if v := dec.Read(); !v || dec.Next() {
+		t.Fatalf("Read mismatch after reset: got %v", v)
+	}
+}
+
+func Test_Encoder_WriteAfterBytes(t *testing.T) {
+	enc := boolean.NewEncoder()
+	var values []bool
+	for _, run := range []int{500, 20, 700, 1} {
+		for i := 0; i < run; i++ {
+			v := len(values) >= 500 && len(values) < 520 || len(values) == 1220
+			values = append(values, v)
+			enc.Write(v)
+		}
+
+		// The runs are rebuilt from the values written so far on each call.
+		b, err := enc.Bytes()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := make([]bool, len(values))
+		if n, err := boolean.DecodeAll(got, b); err != nil || n != len(values) {
+			t.Fatalf("DecodeAll = %v %v, exp %v", n, err, len(values))
+		}
+		for i := range values {
+			if got[i] != values[i] {
+				t.Fatalf("Decoded[%d] != %v, got %v", i, values[i], got[i])
+			}
+		}
+	}
+}
+
+func Test_Decode_Truncated(t *testing.T) {
+	runs := make([]bool, 1000)
+	for i := 500; i < 600; i++ {
+		runs[i] = true
+	}
+	bitset := make([]bool, 100)
+	for i := range bitset {
+		bitset[i] = i%2 == 0
+	}
+
+	for _, values := range [][]bool{runs, bitset} {
+		b, _ := boolean.EncodeAll(values)
+		for n := 1; n < len(b); n++ {
+			if _, err := boolean.DecodeAll(make([]bool, len(values)), b[:n]); err == nil {
+				t.Fatalf("Expected error for input truncated to %d bytes, got nil", n)
+			}
+			if _, err := boolean.CountTrue(b[:n]); err == nil {
+				t.Fatalf("Expected CountTrue error for input truncated to %d bytes, got nil", n)
+			}
+		}
+	}
+}
+
+func Test_DecodeAll_DstTooSmall(t *testing.T) {
+	b, _ := boolean.EncodeAll(make([]bool, 10))
+	if _, err := boolean.DecodeAll(make([]bool, 9), b); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	for _, in := range [][]bool{nil, {true, false, true}, make([]bool, 300)} {
+		encoded, _ := boolean.EncodeAll(in)
+		f.Add(encoded)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := boolean.CountBytes(b)
+		if err != nil || n > 1<<20 {
+			return
+		}
+
+		dst := make([]bool, n)
+		got, err := boolean.DecodeAll(dst, b)
+		if err != nil {
+			// The count is read from the header, so the runs may still be invalid.
+			if _, err := boolean.CountTrue(b); err == nil {
+				t.Fatalf("Expected CountTrue error, got nil")
+			}
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAll mismatch: got %v, exp %v", got, n)
+		}
+		if n > 0 {
+			if _, err := boolean.DecodeAll(make([]bool, n-1), b); err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+		}
+
+		trues := 0
+		dec := boolean.NewDecoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			if dst[i] {
+				trues++
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+		if got, err := boolean.CountTrue(b); err != nil || got != trues {
+			t.Fatalf("CountTrue mismatch: got %v %v, exp %v", got, err, trues)
+		}
+	})
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	values := make([]bool, 1024)
+	for i := range values {
+		values[i] = i%5 == 0
+	}
+
+	enc := boolean.NewEncoder()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enc.Reset()
+		for _, v := range values {
+			enc.Write(v)
+		}
+		enc.Bytes()
+	}
+}
+
+func BenchmarkCountTrue(b *testing.B) {
+	values := make([]bool, 1024)
+	for i := range values {
+		values[i] = i%5 == 0
+	}
+	buf, _ := boolean.EncodeAll(values)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		boolean.CountTrue(buf)
+	}
+}
@@ -0,0 +1,216 @@
+// Package bp128 implements the SIMD-BP128 integer encoding described by Lemire
+// and Boytsov in "Decoding billions of integers per second through
+// vectorization", Software: Practice & Experience 2015.
+//
+// Values are packed in blocks at a fixed bit width using a vertical layout: a
+// block is read as rows of 128 bit vectors and each vector lane is packed into its
+// own bit stream.  This allows a block to be packed or unpacked with a handful of
+// SIMD shift, or and mask instructions per row.
+//
+// ┌──────────┬───────────┬───────┬───────┬────────────────────────┐
+// │   Type   │ Block len │ Lanes │ Rows  │      Packed words      │
+// ├──────────┼───────────┼───────┼───────┼────────────────────────┤
+// │  uint32  │    128    │   4   │  32   │  4 × bits (uint32)     │
+// │  uint64  │     64    │   2   │  32   │      bits (uint64)     │
+// └──────────┴───────────┴───────┴───────┴────────────────────────┘
+//
+// Value i of a block is stored in lane i%lanes.  Packed word k*lanes+l holds the
+// k'th word of the bit stream of lane l.  With an odd bit width the bit stream of
+// each uint64 lane ends with a half word, so the final packed uint64 holds the
+// tail of lane 0 in its low 32 bits and the tail of lane 1 in its high 32 bits.
+//
+// With differential coding each value is replaced by its difference from the
+// value one row earlier, which keeps the lanes independent.  The first row is
+// relative to a seed row, which is the last row of the previous block.
+//
+// On amd64 blocks are packed and unpacked with AVX2 when the processor supports
+// it, and with SSE2 otherwise.  The AVX2 kernels handle rows r and r+16 of a block
+// together in the two halves of a 256 bit register, so the layout is the same for
+// both.  The blocks used by EncodeAll and DecodeAll are allocated with the aligned
+// helpers of simple8b.
+package bp128
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const (
+	// BlockSize32 is the number of uint32 values in a packed block.
+	BlockSize32 = 128
+
+	// BlockSize64 is the number of uint64 values in a packed block.
+	BlockSize64 = 64
+)
+
+// Kernels used to pack and unpack blocks.  The scalar versions are replaced with
+// SIMD versions when the processor supports them.
+var (
+	pack32         = pack32Scalar
+	unpack32       = unpack32Scalar
+	deltaPack32    = deltaPack32Scalar
+	deltaUnpack32  = deltaUnpack32Scalar
+	maxBits32      = maxBits32Scalar
+	deltaMaxBits32 = deltaMaxBits32Scalar
+
+	pack64         = pack64Scalar
+	unpack64       = unpack64Scalar
+	deltaPack64    = deltaPack64Scalar
+	deltaUnpack64  = deltaUnpack64Scalar
+	maxBits64      = maxBits64Scalar
+	deltaMaxBits64 = deltaMaxBits64Scalar
+)
+
+// PackedLen32 returns the number of uint32 words used by a block of uint32 values
+// packed at the given bit width.
+func PackedLen32(bits int) int {
+	return 4 * bits
+}
+
+// PackedLen64 returns the number of uint64 words used by a block of uint64 values
+// packed at the given bit width.
+func PackedLen64(bits int) int {
+	return bits
+}
+
+// MaxBits32 returns the bit width required to pack every value in src.
+func MaxBits32(src *[BlockSize32]uint32) int {
+	return bits.Len32(maxBits32(src))
+}
+
+// DeltaMaxBits32 returns the bit width required to pack the differences between
+// the rows of src, with the first row relative to seed.
+func DeltaMaxBits32(src *[BlockSize32]uint32, seed *[4]uint32) int {
+	return bits.Len32(deltaMaxBits32(src, seed))
+}
+
+// Pack32 packs src into dst at the given bit width and returns the number of words
+// written.  Values must fit within bits.  It panics if dst is shorter than
+// PackedLen32(bits).
+func Pack32(dst []uint32, src *[BlockSize32]uint32, bits int) int {
+	n := checkBits(bits, 32, len(dst), PackedLen32(bits))
+	if n > 0 {
+		pack32(dst, src, uint(bits))
+	}
+	return n
+}
+
+// Unpack32 unpacks a block packed at the given bit width from src into dst and
+// returns the number of words read.  It panics if src is shorter than
+// PackedLen32(bits).
+func Unpack32(dst *[BlockSize32]uint32, src []uint32, bits int) int {
+	n := checkBits(bits, 32, len(src), PackedLen32(bits))
+	if n == 0 {
+		*dst = [BlockSize32]uint32{}
+		return 0
+	}
+	unpack32(dst, src, uint(bits))
+	return n
+}
+
+// DeltaPack32 packs the differences between the rows of src into dst at the given
+// bit width and returns the number of words written.  The first row is relative
+// to seed, which is updated to the last row of src.  It panics if dst is shorter
+// than PackedLen32(bits).
+func DeltaPack32(dst []uint32, src *[BlockSize32]uint32, bits int, seed *[4]uint32) int {
+	n := checkBits(bits, 32, len(dst), PackedLen32(bits))
+	if n > 0 {
+		deltaPack32(dst, src, uint(bits), seed)
+	} else {
+		copy(seed[:], src[BlockSize32-4:])
+	}
+	return n
+}
+
+// DeltaUnpack32 unpacks a block packed by DeltaPack32 from src into dst and returns
+// the number of words read.  The seed must match the one given to DeltaPack32 and
+// is updated to the last row of dst.  It panics if src is shorter than
+// PackedLen32(bits).
+func DeltaUnpack32(dst *[BlockSize32]uint32, src []uint32, bits int, seed *[4]uint32) int {
+	n := checkBits(bits, 32, len(src), PackedLen32(bits))
+	if n == 0 {
+		for i := range dst {
+			dst[i] = seed[i%4]
+		}
+		return 0
+	}
+	deltaUnpack32(dst, src, uint(bits), seed)
+	return n
+}
+
+// MaxBits64 returns the bit width required to pack every value in src.
+func MaxBits64(src *[BlockSize64]uint64) int {
+	return bits.Len64(maxBits64(src))
+}
+
+// DeltaMaxBits64 returns the bit width required to pack the differences between
+// the rows of src, with the first row relative to seed.
+func DeltaMaxBits64(src *[BlockSize64]uint64, seed *[2]uint64) int {
+	return bits.Len64(deltaMaxBits64(src, seed))
+}
+
+// Pack64 packs src into dst at the given bit width and returns the number of words
+// written.  Values must fit within bits.  It panics if dst is shorter than
+// PackedLen64(bits).
+func Pack64(dst []uint64, src *[BlockSize64]uint64, bits int) int {
+	n := checkBits(bits, 64, len(dst), PackedLen64(bits))
+	if n > 0 {
+		pack64(dst, src, uint(bits))
+	}
+	return n
+}
+
+// Unpack64 unpacks a block packed at the given bit width from src into dst and
+// returns the number of words read.  It panics if src is shorter than
+// PackedLen64(bits).
+func Unpack64(dst *[BlockSize64]uint64, src []uint64, bits int) int {
+	n := checkBits(bits, 64, len(src), PackedLen64(bits))
+	if n == 0 {
+		*dst = [BlockSize64]uint64{}
+		return 0
+	}
+	unpack64(dst, src, uint(bits))
+	return n
+}
+
+// DeltaPack64 packs the differences between the rows of src into dst at the given
+// bit width and returns the number of words written.  The first row is relative
+// to seed, which is updated to the last row of src.  It panics if dst is shorter
+// than PackedLen64(bits).
+func DeltaPack64(dst []uint64, src *[BlockSize64]uint64, bits int, seed *[2]uint64) int {
+	n := checkBits(bits, 64, len(dst), PackedLen64(bits))
+	if n > 0 {
+		deltaPack64(dst, src, uint(bits), seed)
+	} else {
+		copy(seed[:], src[BlockSize64-2:])
+	}
+	return n
+}
+
+// DeltaUnpack64 unpacks a block packed by DeltaPack64 from src into dst and returns
+// the number of words read.  The seed must match the one given to DeltaPack64 and
+// is updated to the last row of dst.  It panics if src is shorter than
+// PackedLen64(bits).
+func DeltaUnpack64(dst *[BlockSize64]uint64, src []uint64, bits int, seed *[2]uint64) int {
+	n := checkBits(bits, 64, len(src), PackedLen64(bits))
+	if n == 0 {
+		for i := range dst {
+			dst[i] = seed[i%2]
+		}
+		return 0
+	}
+	deltaUnpack64(dst, src, uint(bits), seed)
+	return n
+}
+
+// checkBits panics if bits is not a valid width for values of size width or if a
+// slice of length have is shorter than need.  It returns need.
+func checkBits(bits, width, have, need int) int {
+	if bits < 0 || bits > width {
+		panic(fmt.Sprintf("bp128: invalid bit width %d", bits))
+	}
+	if have < need {
+		panic(fmt.Sprintf("bp128: slice too short: need %d words, have %d", need, have))
+	}
+	return need
+}
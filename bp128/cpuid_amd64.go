@@ -0,0 +1,4 @@
+package bp128
+
+//go:noescape
+func cpu_info()
@@ -0,0 +1,268 @@
+package bp128
+
+// EncodeAll and DecodeAll store a slice of any length as a sequence of blocks.  The
+// first word holds the number of values, and each block is preceded by a word
+// holding its bit width.  A final partial block is padded with zeros, or with its
+// last row when using differential coding, so the padding packs to nothing.
+import (
+	"fmt"
+	"math"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+// EncodeAll32 returns the values from src packed in blocks of BlockSize32.  An
+// error is returned if src has more than math.MaxUint32 values.
+func EncodeAll32(src []uint32) ([]uint32, error) {
+	return encodeAll32(src, false)
+}
+
+// EncodeAllDelta32 returns the values from src packed in blocks of BlockSize32 using
+// differential coding.  It is best suited to ascending values, though any values
+// may be encoded.  An error is returned if src has more than math.MaxUint32 values.
+func EncodeAllDelta32(src []uint32) ([]uint32, error) {
+	return encodeAll32(src, true)
+}
+
+// DecodeAll32 writes the values packed by EncodeAll32 in src to dst.  It returns
+// the number of values written or an error.
+func DecodeAll32(dst, src []uint32) (int, error) {
+	return decodeAll32(dst, src, false)
+}
+
+// DecodeAllDelta32 writes the values packed by EncodeAllDelta32 in src to dst.  It
+// returns the number of values written or an error.
+func DecodeAllDelta32(dst, src []uint32) (int, error) {
+	return decodeAll32(dst, src, true)
+}
+
+// Count32 returns the number of values packed in src by EncodeAll32 or
+// EncodeAllDelta32.
+func Count32(src []uint32) int {
+	if len(src) == 0 {
+		return 0
+	}
+	return int(src[0])
+}
+
+// EncodeAll64 returns the values from src packed in blocks of BlockSize64.  The
+// number of values is stored in a full uint64 word, so unlike EncodeAll32 there is
+// no limit on len(src) and the error is always nil.
+func EncodeAll64(src []uint64) ([]uint64, error) {
+	return encodeAll64(src, false)
+}
+
+// EncodeAllDelta64 returns the values from src packed in blocks of BlockSize64 using
+// differential coding.  It is best suited to ascending values, though any values
+// may be encoded.  As with EncodeAll64, the error is always nil.
+func EncodeAllDelta64(src []uint64) ([]uint64, error) {
+	return encodeAll64(src, true)
+}
+
+// DecodeAll64 writes the values packed by EncodeAll64 in src to dst.  It returns
+// the number of values written or an error.
+func DecodeAll64(dst, src []uint64) (int, error) {
+	return decodeAll64(dst, src, false)
+}
+
+// DecodeAllDelta64 writes the values packed by EncodeAllDelta64 in src to dst.  It
+// returns the number of values written or an error.
+func DecodeAllDelta64(dst, src []uint64) (int, error) {
+	return decodeAll64(dst, src, true)
+}
+
+// Count64 returns the number of values packed in src by EncodeAll64 or
+// EncodeAllDelta64.
+func Count64(src []uint64) int {
+	if len(src) == 0 {
+		return 0
+	}
+	return int(src[0])
+}
+
+func encodeAll32(src []uint32, delta bool) ([]uint32, error) {
+	if uint64(len(src)) > math.MaxUint32 {
+		return nil, fmt.Errorf("too many values: %d", len(src))
+	}
+
+	dst := make([]uint32, 1, len(src)/2+1)
+	dst[0] = uint32(len(src))
+
+	block := makeBlock32()
+	var seed [4]uint32
+	for i := 0; i < len(src); i += BlockSize32 {
+		n := copy(block[:], src[i:])
+		for j := n; j < BlockSize32; j++ {
+			switch {
+			case !delta:
+				block[j] = 0
+			case j >= 4:
+				block[j] = block[j-4]
+			default:
+				block[j] = seed[j]
+			}
+		}
+
+		var bits int
+		if delta {
+			bits = DeltaMaxBits32(block, &seed)
+		} else {
+			bits = MaxBits32(block)
+		}
+
+		dst = append(dst, uint32(bits))
+		k := len(dst)
+		dst = append(dst, make([]uint32, PackedLen32(bits))...)
+		if delta {
+			DeltaPack32(dst[k:], block, bits, &seed)
+		} else {
+			Pack32(dst[k:], block, bits)
+		}
+	}
+	return dst, nil
+}
+
+func decodeAll32(dst, src []uint32, delta bool) (int, error) {
+	if len(src) == 0 {
+		return 0, nil
+	}
+
+	n := int(src[0])
+	if n > len(dst) {
+		return 0, fmt.Errorf("dst too small: need %d values, have %d", n, len(dst))
+	}
+	src = src[1:]
+
+	block := makeBlock32()
+	var seed [4]uint32
+	for j := 0; j < n; j += BlockSize32 {
+		if len(src) == 0 {
+			return 0, fmt.Errorf("unexpected end of input: decoded %d of %d values", j, n)
+		}
+
+		bits := int(src[0])
+		if bits > 32 {
+			return 0, fmt.Errorf("invalid bit width: %d", bits)
+		}
+		if len(src)-1 < PackedLen32(bits) {
+			return 0, fmt.Errorf("unexpected end of input: decoded %d of %d values", j, n)
+		}
+
+		// Unpack full blocks directly into dst.
+		out := block
+		if j+BlockSize32 <= n {
+			out = (*[BlockSize32]uint32)(dst[j:])
+		}
+
+		var m int
+		if delta {
+			m = DeltaUnpack32(out, src[1:], bits, &seed)
+		} else {
+			m = Unpack32(out, src[1:], bits)
+		}
+		if out == block {
+			copy(dst[j:n], block[:])
+		}
+		src = src[1+m:]
+	}
+	return n, nil
+}
+
+func encodeAll64(src []uint64, delta bool) ([]uint64, error) {
+	dst := make([]uint64, 1, len(src)/2+1)
+	dst[0] = uint64(len(src))
+
+	block := makeBlock64()
+	var seed [2]uint64
+	for i := 0; i < len(src); i += BlockSize64 {
+		n := copy(block[:], src[i:])
+		for j := n; j < BlockSize64; j++ {
+			switch {
+			case !delta:
+				block[j] = 0
+			case j >= 2:
+				block[j] = block[j-2]
+			default:
+				block[j] = seed[j]
+			}
+		}
+
+		var bits int
+		if delta {
+			bits = DeltaMaxBits64(block, &seed)
+		} else {
+			bits = MaxBits64(block)
+		}
+
+		dst = append(dst, uint64(bits))
+		k := len(dst)
+		dst = append(dst, make([]uint64, PackedLen64(bits))...)
+		if delta {
+			DeltaPack64(dst[k:], block, bits, &seed)
+		} else {
+			Pack64(dst[k:], block, bits)
+		}
+	}
+	return dst, nil
+}
+
+func decodeAll64(dst, src []uint64, delta bool) (int, error) {
+	if len(src) == 0 {
+		return 0, nil
+	}
+
+	if src[0] > uint64(len(dst)) {
+		return 0, fmt.Errorf("dst too small: need %d values, have %d", src[0], len(dst))
+	}
+	n := int(src[0])
+	src = src[1:]
+
+	block := makeBlock64()
+	var seed [2]uint64
+	for j := 0; j < n; j += BlockSize64 {
+		if len(src) == 0 {
+			return 0, fmt.Errorf("unexpected end of input: decoded %d of %d values", j, n)
+		}
+
+		if src[0] > 64 {
+			return 0, fmt.Errorf("invalid bit width: %d", src[0])
+		}
+		bits := int(src[0])
+		if len(src)-1 < PackedLen64(bits) {
+			return 0, fmt.Errorf("unexpected end of input: decoded %d of %d values", j, n)
+		}
+
+		// Unpack full blocks directly into dst.
+		out := block
+		if j+BlockSize64 <= n {
+			out = (*[BlockSize64]uint64)(dst[j:])
+		}
+
+		var m int
+		if delta {
+			m = DeltaUnpack64(out, src[1:], bits, &seed)
+		} else {
+			m = Unpack64(out, src[1:], bits)
+		}
+		if out == block {
+			copy(dst[j:n], block[:])
+		}
+		src = src[1+m:]
+	}
+	return n, nil
+}
+
+// makeBlock32 returns a block aligned with simple8b.MakeAlignedSlice, so that no row
+// read or written by the SIMD kernels spans two cache lines.
+func makeBlock32() *[BlockSize32]uint32 {
+	var mem []uint32
+	simple8b.MakeAlignedSlice(BlockSize32, &mem)
+	return (*[BlockSize32]uint32)(mem)
+}
+
+// makeBlock64 is like makeBlock32 for blocks of uint64.
+func makeBlock64() *[BlockSize64]uint64 {
+	var mem []uint64
+	simple8b.MakeAlignedSlice(BlockSize64, &mem)
+	return (*[BlockSize64]uint64)(mem)
+}
@@ -0,0 +1,309 @@
+package bp128_test
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/jwilder/encoding/bp128"
+)
+
+func Test_EncodeAll32(t *testing.T) {
+	for _, n := range []int{0, 1, 5, 127, 128, 129, 1000} {
+		in := make([]uint32, n)
+		for i := range in {
+			in[i] = uint32(rand.Int63n(1 << uint(i%33)))
+		}
+
+		encoded, err := bp128.EncodeAll32(in)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := bp128.Count32(encoded); got != n {
+			t.Fatalf("Count mismatch: got %v, exp %v", got, n)
+		}
+
+		decoded := make([]uint32, n)
+		m, err := bp128.DecodeAll32(decoded, encoded)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if m != n {
+			t.Fatalf("Len mismatch: got %v, exp %v", m, n)
+		}
+		for i := range in {
+			if decoded[i] != in[i] {
+				t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+			}
+		}
+	}
+}
+
+func Test_EncodeAllDelta32(t *testing.T) {
+	for _, n := range []int{0, 1, 5, 127, 128, 129, 1000} {
+		in := make([]uint32, n)
+		var v uint32
+		for i := range in {
+			v += uint32(rand.Intn(100))
+			in[i] = v
+		}
+
+		encoded, err := bp128.EncodeAllDelta32(in)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		decoded := make([]uint32, n)
+		m, err := bp128.DecodeAllDelta32(decoded, encoded)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if m != n {
+			t.Fatalf("Len mismatch: got %v, exp %v", m, n)
+		}
+		for i := range in {
+			if decoded[i] != in[i] {
+				t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+			}
+		}
+	}
+}
+
+func Test_EncodeAllDelta32_Compresses(t *testing.T) {
+	in := make([]uint32, 1280)
+	for i := range in {
+		in[i] = uint32(1000000 + i*3)
+	}
+
+	encoded, _ := bp128.EncodeAllDelta32(in)
+
+	// The first row needs 20 bits while later deltas of 12 between rows need 4.
+	if exp := 1 + (1 + bp128.PackedLen32(20)) + 9*(1+bp128.PackedLen32(4)); len(encoded) != exp {
+		t.Fatalf("Encoded len mismatch: got %v, exp %v", len(encoded), exp)
+	}
+}
+
+func Test_EncodeAll64(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 63, 64, 65, 1000} {
+		in := make([]uint64, n)
+		for i := range in {
+			in[i] = rand.Uint64() >> uint(i%65)
+		}
+
+		encoded, err := bp128.EncodeAll64(in)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := bp128.Count64(encoded); got != n {
+			t.Fatalf("Count mismatch: got %v, exp %v", got, n)
+		}
+
+		decoded := make([]uint64, n)
+		m, err := bp128.DecodeAll64(decoded, encoded)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if m != n {
+			t.Fatalf("Len mismatch: got %v, exp %v", m, n)
+		}
+		for i := range in {
+			if decoded[i] != in[i] {
+				t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+			}
+		}
+	}
+}
+
+func Test_EncodeAllDelta64(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 63, 64, 65, 1000} {
+		in := make([]uint64, n)
+		var v uint64
+		for i := range in {
+			v += uint64(rand.Int63n(1 << 40))
+			in[i] = v
+		}
+
+		encoded, err := bp128.EncodeAllDelta64(in)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		decoded := make([]uint64, n)
+		m, err := bp128.DecodeAllDelta64(decoded, encoded)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if m != n {
+			t.Fatalf("Len mismatch: got %v, exp %v", m, n)
+		}
+		for i := range in {
+			if decoded[i] != in[i] {
+				t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+			}
+		}
+	}
+}
+
+func Test_DecodeAll32_Invalid(t *testing.T) {
+	in := make([]uint32, 300)
+	for i := range in {
+		in[i] = uint32(i)
+	}
+	encoded, _ := bp128.EncodeAll32(in)
+
+	if _, err := bp128.DecodeAll32(make([]uint32, 299), encoded); err == nil {
+		t.Fatalf("Expected error for short dst, got nil")
+	}
+	if _, err := bp128.DecodeAll32(make([]uint32, 300), encoded[:len(encoded)-1]); err == nil {
+		t.Fatalf("Expected error for truncated input, got nil")
+	}
+
+	encoded[1] = 33
+	if _, err := bp128.DecodeAll32(make([]uint32, 300), encoded); err == nil {
+		t.Fatalf("Expected error for invalid bit width, got nil")
+	}
+}
+
+func Test_DecodeAll64_Invalid(t *testing.T) {
+	in := make([]uint64, 100)
+	for i := range in {
+		in[i] = uint64(i)
+	}
+	encoded, _ := bp128.EncodeAll64(in)
+
+	if _, err := bp128.DecodeAll64(make([]uint64, 99), encoded); err == nil {
+		t.Fatalf("Expected error for short dst, got nil")
+	}
+	if _, err := bp128.DecodeAll64(make([]uint64, 100), encoded[:len(encoded)-1]); err == nil {
+		t.Fatalf("Expected error for truncated input, got nil")
+	}
+
+	encoded[1] = 65
+	if _, err := bp128.DecodeAll64(make([]uint64, 100), encoded); err == nil {
+		t.Fatalf("Expected error for invalid bit width, got nil")
+	}
+}
+
+func FuzzDecodeAll32(f *testing.F) {
+	for _, in := range [][]uint32{nil, {1, 2, 3}, make([]uint32, 300)} {
+		encoded, _ := bp128.EncodeAll32(in)
+		b := make([]byte, 4*len(encoded))
+		for i, w := range encoded {
+			binary.BigEndian.PutUint32(b[i*4:], w)
+		}
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		src := make([]uint32, len(b)/4)
+		for i := range src {
+			src[i] = binary.BigEndian.Uint32(b[i*4:])
+		}
+
+		n := bp128.Count32(src)
+		if n > 1<<20 {
+			return
+		}
+		dst := make([]uint32, n)
+		got, err := bp128.DecodeAll32(dst, src)
+		if err != nil {
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAll32 mismatch: got %v, exp %v", got, n)
+		}
+		if n > 0 {
+			if _, err := bp128.DecodeAll32(make([]uint32, n-1), src); err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+		}
+		if _, err := bp128.DecodeAllDelta32(dst, src); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func FuzzDecodeAll64(f *testing.F) {
+	for _, in := range [][]uint64{nil, {1, 2, 3}, make([]uint64, 300)} {
+		encoded, _ := bp128.EncodeAll64(in)
+		b := make([]byte, 8*len(encoded))
+		for i, w := range encoded {
+			binary.BigEndian.PutUint64(b[i*8:], w)
+		}
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		src := make([]uint64, len(b)/8)
+		for i := range src {
+			src[i] = binary.BigEndian.Uint64(b[i*8:])
+		}
+
+		n := bp128.Count64(src)
+		if n < 0 || n > 1<<20 {
+			return
+		}
+		dst := make([]uint64, n)
+		got, err := bp128.DecodeAll64(dst, src)
+		if err != nil {
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAll64 mismatch: got %v, exp %v", got, n)
+		}
+		if n > 0 {
+			if _, err := bp128.DecodeAll64(make([]uint64, n-1), src); err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+		}
+		if _, err := bp128.DecodeAllDelta64(dst, src); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func Test_Pack32_ZeroBits(t *testing.T) {
+	var src [bp128.BlockSize32]uint32
+	if bits := bp128.MaxBits32(&src); bits != 0 {
+		t.Fatalf("MaxBits32 = %v, exp 0", bits)
+	}
+	if n := bp128.Pack32(nil, &src, 0); n != 0 {
+		t.Fatalf("Pack32 = %v, exp 0", n)
+	}
+
+	dst := [bp128.BlockSize32]uint32{1, 2, 3}
+	if n := bp128.Unpack32(&dst, nil, 0); n != 0 || dst != src {
+		t.Fatalf("Unpack32 mismatch")
+	}
+}
+
+func BenchmarkUnpack32(b *testing.B) {
+	var src [bp128.BlockSize32]uint32
+	for i := range src {
+		src[i] = uint32(i)
+	}
+	packed := make([]uint32, bp128.PackedLen32(7))
+	bp128.Pack32(packed, &src, 7)
+
+	var dst [bp128.BlockSize32]uint32
+	b.SetBytes(bp128.BlockSize32 * 4)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bp128.Unpack32(&dst, packed, 7)
+	}
+}
+
+func BenchmarkDecodeAllDelta32(b *testing.B) {
+	x := make([]uint32, 1024)
+	for i := range x {
+		x[i] = uint32(i * 3)
+	}
+	encoded, _ := bp128.EncodeAllDelta32(x)
+	dst := make([]uint32, len(x))
+
+	b.SetBytes(int64(len(x) * 4))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bp128.DecodeAllDelta32(dst, encoded)
+	}
+}
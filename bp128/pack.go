@@ -0,0 +1,200 @@
+package bp128
+
+// Scalar kernels.  Each lane of a block is packed into its own bit stream as
+// described in the package documentation, processing one lane at a time.
+
+func pack32Scalar(dst []uint32, src *[BlockSize32]uint32, bits uint) {
+	for l := 0; l < 4; l++ {
+		var acc uint32
+		var s uint
+		k := l
+		for r := 0; r < 32; r++ {
+			v := src[r*4+l]
+			acc |= v << s
+			s += bits
+			if s >= 32 {
+				dst[k] = acc
+				k += 4
+				s -= 32
+
+				// The high bits of v that did not fit start the next word.
+				acc = v >> (bits - s)
+			}
+		}
+	}
+}
+
+func unpack32Scalar(dst *[BlockSize32]uint32, src []uint32, bits uint) {
+	mask := uint32(1)<<bits - 1
+	for l := 0; l < 4; l++ {
+		k := l
+		cur := src[k]
+		var s uint
+		for r := 0; r < 32; r++ {
+			if s == 32 {
+				k += 4
+				cur = src[k]
+				s = 0
+			}
+
+			v := cur >> s
+			if s+bits > 32 {
+				// The value continues in the low bits of the next word.
+				k += 4
+				cur = src[k]
+				v |= cur << (32 - s)
+				s = s + bits - 32
+			} else {
+				s += bits
+			}
+			dst[r*4+l] = v & mask
+		}
+	}
+}
+
+func deltaPack32Scalar(dst []uint32, src *[BlockSize32]uint32, bits uint, seed *[4]uint32) {
+	var buf [BlockSize32]uint32
+	for i := 0; i < 4; i++ {
+		buf[i] = src[i] - seed[i]
+	}
+	for i := 4; i < len(src); i++ {
+		buf[i] = src[i] - src[i-4]
+	}
+	copy(seed[:], src[BlockSize32-4:])
+	pack32Scalar(dst, &buf, bits)
+}
+
+func deltaUnpack32Scalar(dst *[BlockSize32]uint32, src []uint32, bits uint, seed *[4]uint32) {
+	unpack32Scalar(dst, src, bits)
+	for i := 0; i < 4; i++ {
+		dst[i] += seed[i]
+	}
+	for i := 4; i < len(dst); i++ {
+		dst[i] += dst[i-4]
+	}
+	copy(seed[:], dst[BlockSize32-4:])
+}
+
+func maxBits32Scalar(src *[BlockSize32]uint32) uint32 {
+	var v uint32
+	for _, x := range src {
+		v |= x
+	}
+	return v
+}
+
+func deltaMaxBits32Scalar(src *[BlockSize32]uint32, seed *[4]uint32) uint32 {
+	var v uint32
+	for i := 0; i < 4; i++ {
+		v |= src[i] - seed[i]
+	}
+	for i := 4; i < len(src); i++ {
+		v |= src[i] - src[i-4]
+	}
+	return v
+}
+
+func pack64Scalar(dst []uint64, src *[BlockSize64]uint64, bits uint) {
+	// With an odd width both lanes end with a half word sharing the last word.
+	if bits%2 == 1 {
+		dst[bits-1] = 0
+	}
+
+	for l := 0; l < 2; l++ {
+		var acc uint64
+		var s uint
+		k := l
+		for r := 0; r < 32; r++ {
+			v := src[r*2+l]
+			acc |= v << s
+			s += bits
+			if s >= 64 {
+				dst[k] = acc
+				k += 2
+				s -= 64
+				acc = v >> (bits - s)
+			}
+		}
+
+		if s > 0 {
+			dst[bits-1] |= acc << (32 * uint(l))
+		}
+	}
+}
+
+func unpack64Scalar(dst *[BlockSize64]uint64, src []uint64, bits uint) {
+	mask := uint64(1)<<bits - 1
+	full := int(bits / 2 * 2)
+	for l := 0; l < 2; l++ {
+		load := func(k int) uint64 {
+			if k < full {
+				return src[k]
+			}
+			return src[bits-1] >> (32 * uint(l)) & 0xffffffff
+		}
+
+		k := l
+		cur := load(k)
+		var s uint
+		for r := 0; r < 32; r++ {
+			if s == 64 {
+				k += 2
+				cur = load(k)
+				s = 0
+			}
+
+			v := cur >> s
+			if s+bits > 64 {
+				k += 2
+				cur = load(k)
+				v |= cur << (64 - s)
+				s = s + bits - 64
+			} else {
+				s += bits
+			}
+			dst[r*2+l] = v & mask
+		}
+	}
+}
+
+func deltaPack64Scalar(dst []uint64, src *[BlockSize64]uint64, bits uint, seed *[2]uint64) {
+	var buf [BlockSize64]uint64
+	for i := 0; i < 2; i++ {
+		buf[i] = src[i] - seed[i]
+	}
+	for i := 2; i < len(src); i++ {
+		buf[i] = src[i] - src[i-2]
+	}
+	copy(seed[:], src[BlockSize64-2:])
+	pack64Scalar(dst, &buf, bits)
+}
+
+func deltaUnpack64Scalar(dst *[BlockSize64]uint64, src []uint64, bits uint, seed *[2]uint64) {
+	unpack64Scalar(dst, src, bits)
+	for i := 0; i < 2; i++ {
+		dst[i] += seed[i]
+	}
+	for i := 2; i < len(dst); i++ {
+		dst[i] += dst[i-2]
+	}
+	copy(seed[:], dst[BlockSize64-2:])
+}
+
+func maxBits64Scalar(src *[BlockSize64]uint64) uint64 {
+	var v uint64
+	for _, x := range src {
+		v |= x
+	}
+	return v
+}
+
+func deltaMaxBits64Scalar(src *[BlockSize64]uint64, seed *[2]uint64) uint64 {
+	var v uint64
+	for i := 0; i < 2; i++ {
+		v |= src[i] - seed[i]
+	}
+	for i := 2; i < len(src); i++ {
+		v |= src[i] - src[i-2]
+	}
+	return v
+}
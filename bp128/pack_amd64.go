@@ -0,0 +1,101 @@
+package bp128
+
+// The SSE2 kernels pack and unpack a row of a block per iteration.  SSE2 is part
+// of the amd64 baseline so they are used when AVX2 is not supported.  The AVX2
+// kernels pack rows r and r+16 per iteration, so the bit streams of rows 16-31
+// start part way through a packed word and are shifted into place after packing,
+// or out of place before unpacking.  Memory is accessed with unaligned loads and
+// stores, so src and dst need no particular alignment.
+
+//go:noescape
+func pack32SSE(dst []uint32, src *[BlockSize32]uint32, bits uint)
+
+//go:noescape
+func unpack32SSE(dst *[BlockSize32]uint32, src []uint32, bits uint)
+
+//go:noescape
+func deltaPack32SSE(dst []uint32, src *[BlockSize32]uint32, bits uint, seed *[4]uint32)
+
+//go:noescape
+func deltaUnpack32SSE(dst *[BlockSize32]uint32, src []uint32, bits uint, seed *[4]uint32)
+
+//go:noescape
+func pack64SSE(dst []uint64, src *[BlockSize64]uint64, bits uint)
+
+//go:noescape
+func unpack64SSE(dst *[BlockSize64]uint64, src []uint64, bits uint)
+
+//go:noescape
+func deltaPack64SSE(dst []uint64, src *[BlockSize64]uint64, bits uint, seed *[2]uint64)
+
+//go:noescape
+func deltaUnpack64SSE(dst *[BlockSize64]uint64, src []uint64, bits uint, seed *[2]uint64)
+
+//go:noescape
+func pack32AVX2(dst []uint32, src *[BlockSize32]uint32, bits uint)
+
+//go:noescape
+func unpack32AVX2(dst *[BlockSize32]uint32, src []uint32, bits uint)
+
+//go:noescape
+func deltaPack32AVX2(dst []uint32, src *[BlockSize32]uint32, bits uint, seed *[4]uint32)
+
+//go:noescape
+func deltaUnpack32AVX2(dst *[BlockSize32]uint32, src []uint32, bits uint, seed *[4]uint32)
+
+//go:noescape
+func pack64AVX2(dst []uint64, src *[BlockSize64]uint64, bits uint)
+
+//go:noescape
+func unpack64AVX2(dst *[BlockSize64]uint64, src []uint64, bits uint)
+
+//go:noescape
+func deltaPack64AVX2(dst []uint64, src *[BlockSize64]uint64, bits uint, seed *[2]uint64)
+
+//go:noescape
+func deltaUnpack64AVX2(dst *[BlockSize64]uint64, src []uint64, bits uint, seed *[2]uint64)
+
+//go:noescape
+func maxBits32AVX2(src *[BlockSize32]uint32) uint32
+
+//go:noescape
+func deltaMaxBits32AVX2(src *[BlockSize32]uint32, seed *[4]uint32) uint32
+
+//go:noescape
+func maxBits64AVX2(src *[BlockSize64]uint64) uint64
+
+//go:noescape
+func deltaMaxBits64AVX2(src *[BlockSize64]uint64, seed *[2]uint64) uint64
+
+var (
+	support_avx2 bool
+)
+
+func init() {
+	pack32 = pack32SSE
+	unpack32 = unpack32SSE
+	deltaPack32 = deltaPack32SSE
+	deltaUnpack32 = deltaUnpack32SSE
+	pack64 = pack64SSE
+	unpack64 = unpack64SSE
+	deltaPack64 = deltaPack64SSE
+	deltaUnpack64 = deltaUnpack64SSE
+
+	cpu_info()
+	if !support_avx2 {
+		return
+	}
+
+	pack32 = pack32AVX2
+	unpack32 = unpack32AVX2
+	deltaPack32 = deltaPack32AVX2
+	deltaUnpack32 = deltaUnpack32AVX2
+	pack64 = pack64AVX2
+	unpack64 = unpack64AVX2
+	deltaPack64 = deltaPack64AVX2
+	deltaUnpack64 = deltaUnpack64AVX2
+	maxBits32 = maxBits32AVX2
+	deltaMaxBits32 = deltaMaxBits32AVX2
+	maxBits64 = maxBits64AVX2
+	deltaMaxBits64 = deltaMaxBits64AVX2
+}
@@ -0,0 +1,1415 @@
+#include "textflag.h"
+
+// Register use by the pack kernels:
+//
+//	SI  next row of src        X0  word being packed
+//	DI  next packed word       X1  current row
+//	BX  bit width              X2  shift count
+//	CX  bits used in X0        X4  previous row (delta only)
+//	DX  rows remaining         R8  seed (delta only)
+//
+// and by the unpack kernels:
+//
+//	SI  next packed word       X0  word being unpacked
+//	DI  next row of dst        X1  current row
+//	BX  bit width              X2  shift count
+//	CX  bits consumed in X0    X4  previous row (delta only)
+//	DX  rows remaining         X7  value mask
+//	R9  full 64bit packed words remaining
+
+// func pack32SSE(dst []uint32, src *[128]uint32, bits uint)
+TEXT ·pack32SSE(SB), NOSPLIT, $0-40
+	MOVQ dst_base+0(FP), DI
+	MOVQ src+24(FP), SI
+	MOVQ bits+32(FP), BX
+	PXOR X0, X0
+	XORQ CX, CX
+	MOVQ $32, DX
+
+loop:
+	MOVOU (SI), X1
+	MOVQ  CX, X2
+	MOVO  X1, X3
+	PSLLL X2, X3
+	POR   X3, X0
+	ADDQ  BX, CX
+	CMPQ  CX, $32
+	JLT   next
+
+	// The word is full, so store it and start the next one with the high bits
+	// of the row that did not fit.
+	MOVOU X0, (DI)
+	ADDQ  $16, DI
+	SUBQ  $32, CX
+	MOVQ  BX, AX
+	SUBQ  CX, AX
+	MOVQ  AX, X2
+	MOVO  X1, X0
+	PSRLL X2, X0
+
+next:
+	ADDQ $16, SI
+	DECQ DX
+	JNZ  loop
+	RET
+
+// func unpack32SSE(dst *[128]uint32, src []uint32, bits uint)
+TEXT ·unpack32SSE(SB), NOSPLIT, $0-40
+	MOVQ dst+0(FP), DI
+	MOVQ src_base+8(FP), SI
+	MOVQ bits+32(FP), BX
+
+	MOVQ   $1, AX
+	MOVQ   BX, CX
+	SHLQ   CX, AX
+	DECQ   AX
+	MOVQ   AX, X7
+	PSHUFD $0, X7, X7
+
+	MOVOU (SI), X0
+	ADDQ  $16, SI
+	XORQ  CX, CX
+	MOVQ  $32, DX
+
+loop:
+	CMPQ  CX, $32
+	JNE   extract
+	MOVOU (SI), X0
+	ADDQ  $16, SI
+	XORQ  CX, CX
+
+extract:
+	MOVQ  CX, X2
+	MOVO  X0, X1
+	PSRLL X2, X1
+	ADDQ  BX, CX
+	CMPQ  CX, $32
+	JLE   store
+
+	// The row continues in the low bits of the next word.
+	MOVOU (SI), X0
+	ADDQ  $16, SI
+	SUBQ  $32, CX
+	MOVQ  BX, AX
+	SUBQ  CX, AX
+	MOVQ  AX, X2
+	MOVO  X0, X3
+	PSLLL X2, X3
+	POR   X3, X1
+
+store:
+	PAND  X7, X1
+	MOVOU X1, (DI)
+	ADDQ  $16, DI
+	DECQ  DX
+	JNZ   loop
+	RET
+
+// func deltaPack32SSE(dst []uint32, src *[128]uint32, bits uint, seed *[4]uint32)
+TEXT ·deltaPack32SSE(SB), NOSPLIT, $0-48
+	MOVQ  dst_base+0(FP), DI
+	MOVQ  src+24(FP), SI
+	MOVQ  bits+32(FP), BX
+	MOVQ  seed+40(FP), R8
+	MOVOU (R8), X4
+	PXOR  X0, X0
+	XORQ  CX, CX
+	MOVQ  $32, DX
+
+loop:
+	MOVOU (SI), X1
+	MOVO  X1, X5
+	PSUBL X4, X1
+	MOVO  X5, X4
+	MOVQ  CX, X2
+	MOVO  X1, X3
+	PSLLL X2, X3
+	POR   X3, X0
+	ADDQ  BX, CX
+	CMPQ  CX, $32
+	JLT   next
+
+	MOVOU X0, (DI)
+	ADDQ  $16, DI
+	SUBQ  $32, CX
+	MOVQ  BX, AX
+	SUBQ  CX, AX
+	MOVQ  AX, X2
+	MOVO  X1, X0
+	PSRLL X2, X0
+
+next:
+	ADDQ  $16, SI
+	DECQ  DX
+	JNZ   loop
+	MOVOU X4, (R8)
+	RET
+
+// func deltaUnpack32SSE(dst *[128]uint32, src []uint32, bits uint, seed *[4]uint32)
+TEXT ·deltaUnpack32SSE(SB), NOSPLIT, $0-48
+	MOVQ  dst+0(FP), DI
+	MOVQ  src_base+8(FP), SI
+	MOVQ  bits+32(FP), BX
+	MOVQ  seed+40(FP), R8
+	MOVOU (R8), X4
+
+	MOVQ   $1, AX
+	MOVQ   BX, CX
+	SHLQ   CX, AX
+	DECQ   AX
+	MOVQ   AX, X7
+	PSHUFD $0, X7, X7
+
+	MOVOU (SI), X0
+	ADDQ  $16, SI
+	XORQ  CX, CX
+	MOVQ  $32, DX
+
+loop:
+	CMPQ  CX, $32
+	JNE   extract
+	MOVOU (SI), X0
+	ADDQ  $16, SI
+	XORQ  CX, CX
+
+extract:
+	MOVQ  CX, X2
+	MOVO  X0, X1
+	PSRLL X2, X1
+	ADDQ  BX, CX
+	CMPQ  CX, $32
+	JLE   store
+
+	MOVOU (SI), X0
+	ADDQ  $16, SI
+	SUBQ  $32, CX
+	MOVQ  BX, AX
+	SUBQ  CX, AX
+	MOVQ  AX, X2
+	MOVO  X0, X3
+	PSLLL X2, X3
+	POR   X3, X1
+
+store:
+	PAND  X7, X1
+	PADDL X4, X1
+	MOVO  X1, X4
+	MOVOU X1, (DI)
+	ADDQ  $16, DI
+	DECQ  DX
+	JNZ   loop
+	MOVOU X4, (R8)
+	RET
+
+// func pack64SSE(dst []uint64, src *[64]uint64, bits uint)
+TEXT ·pack64SSE(SB), NOSPLIT, $0-40
+	MOVQ dst_base+0(FP), DI
+	MOVQ src+24(FP), SI
+	MOVQ bits+32(FP), BX
+	PXOR X0, X0
+	XORQ CX, CX
+	MOVQ $32, DX
+
+loop:
+	MOVOU (SI), X1
+	MOVQ  CX, X2
+	MOVO  X1, X3
+	PSLLQ X2, X3
+	POR   X3, X0
+	ADDQ  BX, CX
+	CMPQ  CX, $64
+	JLT   next
+
+	MOVOU X0, (DI)
+	ADDQ  $16, DI
+	SUBQ  $64, CX
+	MOVQ  BX, AX
+	SUBQ  CX, AX
+	MOVQ  AX, X2
+	MOVO  X1, X0
+	PSRLQ X2, X0
+
+next:
+	ADDQ $16, SI
+	DECQ DX
+	JNZ  loop
+
+	// With an odd width both lanes end with a half word, which are stored
+	// together in the last word.
+	TESTQ  CX, CX
+	JZ     done
+	PSHUFD $0xd8, X0, X0
+	MOVQ   X0, (DI)
+
+done:
+	RET
+
+// func unpack64SSE(dst *[64]uint64, src []uint64, bits uint)
+TEXT ·unpack64SSE(SB), NOSPLIT, $0-40
+	MOVQ dst+0(FP), DI
+	MOVQ src_base+8(FP), SI
+	MOVQ bits+32(FP), BX
+
+	MOVQ       $-1, AX
+	MOVQ       $64, CX
+	SUBQ       BX, CX
+	SHRQ       CX, AX
+	MOVQ       AX, X7
+	PUNPCKLQDQ X7, X7
+
+	MOVQ BX, R9
+	SHRQ $1, R9
+
+	TESTQ  R9, R9
+	JZ     tail0
+	MOVOU  (SI), X0
+	ADDQ   $16, SI
+	DECQ   R9
+	JMP    start
+
+tail0:
+	MOVQ   (SI), X0
+	PSHUFD $0xd8, X0, X0
+
+start:
+	XORQ CX, CX
+	MOVQ $32, DX
+
+loop:
+	CMPQ  CX, $64
+	JNE   extract
+	TESTQ R9, R9
+	JZ    tail1
+	MOVOU (SI), X0
+	ADDQ  $16, SI
+	DECQ  R9
+	JMP   reloaded
+
+tail1:
+	MOVQ   (SI), X0
+	PSHUFD $0xd8, X0, X0
+
+reloaded:
+	XORQ CX, CX
+
+extract:
+	MOVQ  CX, X2
+	MOVO  X0, X1
+	PSRLQ X2, X1
+	ADDQ  BX, CX
+	CMPQ  CX, $64
+	JLE   store
+
+	TESTQ R9, R9
+	JZ    tail2
+	MOVOU (SI), X0
+	ADDQ  $16, SI
+	DECQ  R9
+	JMP   spanned
+
+tail2:
+	MOVQ   (SI), X0
+	PSHUFD $0xd8, X0, X0
+
+spanned:
+	SUBQ  $64, CX
+	MOVQ  BX, AX
+	SUBQ  CX, AX
+	MOVQ  AX, X2
+	MOVO  X0, X3
+	PSLLQ X2, X3
+	POR   X3, X1
+
+store:
+	PAND  X7, X1
+	MOVOU X1, (DI)
+	ADDQ  $16, DI
+	DECQ  DX
+	JNZ   loop
+	RET
+
+// func deltaPack64SSE(dst []uint64, src *[64]uint64, bits uint, seed *[2]uint64)
+TEXT ·deltaPack64SSE(SB), NOSPLIT, $0-48
+	MOVQ  dst_base+0(FP), DI
+	MOVQ  src+24(FP), SI
+	MOVQ  bits+32(FP), BX
+	MOVQ  seed+40(FP), R8
+	MOVOU (R8), X4
+	PXOR  X0, X0
+	XORQ  CX, CX
+	MOVQ  $32, DX
+
+loop:
+	MOVOU (SI), X1
+	MOVO  X1, X5
+	PSUBQ X4, X1
+	MOVO  X5, X4
+	MOVQ  CX, X2
+	MOVO  X1, X3
+	PSLLQ X2, X3
+	POR   X3, X0
+	ADDQ  BX, CX
+	CMPQ  CX, $64
+	JLT   next
+
+	MOVOU X0, (DI)
+	ADDQ  $16, DI
+	SUBQ  $64, CX
+	MOVQ  BX, AX
+	SUBQ  CX, AX
+	MOVQ  AX, X2
+	MOVO  X1, X0
+	PSRLQ X2, X0
+
+next:
+	ADDQ $16, SI
+	DECQ DX
+	JNZ  loop
+
+	TESTQ  CX, CX
+	JZ     done
+	PSHUFD $0xd8, X0, X0
+	MOVQ   X0, (DI)
+
+done:
+	MOVOU X4, (R8)
+	RET
+
+// func deltaUnpack64SSE(dst *[64]uint64, src []uint64, bits uint, seed *[2]uint64)
+TEXT ·deltaUnpack64SSE(SB), NOSPLIT, $0-48
+	MOVQ  dst+0(FP), DI
+	MOVQ  src_base+8(FP), SI
+	MOVQ  bits+32(FP), BX
+	MOVQ  seed+40(FP), R8
+	MOVOU (R8), X4
+
+	MOVQ       $-1, AX
+	MOVQ       $64, CX
+	SUBQ       BX, CX
+	SHRQ       CX, AX
+	MOVQ       AX, X7
+	PUNPCKLQDQ X7, X7
+
+	MOVQ BX, R9
+	SHRQ $1, R9
+
+	TESTQ  R9, R9
+	JZ     tail0
+	MOVOU  (SI), X0
+	ADDQ   $16, SI
+	DECQ   R9
+	JMP    start
+
+tail0:
+	MOVQ   (SI), X0
+	PSHUFD $0xd8, X0, X0
+
+start:
+	XORQ CX, CX
+	MOVQ $32, DX
+
+loop:
+	CMPQ  CX, $64
+	JNE   extract
+	TESTQ R9, R9
+	JZ    tail1
+	MOVOU (SI), X0
+	ADDQ  $16, SI
+	DECQ  R9
+	JMP   reloaded
+
+tail1:
+	MOVQ   (SI), X0
+	PSHUFD $0xd8, X0, X0
+
+reloaded:
+	XORQ CX, CX
+
+extract:
+	MOVQ  CX, X2
+	MOVO  X0, X1
+	PSRLQ X2, X1
+	ADDQ  BX, CX
+	CMPQ  CX, $64
+	JLE   store
+
+	TESTQ R9, R9
+	JZ    tail2
+	MOVOU (SI), X0
+	ADDQ  $16, SI
+	DECQ  R9
+	JMP   spanned
+
+tail2:
+	MOVQ   (SI), X0
+	PSHUFD $0xd8, X0, X0
+
+spanned:
+	SUBQ  $64, CX
+	MOVQ  BX, AX
+	SUBQ  CX, AX
+	MOVQ  AX, X2
+	MOVO  X0, X3
+	PSLLQ X2, X3
+	POR   X3, X1
+
+store:
+	PAND  X7, X1
+	PADDQ X4, X1
+	MOVO  X1, X4
+	MOVOU X1, (DI)
+	ADDQ  $16, DI
+	DECQ  DX
+	JNZ   loop
+	MOVOU X4, (R8)
+	RET
+
+// func maxBits32AVX2(src *[128]uint32) uint32
+TEXT ·maxBits32AVX2(SB), NOSPLIT, $0-12
+	MOVQ  src+0(FP), SI
+	VPXOR Y0, Y0, Y0
+	MOVQ  $16, DX
+
+loop:
+	VPOR (SI), Y0, Y0
+	ADDQ $32, SI
+	DECQ DX
+	JNZ  loop
+
+	VEXTRACTI128 $1, Y0, X1
+	VPOR         X1, X0, X0
+	VPSHUFD      $0x4e, X0, X1
+	VPOR         X1, X0, X0
+	VPSHUFD      $0xb1, X0, X1
+	VPOR         X1, X0, X0
+	VMOVD        X0, AX
+	VZEROUPPER
+	MOVL         AX, ret+8(FP)
+	RET
+
+// func deltaMaxBits32AVX2(src *[128]uint32, seed *[4]uint32) uint32
+TEXT ·deltaMaxBits32AVX2(SB), NOSPLIT, $0-20
+	MOVQ src+0(FP), SI
+	MOVQ seed+8(FP), R8
+
+	// The first rows are relative to the seed followed by the first row.
+	VMOVDQU     (R8), X1
+	VINSERTI128 $1, (SI), Y1, Y1
+	VMOVDQU     (SI), Y2
+	VPSUBD      Y1, Y2, Y0
+	ADDQ        $32, SI
+	MOVQ        $15, DX
+
+loop:
+	VMOVDQU (SI), Y2
+	VPSUBD  -16(SI), Y2, Y3
+	VPOR    Y3, Y0, Y0
+	ADDQ    $32, SI
+	DECQ    DX
+	JNZ     loop
+
+	VEXTRACTI128 $1, Y0, X1
+	VPOR         X1, X0, X0
+	VPSHUFD      $0x4e, X0, X1
+	VPOR         X1, X0, X0
+	VPSHUFD      $0xb1, X0, X1
+	VPOR         X1, X0, X0
+	VMOVD        X0, AX
+	VZEROUPPER
+	MOVL         AX, ret+16(FP)
+	RET
+
+// func maxBits64AVX2(src *[64]uint64) uint64
+TEXT ·maxBits64AVX2(SB), NOSPLIT, $0-16
+	MOVQ  src+0(FP), SI
+	VPXOR Y0, Y0, Y0
+	MOVQ  $16, DX
+
+loop:
+	VPOR (SI), Y0, Y0
+	ADDQ $32, SI
+	DECQ DX
+	JNZ  loop
+
+	VEXTRACTI128 $1, Y0, X1
+	VPOR         X1, X0, X0
+	VPSHUFD      $0x4e, X0, X1
+	VPOR         X1, X0, X0
+	VMOVQ        X0, AX
+	VZEROUPPER
+	MOVQ         AX, ret+8(FP)
+	RET
+
+// func deltaMaxBits64AVX2(src *[64]uint64, seed *[2]uint64) uint64
+TEXT ·deltaMaxBits64AVX2(SB), NOSPLIT, $0-24
+	MOVQ src+0(FP), SI
+	MOVQ seed+8(FP), R8
+
+	VMOVDQU     (R8), X1
+	VINSERTI128 $1, (SI), Y1, Y1
+	VMOVDQU     (SI), Y2
+	VPSUBQ      Y1, Y2, Y0
+	ADDQ        $32, SI
+	MOVQ        $15, DX
+
+loop:
+	VMOVDQU (SI), Y2
+	VPSUBQ  -16(SI), Y2, Y3
+	VPOR    Y3, Y0, Y0
+	ADDQ    $32, SI
+	DECQ    DX
+	JNZ     loop
+
+	VEXTRACTI128 $1, Y0, X1
+	VPOR         X1, X0, X0
+	VPSHUFD      $0x4e, X0, X1
+	VPOR         X1, X0, X0
+	VMOVQ        X0, AX
+	VZEROUPPER
+	MOVQ         AX, ret+16(FP)
+	RET
+
+// The AVX2 kernels pack two rows per instruction, holding rows 0-15 of a block in
+// the low half of each YMM register and rows 16-31 in the high half.  The high
+// half's bit streams start part way through the packed words, at bit 16*bits of
+// each lane, so they are packed as if starting at a word boundary and then shifted
+// into place, or shifted out of place into a buffer on the stack before unpacking.
+// Registers are used as by the SSE2 kernels, with the high half of each row stored
+// to or loaded from R10.
+
+// func pack32AVX2(dst []uint32, src *[128]uint32, bits uint)
+TEXT ·pack32AVX2(SB), NOSPLIT, $0-40
+	MOVQ dst_base+0(FP), DI
+	MOVQ src+24(FP), SI
+	MOVQ bits+32(FP), BX
+
+	// Row 16 starts in word bits/2 of its lane.
+	MOVQ BX, R10
+	SHRQ $1, R10
+	SHLQ $4, R10
+	ADDQ DI, R10
+
+	VPXOR Y0, Y0, Y0
+	XORQ  CX, CX
+	MOVQ  $16, DX
+
+loop:
+	VMOVDQU     (SI), X1
+	VINSERTI128 $1, 256(SI), Y1, Y1
+	VMOVQ       CX, X2
+	VPSLLD      X2, Y1, Y3
+	VPOR        Y3, Y0, Y0
+	ADDQ        BX, CX
+	CMPQ        CX, $32
+	JLT         next
+
+	VMOVDQU      X0, (DI)
+	VEXTRACTI128 $1, Y0, (R10)
+	ADDQ         $16, DI
+	ADDQ         $16, R10
+	SUBQ         $32, CX
+	MOVQ         BX, AX
+	SUBQ         CX, AX
+	VMOVQ        AX, X2
+	VPSRLD       X2, Y1, Y0
+
+next:
+	ADDQ $16, SI
+	DECQ DX
+	JNZ  loop
+
+	// With an even width row 16 starts on a word boundary and every word has
+	// been stored.  With an odd width it starts half way through word bits/2,
+	// which also holds the end of row 15, so the words of rows 16-31 are moved
+	// up 16 bits from the last one down.
+	TESTQ        CX, CX
+	JZ           done
+	VEXTRACTI128 $1, Y0, (R10)
+	MOVQ         BX, DX
+	SHRQ         $1, DX
+	TESTQ        DX, DX
+	JZ           first
+
+shift:
+	VMOVDQU (R10), X1
+	VMOVDQU -16(R10), X3
+	VPSLLD  $16, X1, X1
+	VPSRLD  $16, X3, X3
+	VPOR    X3, X1, X1
+	VMOVDQU X1, (R10)
+	SUBQ    $16, R10
+	DECQ    DX
+	JNZ     shift
+
+first:
+	VMOVDQU (R10), X1
+	VPSLLD  $16, X1, X1
+	VPOR    X0, X1, X1
+	VMOVDQU X1, (R10)
+
+done:
+	VZEROUPPER
+	RET
+
+// func unpack32AVX2(dst *[128]uint32, src []uint32, bits uint)
+TEXT ·unpack32AVX2(SB), NOSPLIT, $256-40
+	MOVQ dst+0(FP), DI
+	MOVQ src_base+8(FP), SI
+	MOVQ bits+32(FP), BX
+
+	MOVQ         $1, AX
+	MOVQ         BX, CX
+	SHLQ         CX, AX
+	DECQ         AX
+	VMOVQ        AX, X7
+	VPBROADCASTD X7, Y7
+
+	MOVQ BX, R10
+	SHRQ $1, R10
+	SHLQ $4, R10
+	ADDQ SI, R10
+
+	// With an odd width the words of rows 16-31 are moved down 16 bits into
+	// buf, so that they start on a word boundary.
+	TESTQ $1, BX
+	JZ    start
+	LEAQ  buf-256(SP), R11
+	MOVQ  R11, R12
+	MOVQ  BX, DX
+	SHRQ  $1, DX
+	TESTQ DX, DX
+	JZ    last
+
+shift:
+	VMOVDQU (R10), X1
+	VMOVDQU 16(R10), X3
+	VPSRLD  $16, X1, X1
+	VPSLLD  $16, X3, X3
+	VPOR    X3, X1, X1
+	VMOVDQU X1, (R11)
+	ADDQ    $16, R10
+	ADDQ    $16, R11
+	DECQ    DX
+	JNZ     shift
+
+last:
+	VMOVDQU (R10), X1
+	VPSRLD  $16, X1, X1
+	VMOVDQU X1, (R11)
+	MOVQ    R12, R10
+
+start:
+	VMOVDQU     (SI), X0
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, SI
+	ADDQ        $16, R10
+	XORQ        CX, CX
+	MOVQ        $16, DX
+
+loop:
+	CMPQ        CX, $32
+	JNE         extract
+	VMOVDQU     (SI), X0
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, SI
+	ADDQ        $16, R10
+	XORQ        CX, CX
+
+extract:
+	VMOVQ  CX, X2
+	VPSRLD X2, Y0, Y1
+	ADDQ   BX, CX
+	CMPQ   CX, $32
+	JLE    store
+
+	VMOVDQU     (SI), X0
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, SI
+	ADDQ        $16, R10
+	SUBQ        $32, CX
+	MOVQ        BX, AX
+	SUBQ        CX, AX
+	VMOVQ       AX, X2
+	VPSLLD      X2, Y0, Y3
+	VPOR        Y3, Y1, Y1
+
+store:
+	VPAND        Y7, Y1, Y1
+	VMOVDQU      X1, (DI)
+	VEXTRACTI128 $1, Y1, 256(DI)
+	ADDQ         $16, DI
+	DECQ         DX
+	JNZ          loop
+	VZEROUPPER
+	RET
+
+// func deltaPack32AVX2(dst []uint32, src *[128]uint32, bits uint, seed *[4]uint32)
+TEXT ·deltaPack32AVX2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ src+24(FP), SI
+	MOVQ bits+32(FP), BX
+	MOVQ seed+40(FP), R8
+
+	// Row 0 is relative to the seed and row 16 to row 15.
+	VMOVDQU     (R8), X4
+	VINSERTI128 $1, 240(SI), Y4, Y4
+
+	MOVQ BX, R10
+	SHRQ $1, R10
+	SHLQ $4, R10
+	ADDQ DI, R10
+
+	VPXOR Y0, Y0, Y0
+	XORQ  CX, CX
+	MOVQ  $16, DX
+
+loop:
+	VMOVDQU     (SI), X5
+	VINSERTI128 $1, 256(SI), Y5, Y5
+	VPSUBD      Y4, Y5, Y1
+	VMOVDQA     Y5, Y4
+	VMOVQ       CX, X2
+	VPSLLD      X2, Y1, Y3
+	VPOR        Y3, Y0, Y0
+	ADDQ        BX, CX
+	CMPQ        CX, $32
+	JLT         next
+
+	VMOVDQU      X0, (DI)
+	VEXTRACTI128 $1, Y0, (R10)
+	ADDQ         $16, DI
+	ADDQ         $16, R10
+	SUBQ         $32, CX
+	MOVQ         BX, AX
+	SUBQ         CX, AX
+	VMOVQ        AX, X2
+	VPSRLD       X2, Y1, Y0
+
+next:
+	ADDQ $16, SI
+	DECQ DX
+	JNZ  loop
+
+	VEXTRACTI128 $1, Y4, (R8)
+
+	TESTQ        CX, CX
+	JZ           done
+	VEXTRACTI128 $1, Y0, (R10)
+	MOVQ         BX, DX
+	SHRQ         $1, DX
+	TESTQ        DX, DX
+	JZ           first
+
+shift:
+	VMOVDQU (R10), X1
+	VMOVDQU -16(R10), X3
+	VPSLLD  $16, X1, X1
+	VPSRLD  $16, X3, X3
+	VPOR    X3, X1, X1
+	VMOVDQU X1, (R10)
+	SUBQ    $16, R10
+	DECQ    DX
+	JNZ     shift
+
+first:
+	VMOVDQU (R10), X1
+	VPSLLD  $16, X1, X1
+	VPOR    X0, X1, X1
+	VMOVDQU X1, (R10)
+
+done:
+	VZEROUPPER
+	RET
+
+// func deltaUnpack32AVX2(dst *[128]uint32, src []uint32, bits uint, seed *[4]uint32)
+TEXT ·deltaUnpack32AVX2(SB), NOSPLIT, $256-48
+	MOVQ dst+0(FP), DI
+	MOVQ src_base+8(FP), SI
+	MOVQ bits+32(FP), BX
+	MOVQ seed+40(FP), R8
+
+	// Rows 0-15 are summed from the seed, and rows 16-31 from zero until row 15
+	// is known.
+	VMOVDQU (R8), X4
+
+	MOVQ         $1, AX
+	MOVQ         BX, CX
+	SHLQ         CX, AX
+	DECQ         AX
+	VMOVQ        AX, X7
+	VPBROADCASTD X7, Y7
+
+	MOVQ BX, R10
+	SHRQ $1, R10
+	SHLQ $4, R10
+	ADDQ SI, R10
+
+	TESTQ $1, BX
+	JZ    start
+	LEAQ  buf-256(SP), R11
+	MOVQ  R11, R12
+	MOVQ  BX, DX
+	SHRQ  $1, DX
+	TESTQ DX, DX
+	JZ    last
+
+shift:
+	VMOVDQU (R10), X1
+	VMOVDQU 16(R10), X3
+	VPSRLD  $16, X1, X1
+	VPSLLD  $16, X3, X3
+	VPOR    X3, X1, X1
+	VMOVDQU X1, (R11)
+	ADDQ    $16, R10
+	ADDQ    $16, R11
+	DECQ    DX
+	JNZ     shift
+
+last:
+	VMOVDQU (R10), X1
+	VPSRLD  $16, X1, X1
+	VMOVDQU X1, (R11)
+	MOVQ    R12, R10
+
+start:
+	VMOVDQU     (SI), X0
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, SI
+	ADDQ        $16, R10
+	XORQ        CX, CX
+	MOVQ        $16, DX
+
+loop:
+	CMPQ        CX, $32
+	JNE         extract
+	VMOVDQU     (SI), X0
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, SI
+	ADDQ        $16, R10
+	XORQ        CX, CX
+
+extract:
+	VMOVQ  CX, X2
+	VPSRLD X2, Y0, Y1
+	ADDQ   BX, CX
+	CMPQ   CX, $32
+	JLE    store
+
+	VMOVDQU     (SI), X0
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, SI
+	ADDQ        $16, R10
+	SUBQ        $32, CX
+	MOVQ        BX, AX
+	SUBQ        CX, AX
+	VMOVQ       AX, X2
+	VPSLLD      X2, Y0, Y3
+	VPOR        Y3, Y1, Y1
+
+store:
+	VPAND        Y7, Y1, Y1
+	VPADDD       Y4, Y1, Y1
+	VMOVDQA      Y1, Y4
+	VMOVDQU      X1, (DI)
+	VEXTRACTI128 $1, Y1, 256(DI)
+	ADDQ         $16, DI
+	DECQ         DX
+	JNZ          loop
+
+	// DI is at row 16, and row 15 is in the low half of Y4.
+	VINSERTI128 $1, X4, Y4, Y6
+	MOVQ        $8, DX
+
+add:
+	VPADDD  (DI), Y6, Y1
+	VMOVDQU Y1, (DI)
+	ADDQ    $32, DI
+	DECQ    DX
+	JNZ     add
+
+	VMOVDQU -16(DI), X1
+	VMOVDQU X1, (R8)
+	VZEROUPPER
+	RET
+
+// func pack64AVX2(dst []uint64, src *[64]uint64, bits uint)
+TEXT ·pack64AVX2(SB), NOSPLIT, $288-40
+	MOVQ dst_base+0(FP), DI
+	MOVQ src+24(FP), SI
+	MOVQ bits+32(FP), BX
+
+	// The words of rows 16-31 are packed into buf and merged into dst below.
+	LEAQ buf-288(SP), R10
+
+	VPXOR Y0, Y0, Y0
+	XORQ  CX, CX
+	MOVQ  $16, DX
+
+loop:
+	VMOVDQU     (SI), X1
+	VINSERTI128 $1, 256(SI), Y1, Y1
+	VMOVQ       CX, X2
+	VPSLLQ      X2, Y1, Y3
+	VPOR        Y3, Y0, Y0
+	ADDQ        BX, CX
+	CMPQ        CX, $64
+	JLT         next
+
+	VMOVDQU      X0, (DI)
+	VEXTRACTI128 $1, Y0, (R10)
+	ADDQ         $16, DI
+	ADDQ         $16, R10
+	SUBQ         $64, CX
+	MOVQ         BX, AX
+	SUBQ         CX, AX
+	VMOVQ        AX, X2
+	VPSRLQ       X2, Y1, Y0
+
+next:
+	ADDQ $16, SI
+	DECQ DX
+	JNZ  loop
+
+	// Row 16 starts at bit CX of word bits/4, which holds the end of row 15
+	// in X0.  The words in buf are shifted up by CX bits into place, ending
+	// with the tail word when the width is odd.
+	VEXTRACTI128 $1, Y0, (R10)
+	VPXOR        X1, X1, X1
+	VMOVDQU      X1, 16(R10)
+
+	VMOVQ CX, X5
+	MOVQ  $64, AX
+	SUBQ  CX, AX
+	VMOVQ AX, X6
+	MOVQ  BX, DX
+	INCQ  DX
+	SHRQ  $1, DX
+	MOVQ  BX, AX
+	SHRQ  $2, AX
+	SUBQ  AX, DX
+	LEAQ  buf-288(SP), R10
+
+merge:
+	VMOVDQU (R10), X1
+	VPSLLQ  X5, X1, X3
+	VPOR    X0, X3, X3
+	VPSRLQ  X6, X1, X0
+	ADDQ    $16, R10
+	DECQ    DX
+	JZ      last
+	VMOVDQU X3, (DI)
+	ADDQ    $16, DI
+	JMP     merge
+
+last:
+	TESTQ   $1, BX
+	JNZ     tail
+	VMOVDQU X3, (DI)
+	VZEROUPPER
+	RET
+
+tail:
+	VPSHUFD $0xd8, X3, X3
+	VMOVQ   X3, (DI)
+	VZEROUPPER
+	RET
+
+// func unpack64AVX2(dst *[64]uint64, src []uint64, bits uint)
+TEXT ·unpack64AVX2(SB), NOSPLIT, $288-40
+	MOVQ dst+0(FP), DI
+	MOVQ src_base+8(FP), SI
+	MOVQ bits+32(FP), BX
+
+	MOVQ         $-1, AX
+	MOVQ         $64, CX
+	SUBQ         BX, CX
+	SHRQ         CX, AX
+	VMOVQ        AX, X7
+	VPBROADCASTQ X7, Y7
+
+	// Words bits/4 up to the tail are copied into buf, the tail word
+	// spread over both lanes, and followed by a zero word.
+	MOVQ BX, R9
+	SHRQ $2, R9
+	MOVQ BX, DX
+	INCQ DX
+	SHRQ $1, DX
+	SUBQ R9, DX
+	MOVQ DX, R12
+	MOVQ R9, AX
+	SHLQ $4, AX
+	LEAQ (SI)(AX*1), R10
+	LEAQ buf-288(SP), R11
+
+copy:
+	CMPQ    DX, $1
+	JNE     full
+	TESTQ   $1, BX
+	JZ      full
+	VMOVQ   (R10), X1
+	VPSHUFD $0xd8, X1, X1
+	JMP     copied
+
+full:
+	VMOVDQU (R10), X1
+
+copied:
+	VMOVDQU X1, (R11)
+	ADDQ    $16, R10
+	ADDQ    $16, R11
+	DECQ    DX
+	JNZ     copy
+	VPXOR   X1, X1, X1
+	VMOVDQU X1, (R11)
+
+	// Rows 0-15 end in the first copied word, which is kept in X8.  The
+	// words in buf are then shifted down so that row 16 starts at bit 0.
+	LEAQ    buf-288(SP), R11
+	VMOVDQU (R11), X8
+	MOVQ    BX, AX
+	ANDQ    $3, AX
+	SHLQ    $4, AX
+	VMOVQ   AX, X5
+	MOVQ    $64, CX
+	SUBQ    AX, CX
+	VMOVQ   CX, X6
+	MOVQ    R12, DX
+
+shift:
+	VMOVDQU (R11), X1
+	VMOVDQU 16(R11), X3
+	VPSRLQ  X5, X1, X1
+	VPSLLQ  X6, X3, X3
+	VPOR    X3, X1, X1
+	VMOVDQU X1, (R11)
+	ADDQ    $16, R11
+	DECQ    DX
+	JNZ     shift
+
+	// R9 counts the words of rows 0-15 still to load from src.
+	LEAQ buf-288(SP), R10
+	XORQ CX, CX
+	MOVQ $16, DX
+
+	TESTQ       R9, R9
+	JZ          low0
+	VMOVDQU     (SI), X0
+	ADDQ        $16, SI
+	DECQ        R9
+	JMP         high0
+
+low0:
+	VMOVDQA X8, X0
+
+high0:
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, R10
+
+loop:
+	CMPQ CX, $64
+	JNE  extract
+	TESTQ       R9, R9
+	JZ          low1
+	VMOVDQU     (SI), X0
+	ADDQ        $16, SI
+	DECQ        R9
+	JMP         high1
+
+low1:
+	VMOVDQA X8, X0
+
+high1:
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, R10
+	XORQ CX, CX
+
+extract:
+	VMOVQ  CX, X2
+	VPSRLQ X2, Y0, Y1
+	ADDQ   BX, CX
+	CMPQ   CX, $64
+	JLE    store
+
+	TESTQ       R9, R9
+	JZ          low2
+	VMOVDQU     (SI), X0
+	ADDQ        $16, SI
+	DECQ        R9
+	JMP         high2
+
+low2:
+	VMOVDQA X8, X0
+
+high2:
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, R10
+	SUBQ   $64, CX
+	MOVQ   BX, AX
+	SUBQ   CX, AX
+	VMOVQ  AX, X2
+	VPSLLQ X2, Y0, Y3
+	VPOR   Y3, Y1, Y1
+
+store:
+	VPAND        Y7, Y1, Y1
+	VMOVDQU      X1, (DI)
+	VEXTRACTI128 $1, Y1, 256(DI)
+	ADDQ         $16, DI
+	DECQ         DX
+	JNZ          loop
+	VZEROUPPER
+	RET
+
+// func deltaPack64AVX2(dst []uint64, src *[64]uint64, bits uint, seed *[2]uint64)
+TEXT ·deltaPack64AVX2(SB), NOSPLIT, $288-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ src+24(FP), SI
+	MOVQ bits+32(FP), BX
+	MOVQ seed+40(FP), R8
+
+	VMOVDQU     (R8), X4
+	VINSERTI128 $1, 240(SI), Y4, Y4
+
+	// The words of rows 16-31 are packed into buf and merged into dst below.
+	LEAQ buf-288(SP), R10
+
+	VPXOR Y0, Y0, Y0
+	XORQ  CX, CX
+	MOVQ  $16, DX
+
+loop:
+	VMOVDQU     (SI), X5
+	VINSERTI128 $1, 256(SI), Y5, Y5
+	VPSUBQ      Y4, Y5, Y1
+	VMOVDQA     Y5, Y4
+	VMOVQ       CX, X2
+	VPSLLQ      X2, Y1, Y3
+	VPOR        Y3, Y0, Y0
+	ADDQ        BX, CX
+	CMPQ        CX, $64
+	JLT         next
+
+	VMOVDQU      X0, (DI)
+	VEXTRACTI128 $1, Y0, (R10)
+	ADDQ         $16, DI
+	ADDQ         $16, R10
+	SUBQ         $64, CX
+	MOVQ         BX, AX
+	SUBQ         CX, AX
+	VMOVQ        AX, X2
+	VPSRLQ       X2, Y1, Y0
+
+next:
+	ADDQ $16, SI
+	DECQ DX
+	JNZ  loop
+
+	VEXTRACTI128 $1, Y4, (R8)
+
+	// Row 16 starts at bit CX of word bits/4, which holds the end of row 15
+	// in X0.  The words in buf are shifted up by CX bits into place, ending
+	// with the tail word when the width is odd.
+	VEXTRACTI128 $1, Y0, (R10)
+	VPXOR        X1, X1, X1
+	VMOVDQU      X1, 16(R10)
+
+	VMOVQ CX, X5
+	MOVQ  $64, AX
+	SUBQ  CX, AX
+	VMOVQ AX, X6
+	MOVQ  BX, DX
+	INCQ  DX
+	SHRQ  $1, DX
+	MOVQ  BX, AX
+	SHRQ  $2, AX
+	SUBQ  AX, DX
+	LEAQ  buf-288(SP), R10
+
+merge:
+	VMOVDQU (R10), X1
+	VPSLLQ  X5, X1, X3
+	VPOR    X0, X3, X3
+	VPSRLQ  X6, X1, X0
+	ADDQ    $16, R10
+	DECQ    DX
+	JZ      last
+	VMOVDQU X3, (DI)
+	ADDQ    $16, DI
+	JMP     merge
+
+last:
+	TESTQ   $1, BX
+	JNZ     tail
+	VMOVDQU X3, (DI)
+	VZEROUPPER
+	RET
+
+tail:
+	VPSHUFD $0xd8, X3, X3
+	VMOVQ   X3, (DI)
+	VZEROUPPER
+	RET
+
+// func deltaUnpack64AVX2(dst *[64]uint64, src []uint64, bits uint, seed *[2]uint64)
+TEXT ·deltaUnpack64AVX2(SB), NOSPLIT, $288-48
+	MOVQ dst+0(FP), DI
+	MOVQ src_base+8(FP), SI
+	MOVQ bits+32(FP), BX
+	MOVQ seed+40(FP), R8
+
+	VMOVDQU (R8), X4
+
+	MOVQ         $-1, AX
+	MOVQ         $64, CX
+	SUBQ         BX, CX
+	SHRQ         CX, AX
+	VMOVQ        AX, X7
+	VPBROADCASTQ X7, Y7
+
+	// Words bits/4 up to the tail are copied into buf, the tail word
+	// spread over both lanes, and followed by a zero word.
+	MOVQ BX, R9
+	SHRQ $2, R9
+	MOVQ BX, DX
+	INCQ DX
+	SHRQ $1, DX
+	SUBQ R9, DX
+	MOVQ DX, R12
+	MOVQ R9, AX
+	SHLQ $4, AX
+	LEAQ (SI)(AX*1), R10
+	LEAQ buf-288(SP), R11
+
+copy:
+	CMPQ    DX, $1
+	JNE     full
+	TESTQ   $1, BX
+	JZ      full
+	VMOVQ   (R10), X1
+	VPSHUFD $0xd8, X1, X1
+	JMP     copied
+
+full:
+	VMOVDQU (R10), X1
+
+copied:
+	VMOVDQU X1, (R11)
+	ADDQ    $16, R10
+	ADDQ    $16, R11
+	DECQ    DX
+	JNZ     copy
+	VPXOR   X1, X1, X1
+	VMOVDQU X1, (R11)
+
+	// Rows 0-15 end in the first copied word, which is kept in X8.  The
+	// words in buf are then shifted down so that row 16 starts at bit 0.
+	LEAQ    buf-288(SP), R11
+	VMOVDQU (R11), X8
+	MOVQ    BX, AX
+	ANDQ    $3, AX
+	SHLQ    $4, AX
+	VMOVQ   AX, X5
+	MOVQ    $64, CX
+	SUBQ    AX, CX
+	VMOVQ   CX, X6
+	MOVQ    R12, DX
+
+shift:
+	VMOVDQU (R11), X1
+	VMOVDQU 16(R11), X3
+	VPSRLQ  X5, X1, X1
+	VPSLLQ  X6, X3, X3
+	VPOR    X3, X1, X1
+	VMOVDQU X1, (R11)
+	ADDQ    $16, R11
+	DECQ    DX
+	JNZ     shift
+
+	// R9 counts the words of rows 0-15 still to load from src.
+	LEAQ buf-288(SP), R10
+	XORQ CX, CX
+	MOVQ $16, DX
+
+	TESTQ       R9, R9
+	JZ          low0
+	VMOVDQU     (SI), X0
+	ADDQ        $16, SI
+	DECQ        R9
+	JMP         high0
+
+low0:
+	VMOVDQA X8, X0
+
+high0:
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, R10
+
+loop:
+	CMPQ CX, $64
+	JNE  extract
+	TESTQ       R9, R9
+	JZ          low1
+	VMOVDQU     (SI), X0
+	ADDQ        $16, SI
+	DECQ        R9
+	JMP         high1
+
+low1:
+	VMOVDQA X8, X0
+
+high1:
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, R10
+	XORQ CX, CX
+
+extract:
+	VMOVQ  CX, X2
+	VPSRLQ X2, Y0, Y1
+	ADDQ   BX, CX
+	CMPQ   CX, $64
+	JLE    store
+
+	TESTQ       R9, R9
+	JZ          low2
+	VMOVDQU     (SI), X0
+	ADDQ        $16, SI
+	DECQ        R9
+	JMP         high2
+
+low2:
+	VMOVDQA X8, X0
+
+high2:
+	VINSERTI128 $1, (R10), Y0, Y0
+	ADDQ        $16, R10
+	SUBQ   $64, CX
+	MOVQ   BX, AX
+	SUBQ   CX, AX
+	VMOVQ  AX, X2
+	VPSLLQ X2, Y0, Y3
+	VPOR   Y3, Y1, Y1
+
+store:
+	VPAND        Y7, Y1, Y1
+	VPADDQ       Y4, Y1, Y1
+	VMOVDQA      Y1, Y4
+	VMOVDQU      X1, (DI)
+	VEXTRACTI128 $1, Y1, 256(DI)
+	ADDQ         $16, DI
+	DECQ         DX
+	JNZ          loop
+
+	VINSERTI128 $1, X4, Y4, Y6
+	MOVQ        $8, DX
+
+add:
+	VPADDQ  (DI), Y6, Y1
+	VMOVDQU Y1, (DI)
+	ADDQ    $32, DI
+	DECQ    DX
+	JNZ     add
+
+	VMOVDQU -16(DI), X1
+	VMOVDQU X1, (R8)
+	VZEROUPPER
+	RET
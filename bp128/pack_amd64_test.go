@@ -0,0 +1,210 @@
+package bp128
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randomBlock32(bits int) *[BlockSize32]uint32 {
+	var src [BlockSize32]uint32
+	for i := range src {
+		src[i] = uint32(rand.Uint64() & (1<<uint(bits) - 1))
+	}
+	return &src
+}
+
+func randomBlock64(bits int) *[BlockSize64]uint64 {
+	var src [BlockSize64]uint64
+	for i := range src {
+		src[i] = rand.Uint64() & (1<<uint(bits) - 1)
+	}
+	return &src
+}
+
+func testPack32(t *testing.T, pack func([]uint32, *[BlockSize32]uint32, uint), unpack func(*[BlockSize32]uint32, []uint32, uint)) {
+	for bits := 1; bits <= 32; bits++ {
+		src := randomBlock32(bits)
+
+		exp := make([]uint32, PackedLen32(bits))
+		pack32Scalar(exp, src, uint(bits))
+		got := make([]uint32, PackedLen32(bits))
+		pack(got, src, uint(bits))
+		for i := range exp {
+			if got[i] != exp[i] {
+				t.Fatalf("bits %d: packed[%d] = %x, exp %x", bits, i, got[i], exp[i])
+			}
+		}
+
+		var dst [BlockSize32]uint32
+		unpack(&dst, got, uint(bits))
+		if dst != *src {
+			t.Fatalf("bits %d: unpacked mismatch", bits)
+		}
+	}
+}
+
+func testDeltaPack32(t *testing.T, pack func([]uint32, *[BlockSize32]uint32, uint, *[4]uint32), unpack func(*[BlockSize32]uint32, []uint32, uint, *[4]uint32)) {
+	for bits := 1; bits <= 32; bits++ {
+		seed := [4]uint32{1, 2, 3, 4}
+		src := randomBlock32(bits)
+		for i := range src {
+			if i < 4 {
+				src[i] += seed[i]
+			} else {
+				src[i] += src[i-4]
+			}
+		}
+
+		expSeed, gotSeed := seed, seed
+		exp := make([]uint32, PackedLen32(bits))
+		deltaPack32Scalar(exp, src, uint(bits), &expSeed)
+		got := make([]uint32, PackedLen32(bits))
+		pack(got, src, uint(bits), &gotSeed)
+		if gotSeed != expSeed {
+			t.Fatalf("bits %d: seed = %v, exp %v", bits, gotSeed, expSeed)
+		}
+		for i := range exp {
+			if got[i] != exp[i] {
+				t.Fatalf("bits %d: packed[%d] = %x, exp %x", bits, i, got[i], exp[i])
+			}
+		}
+
+		var expDst, gotDst [BlockSize32]uint32
+		expSeed, gotSeed = seed, seed
+		deltaUnpack32Scalar(&expDst, exp, uint(bits), &expSeed)
+		unpack(&gotDst, got, uint(bits), &gotSeed)
+		if gotDst != expDst || gotSeed != expSeed {
+			t.Fatalf("bits %d: unpacked mismatch", bits)
+		}
+	}
+}
+
+func testPack64(t *testing.T, pack func([]uint64, *[BlockSize64]uint64, uint), unpack func(*[BlockSize64]uint64, []uint64, uint)) {
+	for bits := 1; bits <= 64; bits++ {
+		src := randomBlock64(bits)
+
+		exp := make([]uint64, PackedLen64(bits))
+		pack64Scalar(exp, src, uint(bits))
+		got := make([]uint64, PackedLen64(bits))
+		pack(got, src, uint(bits))
+		for i := range exp {
+			if got[i] != exp[i] {
+				t.Fatalf("bits %d: packed[%d] = %x, exp %x", bits, i, got[i], exp[i])
+			}
+		}
+
+		var dst [BlockSize64]uint64
+		unpack(&dst, got, uint(bits))
+		if dst != *src {
+			t.Fatalf("bits %d: unpacked mismatch", bits)
+		}
+	}
+}
+
+func testDeltaPack64(t *testing.T, pack func([]uint64, *[BlockSize64]uint64, uint, *[2]uint64), unpack func(*[BlockSize64]uint64, []uint64, uint, *[2]uint64)) {
+	for bits := 1; bits <= 64; bits++ {
+		seed := [2]uint64{1, 2}
+		src := randomBlock64(bits)
+		for i := range src {
+			if i < 2 {
+				src[i] += seed[i]
+			} else {
+				src[i] += src[i-2]
+			}
+		}
+
+		expSeed, gotSeed := seed, seed
+		exp := make([]uint64, PackedLen64(bits))
+		deltaPack64Scalar(exp, src, uint(bits), &expSeed)
+		got := make([]uint64, PackedLen64(bits))
+		pack(got, src, uint(bits), &gotSeed)
+		if gotSeed != expSeed {
+			t.Fatalf("bits %d: seed = %v, exp %v", bits, gotSeed, expSeed)
+		}
+		for i := range exp {
+			if got[i] != exp[i] {
+				t.Fatalf("bits %d: packed[%d] = %x, exp %x", bits, i, got[i], exp[i])
+			}
+		}
+
+		var expDst, gotDst [BlockSize64]uint64
+		expSeed, gotSeed = seed, seed
+		deltaUnpack64Scalar(&expDst, exp, uint(bits), &expSeed)
+		unpack(&gotDst, got, uint(bits), &gotSeed)
+		if gotDst != expDst || gotSeed != expSeed {
+			t.Fatalf("bits %d: unpacked mismatch", bits)
+		}
+	}
+}
+
+func TestPack32SSE(t *testing.T) {
+	testPack32(t, pack32SSE, unpack32SSE)
+}
+
+func TestDeltaPack32SSE(t *testing.T) {
+	testDeltaPack32(t, deltaPack32SSE, deltaUnpack32SSE)
+}
+
+func TestPack64SSE(t *testing.T) {
+	testPack64(t, pack64SSE, unpack64SSE)
+}
+
+func TestDeltaPack64SSE(t *testing.T) {
+	testDeltaPack64(t, deltaPack64SSE, deltaUnpack64SSE)
+}
+
+func TestPack32AVX2(t *testing.T) {
+	if !support_avx2 {
+		t.Skip("AVX2 not supported")
+	}
+	testPack32(t, pack32AVX2, unpack32AVX2)
+}
+
+func TestDeltaPack32AVX2(t *testing.T) {
+	if !support_avx2 {
+		t.Skip("AVX2 not supported")
+	}
+	testDeltaPack32(t, deltaPack32AVX2, deltaUnpack32AVX2)
+}
+
+func TestPack64AVX2(t *testing.T) {
+	if !support_avx2 {
+		t.Skip("AVX2 not supported")
+	}
+	testPack64(t, pack64AVX2, unpack64AVX2)
+}
+
+func TestDeltaPack64AVX2(t *testing.T) {
+	if !support_avx2 {
+		t.Skip("AVX2 not supported")
+	}
+	testDeltaPack64(t, deltaPack64AVX2, deltaUnpack64AVX2)
+}
+
+func TestMaxBitsAVX2(t *testing.T) {
+	if !support_avx2 {
+		t.Skip("AVX2 not supported")
+	}
+
+	for bits := 0; bits <= 32; bits++ {
+		src := randomBlock32(bits)
+		seed := [4]uint32{5, 6, 7, 8}
+		if got, exp := maxBits32AVX2(src), maxBits32Scalar(src); got != exp {
+			t.Fatalf("maxBits32AVX2 = %x, exp %x", got, exp)
+		}
+		if got, exp := deltaMaxBits32AVX2(src, &seed), deltaMaxBits32Scalar(src, &seed); got != exp {
+			t.Fatalf("deltaMaxBits32AVX2 = %x, exp %x", got, exp)
+		}
+	}
+
+	for bits := 0; bits <= 64; bits++ {
+		src := randomBlock64(bits)
+		seed := [2]uint64{5, 6}
+		if got, exp := maxBits64AVX2(src), maxBits64Scalar(src); got != exp {
+			t.Fatalf("maxBits64AVX2 = %x, exp %x", got, exp)
+		}
+		if got, exp := deltaMaxBits64AVX2(src, &seed), deltaMaxBits64Scalar(src, &seed); got != exp {
+			t.Fatalf("deltaMaxBits64AVX2 = %x, exp %x", got, exp)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// inspect writes the selector, value count, bit width and wasted bits of every word
+// packed with c in in to w, followed by a summary of the selectors used.
+func inspect(c codec, words bool, in []byte, w io.Writer) error {
+	if len(in)%c.wordSize != 0 {
+		return fmt.Errorf("invalid slice len remaining: %v", len(in)%c.wordSize)
+	}
+
+	// The selectors with a defined count, in order.
+	var selectors []int
+	ns := make(map[int]int)
+	for s := 0; s < 16; s++ {
+		if n, err := c.count(uint64(s) << uint(c.dataBits)); err == nil {
+			selectors = append(selectors, s)
+			ns[s] = n
+		}
+	}
+
+	wordCount := make(map[int]int)
+	var values, wasted int
+
+	if words {
+		fmt.Fprintf(w, "%8s  %-*s  %8s  %4s  %4s  %6s\n", "Word", 2*c.wordSize, "Value", "Selector", "N", "Bits", "Wasted")
+	}
+	for i := 0; i < len(in); i += c.wordSize {
+		v := c.word(in[i:])
+		s := c.selector(v)
+		n, ok := ns[s]
+		if !ok {
+			return fmt.Errorf("word %d: invalid selector value: %d", i/c.wordSize, s)
+		}
+
+		bits := c.bits(n)
+		waste := c.dataBits - n*bits
+		if words {
+			fmt.Fprintf(w, "%8d  %0*x  %8d  %4d  %4d  %6d\n", i/c.wordSize, 2*c.wordSize, v, s, n, bits, waste)
+		}
+
+		wordCount[s]++
+		values += n
+		wasted += waste
+	}
+
+	if words {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "codec %s: %d bytes, %d words, %d values", c.Name(), len(in), len(in)/c.wordSize, values)
+	if values > 0 {
+		fmt.Fprintf(w, ", %.2f bits per value", float64(8*len(in))/float64(values))
+	}
+	fmt.Fprintf(w, ", %d wasted bits\n\n", wasted)
+
+	// Lay out the summary as the selector table of the package docs, with rows for
+	// the number of words and values using each selector.
+	rows := []struct {
+		label string
+		cell  func(s int) int
+	}{
+		{"   Selector   ", func(s int) int { return s }},
+		{"     Bits     ", func(s int) int { return c.bits(ns[s]) }},
+		{"      N       ", func(s int) int { return ns[s] }},
+		{"   Wasted Bits", func(s int) int { return c.dataBits - ns[s]*c.bits(ns[s]) }},
+		{"     Words    ", func(s int) int { return wordCount[s] }},
+		{"    Values    ", func(s int) int { return wordCount[s] * ns[s] }},
+	}
+
+	cells := make([][]string, len(rows))
+	width := 3
+	for i, r := range rows {
+		for _, s := range selectors {
+			cell := fmt.Sprint(r.cell(s))
+			cells[i] = append(cells[i], cell)
+			if len(cell) > width {
+				width = len(cell)
+			}
+		}
+	}
+
+	inner := len(selectors)*(width+1) + 1
+	fmt.Fprintf(w, "┌──────────────┬%s┐\n", strings.Repeat("─", inner))
+	for i, r := range rows {
+		if i > 0 {
+			fmt.Fprintf(w, "├──────────────┼%s┤\n", strings.Repeat("─", inner))
+		}
+		fmt.Fprintf(w, "│%s│", r.label)
+		for _, cell := range cells[i] {
+			fmt.Fprintf(w, " %*s", width, cell)
+		}
+		fmt.Fprintln(w, " │")
+	}
+	fmt.Fprintf(w, "└──────────────┴%s┘\n", strings.Repeat("─", inner))
+	return nil
+}
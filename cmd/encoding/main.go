@@ -0,0 +1,259 @@
+// Command encoding encodes, decodes and inspects integers packed with the simple8b
+// and simple9 codecs.
+//
+// Usage:
+//
+//	encoding encode  [-codec name] [-format text|binary] [-o file] [file]
+//	encoding decode  [-codec name] [-format text|binary] [-o file] [file]
+//	encoding inspect [-codec name] [-words] [file]
+//
+// Input is read from file, or stdin if no file is given, and output is written to
+// stdout unless -o is set.  Plain values are either one decimal integer per line or,
+// with -format binary, 8 byte big endian integers.  Encoded values are the packed
+// words as written by the Encoder of the codec.
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jwilder/encoding"
+	"github.com/jwilder/encoding/simple8b"
+	"github.com/jwilder/encoding/simple9"
+)
+
+// codec describes the word layout of a codec that can be inspected.
+type codec struct {
+	encoding.Codec
+
+	// size of a word in bytes, and the number of bits holding values
+	wordSize int
+	dataBits int
+	count    func(word uint64) (int, error)
+}
+
+var codecs = map[string]codec{
+	"simple8b": {simple8b.Codec{}, 8, 60, simple8b.Count},
+	"simple9": {simple9.Codec{}, 4, 28, func(w uint64) (int, error) {
+		return simple9.Count(uint32(w))
+	}},
+}
+
+// word returns the word starting at b.
+func (c codec) word(b []byte) uint64 {
+	if c.wordSize == 4 {
+		return uint64(binary.BigEndian.Uint32(b))
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// selector returns the selector of w.
+func (c codec) selector(w uint64) int {
+	return int(w >> uint(c.dataBits))
+}
+
+// bits returns the number of bits used by each of the n values of a word.  Words
+// holding more values than data bits encode runs of ones and use none.
+func (c codec) bits(n int) int {
+	if n > c.dataBits {
+		return 0
+	}
+	return c.dataBits / n
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "encoding:", err)
+		os.Exit(1)
+	}
+}
+
+const usage = `usage: encoding <command> [flags] [file]
+
+Commands:
+  encode   pack plain integers using a codec
+  decode   unpack encoded integers to plain integers
+  inspect  print the selector of every word and a summary of an encoded file
+
+Run 'encoding <command> -h' for the flags of a command.`
+
+// run executes the command in args, reading from stdin and writing to stdout when
+// no files are given.
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", usage)
+	}
+
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	name := fs.String("codec", "simple8b", "codec to use: simple8b or simple9")
+	format := fs.String("format", "text", "format of plain integers: text or binary")
+	out := fs.String("o", "", "write output to file instead of stdout")
+	words := fs.Bool("words", true, "print every word when inspecting")
+
+	var cmd func(codec, []byte, io.Writer) error
+	switch args[0] {
+	case "encode":
+		cmd = func(c codec, in []byte, w io.Writer) error { return encode(c, *format, in, w) }
+	case "decode":
+		cmd = func(c codec, in []byte, w io.Writer) error { return decode(c, *format, in, w) }
+	case "inspect":
+		cmd = func(c codec, in []byte, w io.Writer) error { return inspect(c, *words, in, w) }
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+	}
+
+	if err := fs.Parse(args[1:]); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	c, ok := codecs[*name]
+	if !ok {
+		return fmt.Errorf("unknown codec %q", *name)
+	}
+	if *format != "text" && *format != "binary" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("too many arguments: %v", fs.Args())
+	}
+
+	var in []byte
+	var err error
+	if fs.NArg() == 1 {
+		in, err = os.ReadFile(fs.Arg(0))
+	} else {
+		in, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		bw := bufio.NewWriter(stdout)
+		if err := cmd(c, in, bw); err != nil {
+			return err
+		}
+		return bw.Flush()
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	if err := cmd(c, in, bw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// encode writes the plain integers of in packed with c to w.
+func encode(c codec, format string, in []byte, w io.Writer) error {
+	values, err := readValues(format, in)
+	if err != nil {
+		return err
+	}
+
+	b, err := c.Encode(nil, values)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// decode writes the values packed with c in in to w as plain integers.
+func decode(c codec, format string, in []byte, w io.Writer) error {
+	n, err := countValues(c, in)
+	if err != nil {
+		return err
+	}
+
+	values := make([]uint64, n)
+	if _, err := c.Decode(values, in); err != nil {
+		return err
+	}
+	return writeValues(format, values, w)
+}
+
+// countValues returns the number of values packed with c in b.
+func countValues(c codec, b []byte) (int, error) {
+	if len(b)%c.wordSize != 0 {
+		return 0, fmt.Errorf("invalid slice len remaining: %v", len(b)%c.wordSize)
+	}
+
+	var count int
+	for i := 0; i < len(b); i += c.wordSize {
+		n, err := c.count(c.word(b[i:]))
+		if err != nil {
+			return 0, fmt.Errorf("word %d: %v", i/c.wordSize, err)
+		}
+		count += n
+	}
+	return count, nil
+}
+
+// readValues parses plain integers from in.  Text input holds one decimal integer
+// per line, ignoring blank lines, and binary input 8 byte big endian integers.
+func readValues(format string, in []byte) ([]uint64, error) {
+	if format == "binary" {
+		if len(in)%8 != 0 {
+			return nil, fmt.Errorf("invalid slice len remaining: %v", len(in)%8)
+		}
+		values := make([]uint64, len(in)/8)
+		for i := range values {
+			values[i] = binary.BigEndian.Uint64(in[i*8:])
+		}
+		return values, nil
+	}
+
+	var values []uint64
+	for i, line := range strings.Split(string(in), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(line, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// writeValues writes values to w in the plain format.
+func writeValues(format string, values []uint64, w io.Writer) error {
+	var b []byte
+	for _, v := range values {
+		if format == "binary" {
+			var buf [8]byte
+			binary.BigEndian.PutUint64(buf[:], v)
+			b = append(b, buf[:]...)
+		} else {
+			b = strconv.AppendUint(b, v, 10)
+			b = append(b, '\n')
+		}
+
+		if len(b) >= 4096 {
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+			b = b[:0]
+		}
+	}
+	_, err := w.Write(b)
+	return err
+}
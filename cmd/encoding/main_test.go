@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCommand(t *testing.T, stdin []byte, args ...string) []byte {
+	var out bytes.Buffer
+	if err := run(args, bytes.NewReader(stdin), &out); err != nil {
+		t.Fatalf("Unexpected error running %v: %v", args, err)
+	}
+	return out.Bytes()
+}
+
+func Test_EncodeDecode_Text(t *testing.T) {
+	var in bytes.Buffer
+	for i := 0; i < 500; i++ {
+		fmt.Fprintln(&in, i%37)
+	}
+
+	for _, codec := range []string{"simple8b", "simple9"} {
+		encoded := runCommand(t, in.Bytes(), "encode", "-codec", codec)
+		decoded := runCommand(t, encoded, "decode", "-codec", codec)
+		if !bytes.Equal(decoded, in.Bytes()) {
+			t.Fatalf("Decoded mismatch for %s: got %q", codec, decoded)
+		}
+	}
+}
+
+func Test_EncodeDecode_Binary(t *testing.T) {
+	in := make([]byte, 8*300)
+	for i := 0; i < 300; i++ {
+		binary.BigEndian.PutUint64(in[i*8:], uint64(i)<<40)
+	}
+
+	encoded := runCommand(t, in, "encode", "-format", "binary")
+	decoded := runCommand(t, encoded, "decode", "-format", "binary")
+	if !bytes.Equal(decoded, in) {
+		t.Fatalf("Decoded mismatch: got %v bytes, exp %v", len(decoded), len(in))
+	}
+}
+
+func Test_EncodeDecode_Files(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "values.txt")
+	packed := filepath.Join(dir, "values.s8b")
+	if err := os.WriteFile(plain, []byte("1\n2\n\n3\n"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	runCommand(t, nil, "encode", "-o", packed, plain)
+	if got := string(runCommand(t, nil, "decode", packed)); got != "1\n2\n3\n" {
+		t.Fatalf("Decoded mismatch: got %q", got)
+	}
+}
+
+func Test_Inspect(t *testing.T) {
+	var in bytes.Buffer
+	fmt.Fprintln(&in, 1<<59)
+	for i := 0; i < 240; i++ {
+		fmt.Fprintln(&in, 1)
+	}
+	encoded := runCommand(t, in.Bytes(), "encode")
+
+	out := string(runCommand(t, encoded, "inspect"))
+	for _, exp := range []string{
+		"codec simple8b: 16 bytes, 2 words, 241 values",
+		"│   Selector   │",
+		"│     Words    │   1   0   0",
+	} {
+		if !strings.Contains(out, exp) {
+			t.Fatalf("Inspect output missing %q:\n%s", exp, out)
+		}
+	}
+
+	// The first word holds a single 60 bit value, and the second packs 240 ones.
+	lines := strings.Split(out, "\n")
+	if fields := strings.Fields(lines[1]); fields[2] != "15" || fields[3] != "1" || fields[4] != "60" {
+		t.Fatalf("Word 0 mismatch: got %v", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); fields[2] != "0" || fields[3] != "240" || fields[5] != "60" {
+		t.Fatalf("Word 1 mismatch: got %v", lines[2])
+	}
+}
+
+func Test_Errors(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"unknown"},
+		{"encode", "-codec", "unknown"},
+		{"encode", "-format", "unknown"},
+		{"decode", "a", "b"},
+	} {
+		if err := run(args, bytes.NewReader(nil), &bytes.Buffer{}); err == nil {
+			t.Fatalf("Expected error for %v, got nil", args)
+		}
+	}
+
+	// Out of range values, invalid text and partial words.
+	for _, tc := range []struct {
+		in   string
+		args []string
+	}{
+		{"268435456\n", []string{"encode", "-codec", "simple9"}},
+		{"abc\n", []string{"encode"}},
+		{"12345", []string{"decode"}},
+		{"12345", []string{"inspect"}},
+		{"\xff\xff\xff\xff", []string{"inspect", "-codec", "simple9"}},
+	} {
+		if err := run(tc.args, strings.NewReader(tc.in), &bytes.Buffer{}); err == nil {
+			t.Fatalf("Expected error for %v with input %q, got nil", tc.args, tc.in)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package encoding defines the interface shared by the integer codecs of this
+// library and a registry for looking them up by name or identifier.
+//
+// Codec packages register themselves when imported, in the same way as
+// database/sql drivers:
+//
+//	import _ "github.com/jwilder/encoding/simple8b"
+//
+// Data written with Encode starts with a one byte header holding the codec ID, so
+// that Decode can dispatch to the codec that wrote it without outside knowledge.
+package encoding
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// IDs of the codecs in this library.  ID 0 is never assigned so that a zeroed
+// header is detected as invalid.
+const (
+	Simple8b    byte = 1
+	Simple9     byte = 2
+	Simple8bRLE byte = 3
+	Fixed       byte = 4
+	Adaptive    byte = 5
+)
+
+// Codec encodes slices of unsigned integers to bytes.  Values are passed as uint64
+// regardless of the word size of the codec, and Encode returns an error if a value
+// is out of the range the codec supports.
+type Codec interface {
+	// Encode appends the encoded form of src to dst and returns the extended slice.
+	Encode(dst []byte, src []uint64) ([]byte, error)
+
+	// Decode writes the values encoded in src to dst.  It returns the number of
+	// values written or an error if src is invalid or dst is too small.
+	Decode(dst []uint64, src []byte) (int, error)
+
+	// MaxEncodedLen returns the maximum number of bytes used to encode n values.
+	MaxEncodedLen(n int) int
+
+	// Name returns the name the codec is registered under.
+	Name() string
+
+	// ID returns the identifier the codec is registered under, which is also its
+	// header byte.
+	ID() byte
+}
+
+var (
+	mu     sync.RWMutex
+	byID   [256]Codec
+	byName = make(map[string]Codec)
+)
+
+// Register makes a codec available by its name and ID.  It panics if c is nil, its
+// ID is 0 or either its name or ID is already registered.
+func Register(c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if c == nil {
+		panic("encoding: Register codec is nil")
+	}
+	if c.ID() == 0 {
+		panic("encoding: Register codec " + c.Name() + " has reserved ID 0")
+	}
+	if byID[c.ID()] != nil {
+		panic(fmt.Sprintf("encoding: Register called twice for ID %d", c.ID()))
+	}
+	if _, dup := byName[c.Name()]; dup {
+		panic("encoding: Register called twice for codec " + c.Name())
+	}
+
+	byID[c.ID()] = c
+	byName[c.Name()] = c
+}
+
+// Lookup returns the codec registered with id.
+func Lookup(id byte) (Codec, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c := byID[id]
+	return c, c != nil
+}
+
+// LookupName returns the codec registered with name.
+func LookupName(name string) (Codec, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := byName[name]
+	return c, ok
+}
+
+// Codecs returns the registered codecs ordered by ID.
+func Codecs() []Codec {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	codecs := make([]Codec, 0, len(byName))
+	for _, c := range byName {
+		codecs = append(codecs, c)
+	}
+	sort.Slice(codecs, func(i, j int) bool { return codecs[i].ID() < codecs[j].ID() })
+	return codecs
+}
+
+// Encode appends the header byte of c and the encoded form of src to dst and
+// returns the extended slice.
+func Encode(c Codec, dst []byte, src []uint64) ([]byte, error) {
+	return c.Encode(append(dst, c.ID()), src)
+}
+
+// Decode writes the values encoded in src by Encode to dst, using the codec named by
+// its header.  It returns the number of values written or an error.
+func Decode(dst []uint64, src []byte) (int, error) {
+	c, b, err := ReadHeader(src)
+	if err != nil {
+		return 0, err
+	}
+	return c.Decode(dst, b)
+}
+
+// ReadHeader returns the codec named by the header of src and the bytes following
+// the header.
+func ReadHeader(src []byte) (Codec, []byte, error) {
+	if len(src) == 0 {
+		return nil, nil, fmt.Errorf("codec header missing")
+	}
+
+	c, ok := Lookup(src[0])
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown codec ID: %d", src[0])
+	}
+	return c, src[1:], nil
+}
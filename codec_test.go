@@ -0,0 +1,129 @@
+package encoding_test
+
+import (
+	"testing"
+
+	"github.com/jwilder/encoding"
+	_ "github.com/jwilder/encoding/adaptive"
+	"github.com/jwilder/encoding/simple8b"
+	"github.com/jwilder/encoding/simple9"
+)
+
+func Test_Lookup(t *testing.T) {
+	for _, exp := range []encoding.Codec{simple8b.Codec{}, simple9.Codec{}} {
+		c, ok := encoding.Lookup(exp.ID())
+		if !ok || c.Name() != exp.Name() {
+			t.Fatalf("Lookup(%d) = %v %v, exp %v", exp.ID(), c, ok, exp.Name())
+		}
+
+		c, ok = encoding.LookupName(exp.Name())
+		if !ok || c.ID() != exp.ID() {
+			t.Fatalf("LookupName(%s) = %v %v, exp %v", exp.Name(), c, ok, exp.ID())
+		}
+	}
+
+	if _, ok := encoding.Lookup(0); ok {
+		t.Fatalf("Lookup(0) expected false")
+	}
+	if _, ok := encoding.LookupName("unknown"); ok {
+		t.Fatalf("LookupName(unknown) expected false")
+	}
+
+	codecs := encoding.Codecs()
+	if len(codecs) < 2 || codecs[0].ID() != encoding.Simple8b || codecs[1].ID() != encoding.Simple9 {
+		t.Fatalf("Codecs() = %v, exp simple8b and simple9 first", codecs)
+	}
+}
+
+func Test_EncodeDecode(t *testing.T) {
+	in := make([]uint64, 1000)
+	for i := range in {
+		in[i] = uint64(i % 300)
+	}
+
+	for _, c := range encoding.Codecs() {
+		b, err := encoding.Encode(c, nil, in)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.Name(), err)
+		}
+		if b[0] != c.ID() {
+			t.Fatalf("%s: header = %d, exp %d", c.Name(), b[0], c.ID())
+		}
+		if len(b)-1 > c.MaxEncodedLen(len(in)) {
+			t.Fatalf("%s: encoded len %d exceeds max %d", c.Name(), len(b)-1, c.MaxEncodedLen(len(in)))
+		}
+
+		out := make([]uint64, len(in))
+		n, err := encoding.Decode(out, b)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.Name(), err)
+		}
+		if n != len(in) {
+			t.Fatalf("%s: len mismatch: got %v, exp %v", c.Name(), n, len(in))
+		}
+		for i := range in {
+			if out[i] != in[i] {
+				t.Fatalf("%s: decoded[%d] != %v, got %v", c.Name(), i, in[i], out[i])
+			}
+		}
+
+		if _, err := encoding.Decode(out[:len(in)-1], b); err == nil {
+			t.Fatalf("%s: expected error for short dst, got nil", c.Name())
+		}
+	}
+}
+
+func Test_Decode_InvalidHeader(t *testing.T) {
+	if _, err := encoding.Decode(nil, nil); err == nil {
+		t.Fatalf("Expected error for missing header, got nil")
+	}
+	if _, err := encoding.Decode(nil, []byte{255}); err == nil {
+		t.Fatalf("Expected error for unknown codec, got nil")
+	}
+}
+
+func Test_Encode_OutOfRange(t *testing.T) {
+	if _, err := encoding.Encode(simple9.Codec{}, nil, []uint64{1, simple9.MaxValue + 1}); err == nil {
+		t.Fatalf("simple9: expected error, got nil")
+	}
+	if _, err := encoding.Encode(simple8b.Codec{}, nil, []uint64{1, simple8b.MaxValue + 1}); err == nil {
+		t.Fatalf("simple8b: expected error, got nil")
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	in := make([]uint64, 500)
+	for i := range in {
+		in[i] = uint64(i*i) % 1000
+	}
+	for _, c := range encoding.Codecs() {
+		b, _ := encoding.Encode(c, nil, in)
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		dst := make([]uint64, 4096)
+		n, err := encoding.Decode(dst, b)
+		if err != nil {
+			return
+		}
+		if n > 0 {
+			if _, err := encoding.Decode(make([]uint64, n-1), b); err == nil {
+				t.Fatalf("Expected error for short dst, got nil")
+			}
+		}
+	})
+}
+
+type dupCodec struct{ simple8b.Codec }
+
+func (dupCodec) Name() string { return "dup" }
+
+func Test_Register_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Expected panic registering a duplicate ID")
+		}
+	}()
+	encoding.Register(dupCodec{})
+}
@@ -0,0 +1,86 @@
+// Package column implements a simple on-disk format for a column of unsigned
+// integers.
+//
+// Values are written in blocks of BlockSize packed with simple8b, followed by a
+// footer recording the offset, count, minimum, maximum and sum of every block.  A
+// Reader loads only the footer when opened, and uses its statistics to skip blocks
+// that cannot hold values in the range being scanned and to answer aggregates
+// without reading the blocks at all.
+package column
+
+// The file is the packed blocks, each the bytes of a simple8b Encoder, followed by
+// the footer entries of the blocks and a fixed size trailer.  Integers are big
+// endian, and the CRC32C uses the Castagnoli polynomial over the footer entries:
+//
+// ┌─────────────────┬──────────────────────────────┬─────────────────────────────┐
+// │     Blocks      │        Footer entries        │           Trailer           │
+// ├─────────────────┼──────────────────────────────┼─────────────────────────────┤
+// │ simple8b words  │  40 bytes per block          │ block count  4 bytes        │
+// │                 │                              │ CRC32C       4 bytes        │
+// │                 │                              │ magic        "ENCL"         │
+// └─────────────────┴──────────────────────────────┴─────────────────────────────┘
+//
+// Each footer entry describes one block.  The sum wraps modulo 2^64:
+//
+// ┌──────────┬──────────┬──────────┬──────────┬──────────┐
+// │  Offset  │  Count   │   Min    │   Max    │   Sum    │
+// ├──────────┼──────────┼──────────┼──────────┼──────────┤
+// │ 8 bytes  │ 8 bytes  │ 8 bytes  │ 8 bytes  │ 8 bytes  │
+// └──────────┴──────────┴──────────┴──────────┴──────────┘
+import (
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// BlockSize is the number of values in each block, except the last.
+const BlockSize = 1024
+
+const (
+	magic      = "ENCL"
+	entryLen   = 40
+	trailerLen = 4 + 4 + len(magic)
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// BlockStats describes a block of values.
+type BlockStats struct {
+	// Offset is the position of the block in the file.
+	Offset int64
+
+	// Count is the number of values in the block.
+	Count int
+
+	// Min and Max are the smallest and largest values in the block.
+	Min, Max uint64
+
+	// Sum is the sum of the values in the block, modulo 2^64.
+	Sum uint64
+}
+
+// Overlaps returns true if the block may hold values between min and max inclusive.
+func (s BlockStats) Overlaps(min, max uint64) bool {
+	return s.Count > 0 && s.Min <= max && s.Max >= min
+}
+
+// appendEntry appends the footer entry of s to b.
+func appendEntry(b []byte, s BlockStats) []byte {
+	var buf [entryLen]byte
+	binary.BigEndian.PutUint64(buf[0:], uint64(s.Offset))
+	binary.BigEndian.PutUint64(buf[8:], uint64(s.Count))
+	binary.BigEndian.PutUint64(buf[16:], s.Min)
+	binary.BigEndian.PutUint64(buf[24:], s.Max)
+	binary.BigEndian.PutUint64(buf[32:], s.Sum)
+	return append(b, buf[:]...)
+}
+
+// readEntry returns the block statistics of the footer entry at the start of b.
+func readEntry(b []byte) BlockStats {
+	return BlockStats{
+		Offset: int64(binary.BigEndian.Uint64(b[0:])),
+		Count:  int(binary.BigEndian.Uint64(b[8:])),
+		Min:    binary.BigEndian.Uint64(b[16:]),
+		Max:    binary.BigEndian.Uint64(b[24:]),
+		Sum:    binary.BigEndian.Uint64(b[32:]),
+	}
+}
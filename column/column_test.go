@@ -0,0 +1,253 @@
+package column_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/jwilder/encoding/column"
+)
+
+// countingReaderAt counts the calls to ReadAt of the underlying reader.
+type countingReaderAt struct {
+	r     io.ReaderAt
+	reads int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.r.ReadAt(p, off)
+}
+
+func writeColumn(t *testing.T, values []uint64) []byte {
+	var buf bytes.Buffer
+	w := column.NewWriter(&buf)
+	for _, v := range values {
+		if err := w.Write(v); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func Test_Column_RoundTrip(t *testing.T) {
+	for _, n := range []int{0, 1, column.BlockSize, 5000} {
+		values := make([]uint64, n)
+		for i := range values {
+			values[i] = uint64(i*31) % 977
+		}
+
+		b := writeColumn(t, values)
+		r, err := column.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if r.Len() != n {
+			t.Fatalf("Len mismatch: got %v, exp %v", r.Len(), n)
+		}
+		if exp := (n + column.BlockSize - 1) / column.BlockSize; len(r.Blocks()) != exp {
+			t.Fatalf("Blocks mismatch: got %v, exp %v", len(r.Blocks()), exp)
+		}
+
+		var got []uint64
+		for i := range r.Blocks() {
+			if got, err = r.ReadBlock(got, i); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if len(got) != n {
+			t.Fatalf("Len mismatch: got %v, exp %v", len(got), n)
+		}
+
+		var sum uint64
+		for i := range values {
+			if got[i] != values[i] {
+				t.Fatalf("Decoded[%d] != %v, got %v", i, values[i], got[i])
+			}
+			sum += values[i]
+		}
+		if r.Sum() != sum {
+			t.Fatalf("Sum mismatch: got %v, exp %v", r.Sum(), sum)
+		}
+
+		it := r.Range(0, ^uint64(0))
+		i := 0
+		for it.Next() {
+			if it.Read() != values[i] || it.Index() != i {
+				t.Fatalf("Range[%d] != %v, got %v at %v", i, values[i], it.Read(), it.Index())
+			}
+			i++
+		}
+		if it.Err() != nil {
+			t.Fatalf("Unexpected error: %v", it.Err())
+		}
+		if i != n {
+			t.Fatalf("Range len mismatch: got %v, exp %v", i, n)
+		}
+	}
+}
+
+func Test_Column_Stats(t *testing.T) {
+	values := make([]uint64, 2*column.BlockSize+10)
+	for i := range values {
+		values[i] = uint64(1000 + i)
+	}
+
+	b := writeColumn(t, values)
+	r, err := column.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i, s := range r.Blocks() {
+		start := i * column.BlockSize
+		count := column.BlockSize
+		if i == 2 {
+			count = 10
+		}
+
+		var sum uint64
+		for _, v := range values[start : start+count] {
+			sum += v
+		}
+		exp := column.BlockStats{Offset: s.Offset, Count: count, Min: values[start], Max: values[start+count-1], Sum: sum}
+		if s != exp {
+			t.Fatalf("Block %d stats mismatch: got %+v, exp %+v", i, s, exp)
+		}
+	}
+}
+
+func Test_Column_RangeSkipsBlocks(t *testing.T) {
+	// Timestamps increase, so each block covers a distinct range of values.
+	values := make([]uint64, 10*column.BlockSize)
+	for i := range values {
+		values[i] = 1500000000 + uint64(i)*10
+	}
+	b := writeColumn(t, values)
+
+	cr := &countingReaderAt{r: bytes.NewReader(b)}
+	r, err := column.NewReader(cr, int64(len(b)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	footerReads := cr.reads
+
+	// A range within block 4 only.
+	lo, hi := values[4*column.BlockSize+100], values[4*column.BlockSize+200]
+	it := r.Range(lo, hi)
+	i := 4*column.BlockSize + 100
+	for it.Next() {
+		if it.Read() != values[i] || it.Index() != i {
+			t.Fatalf("Range[%d] != %v, got %v at %v", i, values[i], it.Read(), it.Index())
+		}
+		i++
+	}
+	if it.Err() != nil {
+		t.Fatalf("Unexpected error: %v", it.Err())
+	}
+	if exp := 4*column.BlockSize + 201; i != exp {
+		t.Fatalf("Range end mismatch: got %v, exp %v", i, exp)
+	}
+	if it.Next() {
+		t.Fatalf("Expected Next to return false after end of range")
+	}
+
+	if reads := cr.reads - footerReads; reads != 1 {
+		t.Fatalf("Expected 1 block read, got %v", reads)
+	}
+
+	// A range outside the column reads no blocks.
+	cr.reads = 0
+	if it := r.Range(0, 100); it.Next() || it.Err() != nil {
+		t.Fatalf("Expected empty range, got %v %v", it.Read(), it.Err())
+	}
+	if cr.reads != 0 {
+		t.Fatalf("Expected no block reads, got %v", cr.reads)
+	}
+}
+
+func Test_Column_ValueTooLarge(t *testing.T) {
+	w := column.NewWriter(io.Discard)
+	if err := w.Write(1 << 60); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_Column_Invalid(t *testing.T) {
+	values := make([]uint64, 3000)
+	for i := range values {
+		values[i] = uint64(i)
+	}
+	b := writeColumn(t, values)
+
+	// Truncated files lose the trailer.
+	for _, n := range []int{0, 5, len(b) - 1, len(b) - 50} {
+		if _, err := column.NewReader(bytes.NewReader(b[:n]), int64(n)); err == nil {
+			t.Fatalf("Expected error for column truncated to %d bytes, got nil", n)
+		}
+	}
+
+	// Damage to the footer fails the checksum.
+	damaged := append([]byte(nil), b...)
+	damaged[len(b)-20] ^= 1
+	if _, err := column.NewReader(bytes.NewReader(damaged), int64(len(damaged))); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	for _, n := range []int{0, 3, 2*column.BlockSize + 10} {
+		values := make([]uint64, n)
+		for i := range values {
+			values[i] = uint64(i * 7)
+		}
+
+		var buf bytes.Buffer
+		w := column.NewWriter(&buf)
+		for _, v := range values {
+			w.Write(v)
+		}
+		w.Close()
+		f.Add(buf.Bytes())
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		r, err := column.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			return
+		}
+
+		var values []uint64
+		for i := range r.Blocks() {
+			if values, err = r.ReadBlock(values, i); err != nil {
+				break
+			}
+		}
+
+		it := r.Range(0, ^uint64(0))
+		i := 0
+		for it.Next() {
+			if err == nil && (it.Read() != values[i] || it.Index() != i) {
+				t.Fatalf("Range[%d] != %v, got %v at %v", i, values[i], it.Read(), it.Index())
+			}
+			i++
+		}
+		if err == nil && (it.Err() != nil || i != r.Len()) {
+			t.Fatalf("Range len mismatch: got %v %v, exp %v", i, it.Err(), r.Len())
+		}
+	})
+}
+
+func Test_Writer_Closed(t *testing.T) {
+	w := column.NewWriter(io.Discard)
+	w.Write(1)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := w.Write(2); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
@@ -0,0 +1,244 @@
+package column
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+// Reader reads a column written by Writer from an io.ReaderAt.  Only the footer is
+// read when the Reader is created, and blocks are read as they are scanned.
+type Reader struct {
+	r io.ReaderAt
+
+	blocks []BlockStats
+
+	// ends[k] is the offset following block k, and starts[k] the index in the
+	// column of the first value of block k.
+	ends   []int64
+	starts []int
+	n      int
+}
+
+// NewReader returns a Reader over the column of size bytes in r.  An error is
+// returned if the footer is missing or invalid.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	if size < int64(trailerLen) {
+		return nil, fmt.Errorf("column too small: %d bytes", size)
+	}
+
+	var trailer [trailerLen]byte
+	if _, err := r.ReadAt(trailer[:], size-int64(trailerLen)); err != nil {
+		return nil, err
+	}
+	if string(trailer[8:]) != magic {
+		return nil, fmt.Errorf("invalid column: magic not found")
+	}
+
+	count := int64(binary.BigEndian.Uint32(trailer[0:]))
+	footerStart := size - int64(trailerLen) - count*entryLen
+	if footerStart < 0 {
+		return nil, fmt.Errorf("invalid column: footer of %d blocks exceeds %d bytes", count, size)
+	}
+
+	footer := make([]byte, count*entryLen)
+	if _, err := r.ReadAt(footer, footerStart); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(footer, castagnoli) != binary.BigEndian.Uint32(trailer[4:]) {
+		return nil, fmt.Errorf("invalid column: footer checksum mismatch")
+	}
+
+	c := &Reader{
+		r:      r,
+		blocks: make([]BlockStats, count),
+		ends:   make([]int64, count),
+		starts: make([]int, count),
+	}
+	for i := range c.blocks {
+		s := readEntry(footer[i*entryLen:])
+		if s.Count <= 0 || s.Count > BlockSize || s.Min > s.Max {
+			return nil, fmt.Errorf("invalid column: block %d has invalid statistics", i)
+		}
+
+		// Blocks are contiguous, so each ends where the next starts.
+		if (i == 0 && s.Offset != 0) || (i > 0 && s.Offset <= c.blocks[i-1].Offset) {
+			return nil, fmt.Errorf("invalid column: block %d has invalid offset %d", i, s.Offset)
+		}
+		if i > 0 {
+			c.ends[i-1] = s.Offset
+		}
+
+		c.blocks[i] = s
+		c.starts[i] = c.n
+		c.n += s.Count
+	}
+	if count > 0 {
+		if c.blocks[count-1].Offset >= footerStart {
+			return nil, fmt.Errorf("invalid column: block %d has invalid offset %d", count-1, c.blocks[count-1].Offset)
+		}
+		c.ends[count-1] = footerStart
+	}
+	return c, nil
+}
+
+// Len returns the number of values in the column.
+func (c *Reader) Len() int {
+	return c.n
+}
+
+// Blocks returns the statistics of the blocks in the column.  The slice must not be
+// modified.
+func (c *Reader) Blocks() []BlockStats {
+	return c.blocks
+}
+
+// Sum returns the sum of the values in the column, modulo 2^64, using the footer
+// alone.
+func (c *Reader) Sum() uint64 {
+	var sum uint64
+	for _, s := range c.blocks {
+		sum += s.Sum
+	}
+	return sum
+}
+
+// ReadBlock appends the values of block i to dst and returns the extended slice.
+func (c *Reader) ReadBlock(dst []uint64, i int) ([]uint64, error) {
+	b, err := c.readBlock(nil, i)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := simple8b.NewDecoder(b)
+	n := 0
+	for dec.Next() {
+		dst = append(dst, dec.Read())
+		n++
+	}
+	if n != c.blocks[i].Count {
+		return nil, fmt.Errorf("block %d decoded %d values, footer has %d", i, n, c.blocks[i].Count)
+	}
+	return dst, nil
+}
+
+// readBlock reads the packed bytes of block i into buf, growing it if needed.
+func (c *Reader) readBlock(buf []byte, i int) ([]byte, error) {
+	if i < 0 || i >= len(c.blocks) {
+		return nil, fmt.Errorf("block index out of range [%d] with length %d", i, len(c.blocks))
+	}
+
+	n := int(c.ends[i] - c.blocks[i].Offset)
+	if n%8 != 0 {
+		return nil, fmt.Errorf("invalid slice len remaining: %v", n%8)
+	}
+	if cap(buf) < n {
+		buf = make([]byte, n)
+	}
+	buf = buf[:n]
+	if _, err := c.r.ReadAt(buf, c.blocks[i].Offset); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Range returns an Iterator over the values v of the column with min <= v <= max.
+// Blocks whose statistics show they hold no such values are not read.
+func (c *Reader) Range(min, max uint64) *Iterator {
+	return &Iterator{
+		c:     c,
+		min:   min,
+		max:   max,
+		block: -1,
+		dec:   simple8b.NewDecoder(nil),
+	}
+}
+
+// Iterator iterates over the values of a column within a range.
+type Iterator struct {
+	c        *Reader
+	min, max uint64
+
+	// current block, its bytes and the number of its values read
+	block int
+	buf   []byte
+	dec   *simple8b.Decoder
+	n     int
+
+	v   uint64
+	i   int
+	err error
+}
+
+// Next returns true if there are remaining values in the range to be read.
+// Successive calls to Next advance to the next value in the range.
+func (it *Iterator) Next() bool {
+	for it.err == nil {
+		if it.block < 0 || !it.dec.Next() {
+			if !it.nextBlock() {
+				return false
+			}
+			continue
+		}
+
+		v := it.dec.Read()
+		it.i = it.c.starts[it.block] + it.n
+		it.n++
+		if it.n > it.c.blocks[it.block].Count {
+			it.err = fmt.Errorf("block %d decoded more values than footer count %d", it.block, it.c.blocks[it.block].Count)
+			return false
+		}
+		if v >= it.min && v <= it.max {
+			it.v = v
+			return true
+		}
+	}
+	return false
+}
+
+// nextBlock advances to the next block overlapping the range.  It returns false
+// when there are no more blocks or an error is encountered.
+func (it *Iterator) nextBlock() bool {
+	if it.block >= 0 && it.block < len(it.c.blocks) && it.n != it.c.blocks[it.block].Count {
+		it.err = fmt.Errorf("block %d decoded %d values, footer has %d", it.block, it.n, it.c.blocks[it.block].Count)
+		return false
+	}
+
+	for it.block+1 < len(it.c.blocks) {
+		it.block++
+		if !it.c.blocks[it.block].Overlaps(it.min, it.max) {
+			continue
+		}
+
+		if it.buf, it.err = it.c.readBlock(it.buf, it.block); it.err != nil {
+			return false
+		}
+		it.dec.SetBytes(it.buf)
+		it.n = 0
+		return true
+	}
+
+	// Leave the iterator past the last block so later calls return false.
+	it.block = len(it.c.blocks)
+	it.n = 0
+	return false
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (it *Iterator) Read() uint64 {
+	return it.v
+}
+
+// Index returns the position in the column of the current value.
+func (it *Iterator) Index() int {
+	return it.i
+}
+
+// Err returns the first error encountered while reading.
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,126 @@
+package column
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+// Writer writes a column of unsigned integers to an io.Writer.  Each block is
+// written once it is full, and Close writes the final block and the footer.
+type Writer struct {
+	w   io.Writer
+	enc *simple8b.Encoder
+
+	// statistics of the open block and the blocks written
+	stats  BlockStats
+	blocks []BlockStats
+
+	// number of bytes written to w
+	offset int64
+
+	err error
+}
+
+// NewWriter returns a Writer writing a column to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w:   w,
+		enc: simple8b.NewEncoder(),
+	}
+}
+
+// Write adds v to the column.  An error is returned if v is over simple8b.MaxValue
+// or a completed block cannot be written.
+func (w *Writer) Write(v uint64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if v > simple8b.MaxValue {
+		return fmt.Errorf("value out of bounds: %v", v)
+	}
+	if err := w.enc.Write(v); err != nil {
+		w.err = err
+		return err
+	}
+
+	s := &w.stats
+	if s.Count == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.Count == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.Sum += v
+	s.Count += 1
+
+	if s.Count == BlockSize {
+		w.writeBlock()
+	}
+	return w.err
+}
+
+// Blocks returns the statistics of the blocks written so far.
+func (w *Writer) Blocks() []BlockStats {
+	return w.blocks
+}
+
+// Close writes the open block and the footer.  It does not close the underlying
+// writer, and no values may be written after calling Close.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.stats.Count > 0 {
+		if w.writeBlock(); w.err != nil {
+			return w.err
+		}
+	}
+
+	footer := make([]byte, 0, len(w.blocks)*entryLen+trailerLen)
+	for _, s := range w.blocks {
+		footer = appendEntry(footer, s)
+	}
+
+	var b [4]byte
+	crc := crc32.Checksum(footer, castagnoli)
+	binary.BigEndian.PutUint32(b[:], uint32(len(w.blocks)))
+	footer = append(footer, b[:]...)
+	binary.BigEndian.PutUint32(b[:], crc)
+	footer = append(footer, b[:]...)
+	footer = append(footer, magic...)
+
+	w.write(footer)
+	if w.err == nil {
+		w.err = fmt.Errorf("column closed")
+		return nil
+	}
+	return w.err
+}
+
+// writeBlock writes the packed values of the open block and records its statistics.
+func (w *Writer) writeBlock() {
+	b, err := w.enc.Bytes()
+	if err != nil {
+		w.err = err
+		return
+	}
+
+	w.stats.Offset = w.offset
+	w.write(b)
+	w.blocks = append(w.blocks, w.stats)
+	w.stats = BlockStats{}
+	w.enc.Reset()
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	w.err = err
+}
@@ -0,0 +1,460 @@
+// Package container implements a checksummed block format for values encoded with
+// any registered codec.
+//
+// Encoded values carry no length or integrity check of their own, so a partially
+// written or damaged file may decode to fewer or different values without any
+// error.  A container wraps each block of encoded values with a header naming the
+// codec and the number of values, and a CRC32C trailer covering the header and
+// values.  The blocks are followed by an end record holding the total number of
+// values, so that input cut short between two blocks is detected too.  Decoding
+// reports ErrTruncated if the input ends before the end record and ErrCorrupt if a
+// block or the end record does not match its checksum or header.  Errors for an
+// unsupported version or unknown codec wrap ErrCorrupt, so they are matched with
+// errors.Is.
+package container
+
+// The encoded form is a sequence of blocks followed by an end record.  Integers are
+// big endian, and each CRC32C uses the Castagnoli polynomial over the bytes of the
+// block or record preceding it:
+//
+// ┌─────────┬─────────┬──────────┬──────────┬──────────┬──────────────┬──────────┐
+// │  Magic  │ Version │ Codec ID │  Count   │  Length  │    Values    │  CRC32C  │
+// ├─────────┼─────────┼──────────┼──────────┼──────────┼──────────────┼──────────┤
+// │ "ENCB"  │ 1 byte  │  1 byte  │ 4 bytes  │ 4 bytes  │ Length bytes │ 4 bytes  │
+// └─────────┴─────────┴──────────┴──────────┴──────────┴──────────────┴──────────┘
+//
+// The end record holds the number of values in all of the blocks:
+//
+// ┌─────────┬─────────┬──────────────┬──────────┐
+// │  Magic  │ Version │ Total Count  │  CRC32C  │
+// ├─────────┼─────────┼──────────────┼──────────┤
+// │ "ENCE"  │ 1 byte  │   8 bytes    │ 4 bytes  │
+// └─────────┴─────────┴──────────────┴──────────┘
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/jwilder/encoding"
+)
+
+const (
+	// Version is the version of the block format written by this package.
+	Version = 1
+
+	// BlockSize is the number of values in each block written by Encoder.
+	BlockSize = 1024
+
+	// MaxBlockSize is the largest number of values in a block.
+	MaxBlockSize = 1 << 16
+
+	magic    = "ENCB"
+	endMagic = "ENCE"
+
+	headerLen  = len(magic) + 1 + 1 + 4 + 4
+	trailerLen = 4
+	endLen     = len(endMagic) + 1 + 8 + trailerLen
+)
+
+var (
+	// ErrTruncated is returned when the input ends before the end record and the
+	// bytes of any partial block are consistent with a short write.  A damaged
+	// length that still fits the codec cannot be told apart from a short write, so
+	// it is reported as ErrTruncated too.
+	ErrTruncated = errors.New("container: truncated block")
+
+	// ErrCorrupt is returned when a block does not match its checksum or header.
+	ErrCorrupt = errors.New("container: corrupt block")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// AppendBlock appends a block holding the values of src encoded with c to dst.  An
+// error is returned if src holds more than MaxBlockSize values or c cannot encode
+// them.
+func AppendBlock(dst []byte, c encoding.Codec, src []uint64) ([]byte, error) {
+	if len(src) > MaxBlockSize {
+		return nil, fmt.Errorf("block too large: %d values, max %d", len(src), MaxBlockSize)
+	}
+
+	start := len(dst)
+	dst = append(dst, magic...)
+	dst = append(dst, Version, c.ID())
+
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(len(src)))
+	dst = append(dst, b[:]...)
+
+	// The length is filled in once the values are encoded.
+	dst = append(dst, 0, 0, 0, 0)
+	dst, err := c.Encode(dst, src)
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint32(dst[start+headerLen-4:], uint32(len(dst)-start-headerLen))
+
+	binary.BigEndian.PutUint32(b[:], crc32.Checksum(dst[start:], castagnoli))
+	return append(dst, b[:]...), nil
+}
+
+// AppendEnd appends the end record of a sequence of blocks holding count values to
+// dst.  Every sequence of blocks written with AppendBlock must be followed by it.
+func AppendEnd(dst []byte, count uint64) []byte {
+	start := len(dst)
+	dst = append(dst, endMagic...)
+	dst = append(dst, Version)
+
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], count)
+	dst = append(dst, b[:]...)
+
+	binary.BigEndian.PutUint32(b[:], crc32.Checksum(dst[start:], castagnoli))
+	return append(dst, b[:4]...)
+}
+
+// isEnd returns true if src starts with an end record rather than a block.
+func isEnd(src []byte) bool {
+	return len(src) >= len(endMagic) && string(src[:len(endMagic)]) == endMagic
+}
+
+// readEnd verifies that src holds only an end record and that the record holds
+// count values.
+func readEnd(src []byte, count uint64) error {
+	if len(src) < endLen {
+		return ErrTruncated
+	}
+	if len(src) > endLen {
+		return ErrCorrupt
+	}
+	if crc32.Checksum(src[:endLen-trailerLen], castagnoli) != binary.BigEndian.Uint32(src[endLen-trailerLen:]) {
+		return ErrCorrupt
+	}
+	if v := src[len(endMagic)]; v != Version {
+		return fmt.Errorf("%w: unsupported version %d", ErrCorrupt, v)
+	}
+	if binary.BigEndian.Uint64(src[len(endMagic)+1:]) != count {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// block is the header of a block and its encoded values.
+type block struct {
+	codec  encoding.Codec
+	count  int
+	values []byte
+}
+
+// readBlock returns the block at the start of src, after verifying its checksum, and
+// the bytes following it.
+func readBlock(src []byte) (block, []byte, error) {
+	if len(src) < headerLen {
+		return block{}, nil, truncated(src)
+	}
+	if string(src[:len(magic)]) != magic {
+		return block{}, nil, ErrCorrupt
+	}
+
+	l := binary.BigEndian.Uint32(src[headerLen-4:])
+	if int64(l) > int64(len(src)-headerLen-trailerLen) {
+		return block{}, nil, truncated(src)
+	}
+	end := headerLen + int(l)
+
+	if crc32.Checksum(src[:end], castagnoli) != binary.BigEndian.Uint32(src[end:]) {
+		return block{}, nil, ErrCorrupt
+	}
+
+	if v := src[len(magic)]; v != Version {
+		return block{}, nil, fmt.Errorf("%w: unsupported version %d", ErrCorrupt, v)
+	}
+	c, ok := encoding.Lookup(src[len(magic)+1])
+	if !ok {
+		return block{}, nil, fmt.Errorf("%w: unknown codec ID: %d", ErrCorrupt, src[len(magic)+1])
+	}
+	count := binary.BigEndian.Uint32(src[len(magic)+2:])
+	if count > MaxBlockSize {
+		return block{}, nil, ErrCorrupt
+	}
+
+	return block{codec: c, count: int(count), values: src[headerLen:end]}, src[end+trailerLen:], nil
+}
+
+// truncated returns the error for src, which ends before the block it starts.  It
+// is ErrTruncated if the header fields present are valid and the length fits the
+// values of the codec, and ErrCorrupt otherwise.
+func truncated(src []byte) error {
+	n := len(src)
+	if n > len(magic) {
+		n = len(magic)
+	}
+	if string(src[:n]) != magic[:n] {
+		return ErrCorrupt
+	}
+	if len(src) <= len(magic) {
+		return ErrTruncated
+	}
+
+	if v := src[len(magic)]; v != Version {
+		return fmt.Errorf("%w: unsupported version %d", ErrCorrupt, v)
+	}
+	if len(src) <= len(magic)+1 {
+		return ErrTruncated
+	}
+
+	c, ok := encoding.Lookup(src[len(magic)+1])
+	if !ok {
+		return fmt.Errorf("%w: unknown codec ID: %d", ErrCorrupt, src[len(magic)+1])
+	}
+	if len(src) < headerLen-4 {
+		return ErrTruncated
+	}
+
+	count := binary.BigEndian.Uint32(src[len(magic)+2:])
+	if count > MaxBlockSize {
+		return ErrCorrupt
+	}
+	if len(src) < headerLen {
+		return ErrTruncated
+	}
+
+	if l := binary.BigEndian.Uint32(src[headerLen-4:]); int64(l) > int64(c.MaxEncodedLen(int(count))) {
+		return ErrCorrupt
+	}
+	return ErrTruncated
+}
+
+// decode writes the values of the block to dst, which must hold at least b.count
+// values.
+func (b block) decode(dst []uint64) error {
+	n, err := b.codec.Decode(dst[:b.count], b.values)
+	if err != nil || n != b.count {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// Encoder converts a stream of unsigned 64bit integers to a sequence of checksummed
+// blocks encoded with a codec.
+type Encoder struct {
+	c encoding.Codec
+
+	// values of the open block
+	buf []uint64
+
+	// blocks completed so far and the number of values in them
+	bytes []byte
+	count uint64
+
+	// completed blocks and the open block, returned by Bytes
+	out []byte
+}
+
+// NewEncoder returns an Encoder writing blocks of values encoded with c.
+func NewEncoder(c encoding.Codec) *Encoder {
+	return &Encoder{
+		c:   c,
+		buf: make([]uint64, 0, BlockSize),
+	}
+}
+
+// Reset clears the encoder so it can be reused.
+func (e *Encoder) Reset() {
+	e.buf = e.buf[:0]
+	e.bytes = e.bytes[:0]
+	e.count = 0
+}
+
+// Write adds v to the encoder.  An error is returned if the block completed by v
+// cannot be encoded, and the values of that block are discarded.
+func (e *Encoder) Write(v uint64) error {
+	e.buf = append(e.buf, v)
+	if len(e.buf) < BlockSize {
+		return nil
+	}
+
+	b, err := AppendBlock(e.bytes, e.c, e.buf)
+	e.buf = e.buf[:0]
+	if err != nil {
+		return err
+	}
+	e.bytes = b
+	e.count += BlockSize
+	return nil
+}
+
+// Bytes returns the blocks of the values written to the encoder and the end record.
+// The open block is encoded without closing it, so values may continue to be
+// written after calling Bytes.
+func (e *Encoder) Bytes() ([]byte, error) {
+	e.out = append(e.out[:0], e.bytes...)
+	if len(e.buf) > 0 {
+		var err error
+		if e.out, err = AppendBlock(e.out, e.c, e.buf); err != nil {
+			return nil, err
+		}
+	}
+	e.out = AppendEnd(e.out, e.count+uint64(len(e.buf)))
+	return e.out, nil
+}
+
+// Decoder converts a sequence of checksummed blocks to a stream of unsigned 64bit
+// integers.  Each block is verified before any of its values are returned.
+type Decoder struct {
+	bytes []byte
+	buf   []uint64
+	i     int
+	n     int
+	err   error
+
+	// values in the blocks read so far, and whether the end record has been read
+	count uint64
+	done  bool
+}
+
+// NewDecoder returns a Decoder from a byte slice
+func NewDecoder(b []byte) *Decoder {
+	d := &Decoder{}
+	d.SetBytes(b)
+	return d
+}
+
+// SetBytes resets the decoder to read from b.
+func (d *Decoder) SetBytes(b []byte) {
+	d.bytes = b
+	d.i = 0
+	d.n = 0
+	d.err = nil
+	d.count = 0
+	d.done = false
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *Decoder) Next() bool {
+	d.i += 1
+
+	for d.i >= d.n {
+		if d.err != nil || d.done {
+			return false
+		}
+		d.read()
+	}
+	return true
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (d *Decoder) Read() uint64 {
+	return d.buf[d.i]
+}
+
+// Err returns the first error encountered while decoding, which is ErrTruncated or
+// ErrCorrupt for a damaged block.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+func (d *Decoder) read() {
+	d.i, d.n = 0, 0
+
+	if len(d.bytes) == 0 {
+		d.err = ErrTruncated
+		return
+	}
+	if isEnd(d.bytes) {
+		d.err = readEnd(d.bytes, d.count)
+		d.done = true
+		return
+	}
+
+	b, rest, err := readBlock(d.bytes)
+	if err != nil {
+		d.err = err
+		return
+	}
+
+	if cap(d.buf) < b.count {
+		d.buf = make([]uint64, b.count)
+	}
+	d.buf = d.buf[:cap(d.buf)]
+	if d.err = b.decode(d.buf); d.err != nil {
+		return
+	}
+	d.bytes = rest
+	d.n = b.count
+	d.count += uint64(b.count)
+}
+
+// EncodeAll returns the values from src encoded with c in blocks of BlockSize,
+// followed by the end record.
+func EncodeAll(c encoding.Codec, src []uint64) ([]byte, error) {
+	var dst []byte
+	count := uint64(len(src))
+	for len(src) > 0 {
+		n := BlockSize
+		if len(src) < n {
+			n = len(src)
+		}
+
+		var err error
+		if dst, err = AppendBlock(dst, c, src[:n]); err != nil {
+			return nil, err
+		}
+		src = src[n:]
+	}
+	return AppendEnd(dst, count), nil
+}
+
+// DecodeAll writes the values of the blocks in src to dst.  It returns the number of
+// values written or an error, which is ErrTruncated or ErrCorrupt for a damaged
+// block or end record.
+func DecodeAll(dst []uint64, src []byte) (int, error) {
+	j := 0
+	for !isEnd(src) {
+		if len(src) == 0 {
+			return 0, ErrTruncated
+		}
+
+		b, rest, err := readBlock(src)
+		if err != nil {
+			return 0, err
+		}
+		if b.count > len(dst)-j {
+			return 0, fmt.Errorf("dst too small: need %d values, have %d", j+b.count, len(dst))
+		}
+
+		if err := b.decode(dst[j:]); err != nil {
+			return 0, err
+		}
+		j += b.count
+		src = rest
+	}
+	if err := readEnd(src, uint64(j)); err != nil {
+		return 0, err
+	}
+	return j, nil
+}
+
+// CountBytes returns the number of values in the blocks of the byte slice.  The
+// checksum of every block and of the end record is verified.
+func CountBytes(src []byte) (int, error) {
+	var count int
+	for !isEnd(src) {
+		if len(src) == 0 {
+			return 0, ErrTruncated
+		}
+
+		b, rest, err := readBlock(src)
+		if err != nil {
+			return 0, err
+		}
+		count += b.count
+		src = rest
+	}
+	if err := readEnd(src, uint64(count)); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,334 @@
+package container_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	"github.com/jwilder/encoding"
+	"github.com/jwilder/encoding/container"
+	"github.com/jwilder/encoding/simple8b"
+	"github.com/jwilder/encoding/simple9"
+)
+
+func testRoundTrip(t *testing.T, c encoding.Codec, in []uint64) []byte {
+	b, err := container.EncodeAll(c, in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n, err := container.CountBytes(b); err != nil || n != len(in) {
+		t.Fatalf("Count mismatch: got %v %v, exp %v", n, err, len(in))
+	}
+
+	decoded := make([]uint64, len(in))
+	n, err := container.DecodeAll(decoded, b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("Len mismatch: got %v, exp %v", n, len(in))
+	}
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+
+	dec := container.NewDecoder(b)
+	i := 0
+	for dec.Next() {
+		if dec.Read() != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], dec.Read())
+		}
+		i++
+	}
+	if dec.Err() != nil {
+		t.Fatalf("Unexpected error: %v", dec.Err())
+	}
+	if i != len(in) {
+		t.Fatalf("Len mismatch: got %v, exp %v", i, len(in))
+	}
+	return b
+}
+
+func testValues(n int) []uint64 {
+	in := make([]uint64, n)
+	for i := range in {
+		in[i] = uint64(i*7) % 1000
+	}
+	return in
+}
+
+func Test_Container_RoundTrip(t *testing.T) {
+	for _, c := range []encoding.Codec{simple8b.Codec{}, simple9.Codec{}} {
+		for _, n := range []int{0, 1, container.BlockSize, 2500} {
+			testRoundTrip(t, c, testValues(n))
+		}
+	}
+}
+
+func Test_Encoder(t *testing.T) {
+	in := testValues(2500)
+	exp, _ := container.EncodeAll(simple8b.Codec{}, in)
+
+	enc := container.NewEncoder(simple8b.Codec{})
+	for _, v := range in {
+		if err := enc.Write(v); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	b, err := enc.Bytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(b) != string(exp) {
+		t.Fatalf("Encoder output mismatch: got %v bytes, exp %v", len(b), len(exp))
+	}
+
+	enc.Reset()
+	enc.Write(5)
+	b, _ = enc.Bytes()
+	if n, err := container.CountBytes(b); err != nil || n != 1 {
+		t.Fatalf("Count mismatch after reset: got %v %v, exp 1", n, err)
+	}
+}
+
+func Test_Decode_Truncated(t *testing.T) {
+	in := testValues(2500)
+	b, _ := container.EncodeAll(simple8b.Codec{}, in)
+
+	for n := 0; n < len(b); n++ {
+		if _, err := container.DecodeAll(make([]uint64, 2500), b[:n]); err != container.ErrTruncated {
+			t.Fatalf("Expected ErrTruncated for input truncated to %d bytes, got %v", n, err)
+		}
+
+		if _, err := container.CountBytes(b[:n]); err != container.ErrTruncated {
+			t.Fatalf("Expected count ErrTruncated for input truncated to %d bytes, got %v", n, err)
+		}
+
+		dec := container.NewDecoder(b[:n])
+		for dec.Next() {
+		}
+		if dec.Err() != container.ErrTruncated {
+			t.Fatalf("Expected decoder ErrTruncated for input truncated to %d bytes, got %v", n, dec.Err())
+		}
+	}
+}
+
+func Test_Decode_TruncatedAtBlock(t *testing.T) {
+	in := testValues(3 * container.BlockSize)
+	b, err := container.AppendBlock(nil, simple8b.Codec{}, in[:container.BlockSize])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The first block is whole and valid, but the blocks following it are missing.
+	if _, err := container.DecodeAll(make([]uint64, len(in)), b); err != container.ErrTruncated {
+		t.Fatalf("Expected ErrTruncated, got %v", err)
+	}
+	if _, err := container.CountBytes(b); err != container.ErrTruncated {
+		t.Fatalf("Expected count ErrTruncated, got %v", err)
+	}
+
+	dec := container.NewDecoder(b)
+	i := 0
+	for dec.Next() {
+		if dec.Read() != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], dec.Read())
+		}
+		i++
+	}
+	if dec.Err() != container.ErrTruncated {
+		t.Fatalf("Expected decoder ErrTruncated, got %v", dec.Err())
+	}
+	if i != container.BlockSize {
+		t.Fatalf("Len mismatch: got %v, exp %v", i, container.BlockSize)
+	}
+
+	full, _ := container.EncodeAll(simple8b.Codec{}, in)
+	if string(full[:len(b)]) != string(b) {
+		t.Fatalf("First block mismatch")
+	}
+}
+
+func Test_Decode_EndMismatch(t *testing.T) {
+	in := testValues(10)
+	b, _ := container.AppendBlock(nil, simple8b.Codec{}, in)
+
+	for _, count := range []uint64{0, 9, 11, container.BlockSize} {
+		damaged := container.AppendEnd(append([]byte(nil), b...), count)
+		if _, err := container.DecodeAll(make([]uint64, 10), damaged); err != container.ErrCorrupt {
+			t.Fatalf("Expected ErrCorrupt for end count %d, got %v", count, err)
+		}
+		if _, err := container.CountBytes(damaged); err != container.ErrCorrupt {
+			t.Fatalf("Expected count ErrCorrupt for end count %d, got %v", count, err)
+		}
+	}
+
+	// Bytes following the end record are not part of the stream.
+	damaged := container.AppendEnd(append([]byte(nil), b...), 10)
+	damaged = append(damaged, 0)
+	if _, err := container.DecodeAll(make([]uint64, 10), damaged); err != container.ErrCorrupt {
+		t.Fatalf("Expected ErrCorrupt for trailing bytes, got %v", err)
+	}
+	dec := container.NewDecoder(damaged)
+	for dec.Next() {
+	}
+	if dec.Err() != container.ErrCorrupt {
+		t.Fatalf("Expected decoder ErrCorrupt for trailing bytes, got %v", dec.Err())
+	}
+}
+
+func Test_Decode_Corrupt(t *testing.T) {
+	b, _ := container.EncodeAll(simple8b.Codec{}, testValues(100))
+	block, _ := container.AppendBlock(nil, simple8b.Codec{}, testValues(100))
+
+	for i := range b {
+		damaged := append([]byte(nil), b...)
+		damaged[i] ^= 0x10
+		if _, err := container.DecodeAll(make([]uint64, 100), damaged); err != container.ErrCorrupt {
+			t.Fatalf("Expected ErrCorrupt for damaged byte %d, got %v", i, err)
+		}
+
+		// Values of the block are returned before the end record is read.
+		dec := container.NewDecoder(damaged)
+		if i < len(block) && dec.Next() {
+			t.Fatalf("Expected Next to return false for damaged byte %d", i)
+		}
+		for dec.Next() {
+		}
+		if dec.Err() != container.ErrCorrupt {
+			t.Fatalf("Expected decoder ErrCorrupt for damaged byte %d, got %v", i, dec.Err())
+		}
+	}
+}
+
+func Test_Decode_CorruptLength(t *testing.T) {
+	in := testValues(100)
+	b, _ := container.EncodeAll(simple8b.Codec{}, in)
+	max := (simple8b.Codec{}).MaxEncodedLen(len(in))
+
+	// A length past the end of the input is a short write only if the values of
+	// the block could be that long.
+	for _, l := range []int{len(b), max, max + 1, 1 << 31} {
+		damaged := append([]byte(nil), b...)
+		binary.BigEndian.PutUint32(damaged[10:], uint32(l))
+
+		exp := container.ErrTruncated
+		if l > max {
+			exp = container.ErrCorrupt
+		}
+		if _, err := container.DecodeAll(make([]uint64, len(in)), damaged); err != exp {
+			t.Fatalf("Expected %v for length %d, got %v", exp, l, err)
+		}
+		if _, err := container.CountBytes(damaged); err != exp {
+			t.Fatalf("Expected count %v for length %d, got %v", exp, l, err)
+		}
+	}
+
+	// Input ending in the header is only truncated if the bytes present are valid.
+	for _, partial := range []string{"EN", "ENCB", "ENCB\x01", "ENCB\x01\x01\x00\x00"} {
+		if _, err := container.CountBytes([]byte(partial)); err != container.ErrTruncated {
+			t.Fatalf("Expected ErrTruncated for %q, got %v", partial, err)
+		}
+	}
+	for _, partial := range []string{"EX", "XNCB\x01", "ENCB\x01\x01\x01\x00\x00\x01"} {
+		if _, err := container.CountBytes([]byte(partial)); err != container.ErrCorrupt {
+			t.Fatalf("Expected ErrCorrupt for %q, got %v", partial, err)
+		}
+	}
+}
+
+func Test_Decode_UnsupportedHeader(t *testing.T) {
+	in := testValues(100)
+	b, _ := container.EncodeAll(simple8b.Codec{}, in)
+	end := 14 + int(binary.BigEndian.Uint32(b[10:]))
+	castagnoli := crc32.MakeTable(crc32.Castagnoli)
+
+	// The checksum is fixed up so that the header itself is rejected.
+	for _, field := range []struct{ off, v int }{{4, 2}, {5, 0xfe}} {
+		damaged := append([]byte(nil), b...)
+		damaged[field.off] = byte(field.v)
+		binary.BigEndian.PutUint32(damaged[end:], crc32.Checksum(damaged[:end], castagnoli))
+
+		if _, err := container.DecodeAll(make([]uint64, len(in)), damaged); !errors.Is(err, container.ErrCorrupt) {
+			t.Fatalf("Expected ErrCorrupt for byte %d = %d, got %v", field.off, field.v, err)
+		}
+		if _, err := container.CountBytes(damaged[:end]); !errors.Is(err, container.ErrCorrupt) {
+			t.Fatalf("Expected count ErrCorrupt for truncated byte %d = %d, got %v", field.off, field.v, err)
+		}
+	}
+
+	// The end record is the last 17 bytes.
+	end = len(b) - 17
+	damaged := append([]byte(nil), b...)
+	damaged[end+4] = 2
+	binary.BigEndian.PutUint32(damaged[len(b)-4:], crc32.Checksum(damaged[end:len(b)-4], castagnoli))
+	if _, err := container.DecodeAll(make([]uint64, len(in)), damaged); !errors.Is(err, container.ErrCorrupt) {
+		t.Fatalf("Expected ErrCorrupt for end record version, got %v", err)
+	}
+}
+
+func Test_AppendBlock_TooLarge(t *testing.T) {
+	if _, err := container.AppendBlock(nil, simple8b.Codec{}, make([]uint64, container.MaxBlockSize+1)); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_DecodeAll_DstTooSmall(t *testing.T) {
+	b, _ := container.EncodeAll(simple8b.Codec{}, testValues(10))
+	if _, err := container.DecodeAll(make([]uint64, 9), b); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	f.Add([]byte(nil))
+	for _, c := range []encoding.Codec{simple8b.Codec{}, simple9.Codec{}} {
+		b, _ := container.EncodeAll(c, testValues(2*container.BlockSize+10))
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := container.CountBytes(b)
+		if err != nil || n > 1<<20 {
+			return
+		}
+
+		dst := make([]uint64, n)
+		got, err := container.DecodeAll(dst, b)
+		if err != nil {
+			// Counts are read from the block headers, so the values may still be
+			// invalid.
+			dec := container.NewDecoder(b)
+			for dec.Next() {
+			}
+			if dec.Err() == nil {
+				t.Fatalf("Expected Decoder error, got nil")
+			}
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAll mismatch: got %v, exp %v", got, n)
+		}
+		if n > 0 {
+			if _, err := container.DecodeAll(make([]uint64, n-1), b); err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+		}
+
+		dec := container.NewDecoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
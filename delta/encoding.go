@@ -0,0 +1,192 @@
+// Package delta implements delta encoding of integer sequences packed with simple8b.
+//
+// Three transforms are provided:
+//
+//   - Delta stores the difference between consecutive values and suits sorted
+//     []uint64 sequences such as document IDs.
+//   - ZigZag stores zigzag encoded differences and suits []int64 sequences that
+//     move in both directions.
+//   - DeltaOfDelta stores zigzag encoded differences between consecutive deltas and
+//     suits []int64 sequences with a near constant step such as timestamps.
+//
+// The first value of a sequence is stored uncompressed in the first word so that
+// large starting values such as Unix nanosecond times do not need to fit within
+// simple8b.MaxValue.  Decoding unpacks one simple8b word at a time and rebuilds
+// absolute values directly in the destination slice without an intermediate buffer
+// of deltas.
+package delta
+
+import (
+	"fmt"
+
+	"github.com/jwilder/encoding/bitops"
+	"github.com/jwilder/encoding/simple8b"
+)
+
+// EncodeAll returns the deltas between consecutive values in src packed using
+// simple8b.  An error is returned if a delta exceeds simple8b.MaxValue, which
+// usually means src is not sorted.
+func EncodeAll(src []uint64) ([]uint64, error) {
+	if len(src) == 0 {
+		return nil, nil
+	}
+
+	deltas := make([]uint64, len(src)-1)
+	for i := 1; i < len(src); i++ {
+		deltas[i-1] = src[i] - src[i-1]
+	}
+	return pack(src[0], deltas)
+}
+
+// DecodeAll writes the values encoded by EncodeAll in src to dst.  It returns the
+// number of values written or an error.
+func DecodeAll(dst, src []uint64) (int, error) {
+	if len(src) == 0 {
+		return 0, nil
+	}
+	if len(dst) == 0 {
+		return 0, errShortBuffer(1, len(dst))
+	}
+
+	var buf [240]uint64
+	prev := src[0]
+	dst[0] = prev
+	j := 1
+	for _, w := range src[1:] {
+		n, err := simple8b.Decode(&buf, w)
+		if err != nil {
+			return 0, err
+		}
+		if j+n > len(dst) {
+			return 0, errShortBuffer(j+n, len(dst))
+		}
+
+		for _, v := range buf[:n] {
+			prev += v
+			dst[j] = prev
+			j++
+		}
+	}
+	return j, nil
+}
+
+// EncodeAllZigZag returns the zigzag encoded deltas between consecutive values in
+// src packed using simple8b.
+func EncodeAllZigZag(src []int64) ([]uint64, error) {
+	if len(src) == 0 {
+		return nil, nil
+	}
+
+	deltas := make([]uint64, len(src)-1)
+	for i := 1; i < len(src); i++ {
+		deltas[i-1] = bitops.ZigZagEncode64(src[i] - src[i-1])
+	}
+	return pack(uint64(src[0]), deltas)
+}
+
+// DecodeAllZigZag writes the values encoded by EncodeAllZigZag in src to dst.  It
+// returns the number of values written or an error.
+func DecodeAllZigZag(dst []int64, src []uint64) (int, error) {
+	if len(src) == 0 {
+		return 0, nil
+	}
+	if len(dst) == 0 {
+		return 0, errShortBuffer(1, len(dst))
+	}
+
+	var buf [240]uint64
+	prev := int64(src[0])
+	dst[0] = prev
+	j := 1
+	for _, w := range src[1:] {
+		n, err := simple8b.Decode(&buf, w)
+		if err != nil {
+			return 0, err
+		}
+		if j+n > len(dst) {
+			return 0, errShortBuffer(j+n, len(dst))
+		}
+
+		for _, v := range buf[:n] {
+			prev += bitops.ZigZagDecode64(v)
+			dst[j] = prev
+			j++
+		}
+	}
+	return j, nil
+}
+
+// EncodeAllDeltaOfDelta returns the zigzag encoded differences between consecutive
+// deltas of src packed using simple8b.  The first delta is stored relative to zero.
+func EncodeAllDeltaOfDelta(src []int64) ([]uint64, error) {
+	if len(src) == 0 {
+		return nil, nil
+	}
+
+	deltas := make([]uint64, len(src)-1)
+	var prevDelta int64
+	for i := 1; i < len(src); i++ {
+		delta := src[i] - src[i-1]
+		deltas[i-1] = bitops.ZigZagEncode64(delta - prevDelta)
+		prevDelta = delta
+	}
+	return pack(uint64(src[0]), deltas)
+}
+
+// DecodeAllDeltaOfDelta writes the values encoded by EncodeAllDeltaOfDelta in src to
+// dst.  It returns the number of values written or an error.
+func DecodeAllDeltaOfDelta(dst []int64, src []uint64) (int, error) {
+	if len(src) == 0 {
+		return 0, nil
+	}
+	if len(dst) == 0 {
+		return 0, errShortBuffer(1, len(dst))
+	}
+
+	var buf [240]uint64
+	var delta int64
+	prev := int64(src[0])
+	dst[0] = prev
+	j := 1
+	for _, w := range src[1:] {
+		n, err := simple8b.Decode(&buf, w)
+		if err != nil {
+			return 0, err
+		}
+		if j+n > len(dst) {
+			return 0, errShortBuffer(j+n, len(dst))
+		}
+
+		for _, v := range buf[:n] {
+			delta += bitops.ZigZagDecode64(v)
+			prev += delta
+			dst[j] = prev
+			j++
+		}
+	}
+	return j, nil
+}
+
+// pack returns first followed by deltas packed using simple8b.  deltas is modified
+// in place.
+func pack(first uint64, deltas []uint64) ([]uint64, error) {
+	for _, v := range deltas {
+		if v > simple8b.MaxValue {
+			return nil, fmt.Errorf("delta out of bounds: %v", v)
+		}
+	}
+
+	words, err := simple8b.EncodeAll(deltas)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := make([]uint64, len(words)+1)
+	dst[0] = first
+	copy(dst[1:], words)
+	return dst, nil
+}
+
+func errShortBuffer(need, have int) error {
+	return fmt.Errorf("dst too small: need %d values, have %d", need, have)
+}
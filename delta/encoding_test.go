@@ -0,0 +1,177 @@
+package delta_test
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/jwilder/encoding/delta"
+)
+
+func Test_EncodeAll_Sorted(t *testing.T) {
+	in := make([]uint64, 1000)
+	for i := range in {
+		in[i] = uint64(1e12 + i*i)
+	}
+
+	encoded, err := delta.EncodeAll(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded := make([]uint64, len(in))
+	n, err := delta.DecodeAll(decoded, encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", len(in), n)
+	}
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
+func Test_EncodeAll_Unsorted(t *testing.T) {
+	if _, err := delta.EncodeAll([]uint64{10, 5}); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_EncodeAll_NoValues(t *testing.T) {
+	encoded, err := delta.EncodeAll(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n, _ := delta.DecodeAll(nil, encoded); n != 0 {
+		t.Fatalf("Decode len mismatch: exp 0, got %v", n)
+	}
+}
+
+func Test_EncodeAllZigZag(t *testing.T) {
+	rand.Seed(1)
+	in := make([]int64, 1000)
+	v := int64(0)
+	for i := range in {
+		v += int64(rand.Intn(2001) - 1000)
+		in[i] = v
+	}
+
+	encoded, err := delta.EncodeAllZigZag(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded := make([]int64, len(in))
+	n, err := delta.DecodeAllZigZag(decoded, encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	compare(t, in, decoded[:n])
+}
+
+func Test_EncodeAllZigZag_OutOfRange(t *testing.T) {
+	if _, err := delta.EncodeAllZigZag([]int64{0, math.MaxInt64}); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_EncodeAllDeltaOfDelta(t *testing.T) {
+	in := make([]int64, 1000)
+	for i := range in {
+		in[i] = 1444238178437870000 + int64(i)*1e9 + int64(i%3)
+	}
+
+	encoded, err := delta.EncodeAllDeltaOfDelta(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded := make([]int64, len(in))
+	n, err := delta.DecodeAllDeltaOfDelta(decoded, encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	compare(t, in, decoded[:n])
+
+	zz, _ := delta.EncodeAllZigZag(in)
+	if len(encoded) >= len(zz) {
+		t.Fatalf("Expected delta-of-delta to be smaller: got %v, zigzag %v", len(encoded), len(zz))
+	}
+}
+
+func Test_Decode_ShortBuffer(t *testing.T) {
+	in := []int64{1, 2, 3, 4, 5}
+	encoded, _ := delta.EncodeAllDeltaOfDelta(in)
+	if _, err := delta.DecodeAllDeltaOfDelta(make([]int64, 2), encoded); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	for _, in := range [][]uint64{nil, {7}, {1, 2, 3, 10, 1000}} {
+		encoded, _ := delta.EncodeAll(in)
+		b := make([]byte, 8*len(encoded))
+		for i, w := range encoded {
+			binary.BigEndian.PutUint64(b[i*8:], w)
+		}
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		src := make([]uint64, len(b)/8)
+		for i := range src {
+			src[i] = binary.BigEndian.Uint64(b[i*8:])
+		}
+
+		// The first word holds a value and the rest hold at most 240 deltas each.
+		dst := make([]uint64, 240*len(src)+1)
+		n, err := delta.DecodeAll(dst, src)
+		if err != nil {
+			return
+		}
+		if n > 0 {
+			if _, err := delta.DecodeAll(make([]uint64, n-1), src); err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+		}
+
+		signed := make([]int64, len(dst))
+		if got, err := delta.DecodeAllZigZag(signed, src); err != nil || got != n {
+			t.Fatalf("DecodeAllZigZag mismatch: got %v %v, exp %v", got, err, n)
+		}
+		if got, err := delta.DecodeAllDeltaOfDelta(signed, src); err != nil || got != n {
+			t.Fatalf("DecodeAllDeltaOfDelta mismatch: got %v %v, exp %v", got, err, n)
+		}
+	})
+}
+
+func compare(t *testing.T, exp, got []int64) {
+	if len(exp) != len(got) {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", len(exp), len(got))
+	}
+	for i := range exp {
+		if got[i] != exp[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, exp[i], got[i])
+		}
+	}
+}
+
+func BenchmarkDecodeAll(b *testing.B) {
+	x := make([]uint64, 1024)
+	for i := 0; i < len(x); i++ {
+		x[i] = uint64(i * 10)
+	}
+	y, _ := delta.EncodeAll(x)
+
+	decoded := make([]uint64, len(x))
+
+	b.ResetTimer()
+	b.SetBytes(int64(len(x) * 8))
+	for i := 0; i < b.N; i++ {
+		_, _ = delta.DecodeAll(decoded, y)
+	}
+}
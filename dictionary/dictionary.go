@@ -0,0 +1,168 @@
+// Package dictionary implements dictionary encoding of low cardinality columns of
+// strings or integers.
+//
+// Each distinct value is assigned a code, its index in the sorted list of distinct
+// values, and the codes of the values are packed using simple8b.  Codes preserve the
+// order of the values, so a range of values can be compared as a range of codes.
+// Decoders expand the dictionary once and return the entry for each code, so a
+// column of strings is read without allocating a string per value.
+package dictionary
+
+// The encoded form starts with a header holding the type of the values, the number
+// of values and the number of dictionary entries.  The entries follow in ascending
+// order and the remaining bytes are the simple8b words of the codes as 8 byte big
+// endian integers:
+//
+// ┌──────────┬──────────┬──────────┬──────────────────────┬──────────────────────┐
+// │   Type   │  Count   │ Entries  │      Dictionary      │        Codes         │
+// ├──────────┼──────────┼──────────┼──────────────────────┼──────────────────────┤
+// │  1 byte  │ uvarint  │ uvarint  │  Entries values      │  simple8b words      │
+// └──────────┴──────────┴──────────┴──────────────────────┴──────────────────────┘
+//
+// Strings are front coded, with each entry holding the length of the prefix shared
+// with the previous entry and the remaining suffix:
+//
+// ┌──────────────┬──────────────┬──────────────────────────────────┐
+// │ Prefix Len   │ Suffix Len   │              Suffix              │
+// ├──────────────┼──────────────┼──────────────────────────────────┤
+// │   uvarint    │   uvarint    │        Suffix Len bytes          │
+// └──────────────┴──────────────┴──────────────────────────────────┘
+//
+// Integers are stored as the uvarint difference from the previous entry, with the
+// first entry stored relative to zero.
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+// Types of the values in the header.
+const (
+	typeUint64 = 1
+	typeString = 2
+)
+
+// appendHeader appends the header for count values of typ with entries distinct
+// values to dst.
+func appendHeader(dst []byte, typ byte, count, entries int) []byte {
+	var b [binary.MaxVarintLen64]byte
+	dst = append(dst, typ)
+	dst = append(dst, b[:binary.PutUvarint(b[:], uint64(count))]...)
+	return append(dst, b[:binary.PutUvarint(b[:], uint64(entries))]...)
+}
+
+// appendCodes appends the simple8b words of codes to dst.  The codes are packed in
+// place.
+func appendCodes(dst []byte, codes []uint64) ([]byte, error) {
+	words, err := simple8b.EncodeAll(codes)
+	if err != nil {
+		return nil, err
+	}
+
+	var b [8]byte
+	for _, w := range words {
+		binary.BigEndian.PutUint64(b[:], w)
+		dst = append(dst, b[:]...)
+	}
+	return dst, nil
+}
+
+// readHeader returns the value count and number of dictionary entries from b and
+// the remaining bytes.  An error is returned if the values are not of type typ.
+func readHeader(b []byte, typ byte) (count, entries int, rest []byte, err error) {
+	if len(b) == 0 {
+		return 0, 0, nil, nil
+	}
+	if b[0] != typ {
+		return 0, 0, nil, fmt.Errorf("invalid value type: %d, expected %d", b[0], typ)
+	}
+
+	c, n := binary.Uvarint(b[1:])
+	if n <= 0 || c > math.MaxInt32 {
+		return 0, 0, nil, fmt.Errorf("invalid value count")
+	}
+	b = b[1+n:]
+
+	e, n := binary.Uvarint(b)
+	if n <= 0 || e > c || e > uint64(len(b)-n) {
+		return 0, 0, nil, fmt.Errorf("invalid dictionary len")
+	}
+	return int(c), int(e), b[n:], nil
+}
+
+// CountBytes returns the number of values encoded in the byte slice
+func CountBytes(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	typ := b[0]
+	if typ != typeString {
+		typ = typeUint64
+	}
+	count, _, _, err := readHeader(b, typ)
+	return count, err
+}
+
+// codeReader iterates over the codes packed in simple8b words.
+type codeReader struct {
+	words []byte
+
+	// number of dictionary entries, which every code must be less than
+	entries uint64
+
+	// number of codes remaining
+	remaining int
+
+	buf [240]uint64
+	i   int
+	n   int
+	err error
+}
+
+func (r *codeReader) reset(words []byte, count, entries int) {
+	r.words = words
+	r.entries = uint64(entries)
+	r.remaining = count
+	r.i, r.n = 0, 0
+	r.err = nil
+	if len(words)%8 != 0 {
+		r.err = fmt.Errorf("invalid slice len remaining: %v", len(words)%8)
+	}
+}
+
+// next advances to the next code.  It returns false when there are no codes
+// remaining or an error is encountered.
+func (r *codeReader) next() bool {
+	if r.err != nil || r.remaining == 0 {
+		return false
+	}
+
+	r.i += 1
+	if r.i >= r.n {
+		if len(r.words) == 0 {
+			r.err = fmt.Errorf("unexpected end of codes: %d values remaining", r.remaining)
+			return false
+		}
+		n, err := simple8b.Decode(&r.buf, binary.BigEndian.Uint64(r.words))
+		if err != nil {
+			r.err = err
+			return false
+		}
+		r.words = r.words[8:]
+		r.i, r.n = 0, n
+	}
+
+	if r.buf[r.i] >= r.entries {
+		r.err = fmt.Errorf("invalid code: %d, dictionary has %d entries", r.buf[r.i], r.entries)
+		return false
+	}
+	r.remaining -= 1
+	return true
+}
+
+// code returns the current code.
+func (r *codeReader) code() uint64 {
+	return r.buf[r.i]
+}
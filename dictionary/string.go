@@ -0,0 +1,242 @@
+package dictionary
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// MaxDictionaryBytes is the largest total length of the distinct strings of a
+// column.  Front coding stores an entry sharing a long prefix in a few bytes, so
+// without a bound a small input could expand to a dictionary quadratic in its size.
+// Encoders reject larger dictionaries and decoders reject input that expands past
+// it.
+const MaxDictionaryBytes = 16 << 20
+
+// StringEncoder converts a stream of strings to a dictionary encoded byte slice.
+type StringEncoder struct {
+	// distinct values in the order first written, and the index of each
+	values []string
+	ids    map[string]uint64
+
+	// index in values of each value written
+	written []uint64
+
+	// encoded values returned by Bytes
+	out []byte
+}
+
+// NewStringEncoder returns a StringEncoder able to convert strings to dictionary
+// encoded byte slices
+func NewStringEncoder() *StringEncoder {
+	return &StringEncoder{
+		ids: make(map[string]uint64),
+	}
+}
+
+// Reset clears the encoder so it can be reused.
+func (e *StringEncoder) Reset() {
+	e.values = e.values[:0]
+	e.ids = make(map[string]uint64)
+	e.written = e.written[:0]
+}
+
+// Write adds v to the encoder.
+func (e *StringEncoder) Write(v string) error {
+	id, ok := e.ids[v]
+	if !ok {
+		id = uint64(len(e.values))
+		e.ids[v] = id
+		e.values = append(e.values, v)
+	}
+	e.written = append(e.written, id)
+	return nil
+}
+
+// Bytes returns the dictionary and codes of the values written to the encoder.
+// Values may continue to be written after calling Bytes.
+func (e *StringEncoder) Bytes() ([]byte, error) {
+	var size int
+	for _, v := range e.values {
+		size += len(v)
+	}
+	if size > MaxDictionaryBytes {
+		return nil, fmt.Errorf("dictionary too large: %d bytes, max %d", size, MaxDictionaryBytes)
+	}
+
+	order := make([]int, len(e.values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return e.values[order[i]] < e.values[order[j]] })
+
+	e.out = appendHeader(e.out[:0], typeString, len(e.written), len(e.values))
+
+	var b [binary.MaxVarintLen64]byte
+	codes := make([]uint64, len(e.values))
+	var prev string
+	for code, id := range order {
+		v := e.values[id]
+		codes[id] = uint64(code)
+
+		p := commonPrefix(prev, v)
+		e.out = append(e.out, b[:binary.PutUvarint(b[:], uint64(p))]...)
+		e.out = append(e.out, b[:binary.PutUvarint(b[:], uint64(len(v)-p))]...)
+		e.out = append(e.out, v[p:]...)
+		prev = v
+	}
+
+	src := make([]uint64, len(e.written))
+	for i, id := range e.written {
+		src[i] = codes[id]
+	}
+	return appendCodes(e.out, src)
+}
+
+// StringDecoder converts a dictionary encoded byte slice to a stream of strings.
+type StringDecoder struct {
+	dict  []string
+	codes codeReader
+	err   error
+}
+
+// NewStringDecoder returns a StringDecoder from a byte slice
+func NewStringDecoder(b []byte) *StringDecoder {
+	d := &StringDecoder{}
+	d.SetBytes(b)
+	return d
+}
+
+// SetBytes resets the decoder to read from b.  The dictionary is expanded once here.
+func (d *StringDecoder) SetBytes(b []byte) {
+	d.err = nil
+
+	count, entries, b, err := readHeader(b, typeString)
+	if err == nil {
+		d.dict, b, err = readStrings(d.dict[:0], b, entries)
+	}
+	if err != nil {
+		d.dict = d.dict[:0]
+		d.codes.reset(nil, 0, 0)
+		d.err = err
+		return
+	}
+	d.codes.reset(b, count, entries)
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *StringDecoder) Next() bool {
+	if d.err != nil {
+		return false
+	}
+	if !d.codes.next() {
+		d.err = d.codes.err
+		return false
+	}
+	return true
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value, which is shared with every other occurrence of it.
+func (d *StringDecoder) Read() string {
+	return d.dict[d.codes.code()]
+}
+
+// Code returns the code of the current value, which is its index in Dict.
+func (d *StringDecoder) Code() uint64 {
+	return d.codes.code()
+}
+
+// Dict returns the distinct values in ascending order.  The slice must not be
+// modified.
+func (d *StringDecoder) Dict() []string {
+	return d.dict
+}
+
+// Err returns the first error encountered while decoding.
+func (d *StringDecoder) Err() error {
+	return d.err
+}
+
+// EncodeAllStrings returns the dictionary encoded form of the values in src.
+func EncodeAllStrings(src []string) ([]byte, error) {
+	enc := NewStringEncoder()
+	for _, v := range src {
+		if err := enc.Write(v); err != nil {
+			return nil, err
+		}
+	}
+	return enc.Bytes()
+}
+
+// DecodeAllStrings writes the values from src to dst.  It returns the number of
+// values written or an error.
+func DecodeAllStrings(dst []string, src []byte) (int, error) {
+	count, _, _, err := readHeader(src, typeString)
+	if err != nil {
+		return 0, err
+	}
+	if len(dst) < count {
+		return 0, fmt.Errorf("dst too small: need %d values, have %d", count, len(dst))
+	}
+
+	d := NewStringDecoder(src)
+	i := 0
+	for d.Next() {
+		dst[i] = d.Read()
+		i++
+	}
+	if d.Err() != nil {
+		return 0, d.Err()
+	}
+	return i, nil
+}
+
+// readStrings appends the n front coded entries at the start of b to dict.  It
+// returns the extended dictionary and the bytes following the entries, or an error
+// if the entries expand to more than MaxDictionaryBytes.
+func readStrings(dict []string, b []byte, n int) ([]string, []byte, error) {
+	var prev string
+	var size uint64
+	for i := 0; i < n; i++ {
+		p, l := binary.Uvarint(b)
+		if l <= 0 || p > uint64(len(prev)) {
+			return nil, nil, fmt.Errorf("invalid prefix len for entry %d", i)
+		}
+		b = b[l:]
+
+		s, l := binary.Uvarint(b)
+		if l <= 0 || s > uint64(len(b)-l) {
+			return nil, nil, fmt.Errorf("invalid suffix len for entry %d", i)
+		}
+		b = b[l:]
+
+		if size += p + s; size > MaxDictionaryBytes {
+			return nil, nil, fmt.Errorf("dictionary too large: exceeds %d bytes at entry %d", MaxDictionaryBytes, i)
+		}
+
+		v := prev[:p] + string(b[:s])
+		if i > 0 && v <= prev {
+			return nil, nil, fmt.Errorf("dictionary not sorted: %q follows %q", v, prev)
+		}
+		dict = append(dict, v)
+		b = b[s:]
+		prev = v
+	}
+	return dict, b, nil
+}
+
+// commonPrefix returns the length of the longest common prefix of a and b.
+func commonPrefix(a, b string) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
@@ -0,0 +1,242 @@
+package dictionary_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jwilder/encoding/dictionary"
+)
+
+func testStringRoundTrip(t *testing.T, values []string) []byte {
+	b, err := dictionary.EncodeAllStrings(values)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n, err := dictionary.CountBytes(b); err != nil || n != len(values) {
+		t.Fatalf("Count mismatch: got %v %v, exp %v", n, err, len(values))
+	}
+
+	got := make([]string, len(values))
+	n, err := dictionary.DecodeAllStrings(got, b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(values) {
+		t.Fatalf("Len mismatch: got %v, exp %v", n, len(values))
+	}
+	for i := range values {
+		if got[i] != values[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, values[i], got[i])
+		}
+	}
+	return b
+}
+
+func Test_EncodeStrings_NoValues(t *testing.T) {
+	testStringRoundTrip(t, nil)
+}
+
+func Test_EncodeStrings_Single(t *testing.T) {
+	testStringRoundTrip(t, []string{""})
+	testStringRoundTrip(t, []string{"us-west"})
+}
+
+func Test_EncodeStrings_LowCardinality(t *testing.T) {
+	hosts := make([]string, 100)
+	for i := range hosts {
+		hosts[i] = fmt.Sprintf("us-west-2.example.com/server-%03d", i)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	values := make([]string, 10000)
+	for i := range values {
+		values[i] = hosts[rng.Intn(len(hosts))]
+	}
+
+	b := testStringRoundTrip(t, values)
+
+	// Entries after the first store at most 3 characters after the shared prefix, and
+	// codes below 128 use 7 bits, 8 per word.
+	if max := 4 + (2 + 31) + 99*(2+3) + 10000/8*8; len(b) > max {
+		t.Fatalf("Encoded len too large: got %v, exp at most %v", len(b), max)
+	}
+}
+
+func Test_EncodeStrings_Prefixes(t *testing.T) {
+	values := []string{"abc", "ab", "a", "", "abcd", "b", "abd", "ab", ""}
+	testStringRoundTrip(t, values)
+}
+
+func Test_StringDecoder(t *testing.T) {
+	values := []string{"cpu", "mem", "cpu", "disk", "cpu", "mem"}
+	b, err := dictionary.EncodeAllStrings(values)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := dictionary.NewStringDecoder(b)
+	if dict := dec.Dict(); !sort.StringsAreSorted(dict) || len(dict) != 3 {
+		t.Fatalf("Dict mismatch: got %v", dict)
+	}
+
+	i := 0
+	for dec.Next() {
+		if v := dec.Read(); v != values[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, values[i], v)
+		}
+		if v := dec.Dict()[dec.Code()]; v != values[i] {
+			t.Fatalf("Code[%d] mismatch: got %v", i, v)
+		}
+		i++
+	}
+	if dec.Err() != nil {
+		t.Fatalf("Unexpected error: %v", dec.Err())
+	}
+	if i != len(values) {
+		t.Fatalf("Len mismatch: got %v, exp %v", i, len(values))
+	}
+
+	enc := dictionary.NewStringEncoder()
+	enc.Write("b")
+	enc.Reset()
+	enc.Write("a")
+	b, _ = enc.Bytes()
+	dec.SetBytes(b)
+	if !dec.Next() {
+		t.Fatalf("Expected value after reset")
+	}
+	if v := dec.Read(); v != "a" || dec.Next() {
+		t.Fatalf("Read mismatch after reset: got %v", v)
+	}
+}
+
+func Test_DecodeStrings_Truncated(t *testing.T) {
+	b, _ := dictionary.EncodeAllStrings([]string{"cpu", "mem", "cpu", "disk"})
+	for n := 1; n < len(b); n++ {
+		if _, err := dictionary.DecodeAllStrings(make([]string, 4), b[:n]); err == nil {
+			t.Fatalf("Expected error for input truncated to %d bytes, got nil", n)
+		}
+	}
+}
+
+func Test_DecodeStrings_WrongType(t *testing.T) {
+	b, _ := dictionary.EncodeAllUint64([]uint64{1, 2, 3})
+	if _, err := dictionary.DecodeAllStrings(make([]string, 3), b); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_DecodeStrings_DstTooSmall(t *testing.T) {
+	b, _ := dictionary.EncodeAllStrings([]string{"a", "b"})
+	if _, err := dictionary.DecodeAllStrings(make([]string, 1), b); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+// expandingStrings returns encoded strings with a long first entry followed by
+// entries repeating all of the previous one with one more byte, which expands to
+// far more than MaxDictionaryBytes.
+func expandingStrings() []byte {
+	const first, entries = 4096, 4096
+	b := []byte{2}
+	var buf [binary.MaxVarintLen64]byte
+	uvarint := func(v int) {
+		b = append(b, buf[:binary.PutUvarint(buf[:], uint64(v))]...)
+	}
+
+	uvarint(entries)
+	uvarint(entries)
+	uvarint(0)
+	uvarint(first)
+	b = append(b, strings.Repeat("a", first)...)
+	for i := 1; i < entries; i++ {
+		uvarint(first + i - 1)
+		b = append(b, 1, 'a')
+	}
+	return b
+}
+
+func Test_DecodeStrings_TooLarge(t *testing.T) {
+	b := expandingStrings()
+	if len(b) > 1<<15 {
+		t.Fatalf("Encoded len %d larger than expected", len(b))
+	}
+
+	if _, err := dictionary.DecodeAllStrings(make([]string, 4096), b); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("Expected dictionary too large error, got %v", err)
+	}
+	if dec := dictionary.NewStringDecoder(b); dec.Next() || dec.Err() == nil {
+		t.Fatalf("Expected Decoder error, got nil")
+	}
+}
+
+func Test_EncodeStrings_TooLarge(t *testing.T) {
+	v := strings.Repeat("a", dictionary.MaxDictionaryBytes/2)
+	if _, err := dictionary.EncodeAllStrings([]string{v, v + "b", v}); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func FuzzDecodeAllStrings(f *testing.F) {
+	for _, in := range [][]string{nil, {"a"}, {"cpu", "cpu", "mem", "disk", "cpu"}} {
+		b, _ := dictionary.EncodeAllStrings(in)
+		f.Add(b)
+	}
+	f.Add(expandingStrings())
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := dictionary.CountBytes(b)
+		if err != nil || n > 1<<20 {
+			return
+		}
+
+		dst := make([]string, n)
+		got, err := dictionary.DecodeAllStrings(dst, b)
+		if err != nil {
+			dec := dictionary.NewStringDecoder(b)
+			for dec.Next() {
+			}
+			if dec.Err() == nil {
+				t.Fatalf("Expected Decoder error, got nil")
+			}
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAllStrings mismatch: got %v, exp %v", got, n)
+		}
+
+		dec := dictionary.NewStringDecoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] || dec.Code() >= uint64(len(dec.Dict())) {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
+
+func BenchmarkStringDecoder(b *testing.B) {
+	values := make([]string, 1024)
+	for i := range values {
+		values[i] = fmt.Sprintf("host-%d", i%50)
+	}
+	buf, _ := dictionary.EncodeAllStrings(values)
+
+	dec := dictionary.NewStringDecoder(buf)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dec.SetBytes(buf)
+		for dec.Next() {
+			dec.Read()
+		}
+	}
+}
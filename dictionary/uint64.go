@@ -0,0 +1,196 @@
+package dictionary
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Uint64Encoder converts a stream of unsigned 64bit integers to a dictionary encoded
+// byte slice.  Unlike simple8b, values may use all 64 bits.
+type Uint64Encoder struct {
+	// distinct values in the order first written, and the index of each
+	values []uint64
+	ids    map[uint64]uint64
+
+	// index in values of each value written
+	written []uint64
+
+	// encoded values returned by Bytes
+	out []byte
+}
+
+// NewUint64Encoder returns a Uint64Encoder able to convert uint64s to dictionary
+// encoded byte slices
+func NewUint64Encoder() *Uint64Encoder {
+	return &Uint64Encoder{
+		ids: make(map[uint64]uint64),
+	}
+}
+
+// Reset clears the encoder so it can be reused.
+func (e *Uint64Encoder) Reset() {
+	e.values = e.values[:0]
+	e.ids = make(map[uint64]uint64)
+	e.written = e.written[:0]
+}
+
+// Write adds v to the encoder.
+func (e *Uint64Encoder) Write(v uint64) error {
+	id, ok := e.ids[v]
+	if !ok {
+		id = uint64(len(e.values))
+		e.ids[v] = id
+		e.values = append(e.values, v)
+	}
+	e.written = append(e.written, id)
+	return nil
+}
+
+// Bytes returns the dictionary and codes of the values written to the encoder.
+// Values may continue to be written after calling Bytes.
+func (e *Uint64Encoder) Bytes() ([]byte, error) {
+	order := make([]int, len(e.values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return e.values[order[i]] < e.values[order[j]] })
+
+	e.out = appendHeader(e.out[:0], typeUint64, len(e.written), len(e.values))
+
+	var b [binary.MaxVarintLen64]byte
+	codes := make([]uint64, len(e.values))
+	var prev uint64
+	for code, id := range order {
+		v := e.values[id]
+		codes[id] = uint64(code)
+		e.out = append(e.out, b[:binary.PutUvarint(b[:], v-prev)]...)
+		prev = v
+	}
+
+	src := make([]uint64, len(e.written))
+	for i, id := range e.written {
+		src[i] = codes[id]
+	}
+	return appendCodes(e.out, src)
+}
+
+// Uint64Decoder converts a dictionary encoded byte slice to a stream of unsigned
+// 64bit integers.
+type Uint64Decoder struct {
+	dict  []uint64
+	codes codeReader
+	err   error
+}
+
+// NewUint64Decoder returns a Uint64Decoder from a byte slice
+func NewUint64Decoder(b []byte) *Uint64Decoder {
+	d := &Uint64Decoder{}
+	d.SetBytes(b)
+	return d
+}
+
+// SetBytes resets the decoder to read from b.  The dictionary is expanded once here.
+func (d *Uint64Decoder) SetBytes(b []byte) {
+	d.err = nil
+
+	count, entries, b, err := readHeader(b, typeUint64)
+	if err == nil {
+		d.dict, b, err = readUint64s(d.dict[:0], b, entries)
+	}
+	if err != nil {
+		d.dict = d.dict[:0]
+		d.codes.reset(nil, 0, 0)
+		d.err = err
+		return
+	}
+	d.codes.reset(b, count, entries)
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *Uint64Decoder) Next() bool {
+	if d.err != nil {
+		return false
+	}
+	if !d.codes.next() {
+		d.err = d.codes.err
+		return false
+	}
+	return true
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (d *Uint64Decoder) Read() uint64 {
+	return d.dict[d.codes.code()]
+}
+
+// Code returns the code of the current value, which is its index in Dict.
+func (d *Uint64Decoder) Code() uint64 {
+	return d.codes.code()
+}
+
+// Dict returns the distinct values in ascending order.  The slice must not be
+// modified.
+func (d *Uint64Decoder) Dict() []uint64 {
+	return d.dict
+}
+
+// Err returns the first error encountered while decoding.
+func (d *Uint64Decoder) Err() error {
+	return d.err
+}
+
+// EncodeAllUint64 returns the dictionary encoded form of the values in src.
+func EncodeAllUint64(src []uint64) ([]byte, error) {
+	enc := NewUint64Encoder()
+	for _, v := range src {
+		if err := enc.Write(v); err != nil {
+			return nil, err
+		}
+	}
+	return enc.Bytes()
+}
+
+// DecodeAllUint64 writes the values from src to dst.  It returns the number of
+// values written or an error.
+func DecodeAllUint64(dst []uint64, src []byte) (int, error) {
+	count, _, _, err := readHeader(src, typeUint64)
+	if err != nil {
+		return 0, err
+	}
+	if len(dst) < count {
+		return 0, fmt.Errorf("dst too small: need %d values, have %d", count, len(dst))
+	}
+
+	d := NewUint64Decoder(src)
+	i := 0
+	for d.Next() {
+		dst[i] = d.Read()
+		i++
+	}
+	if d.Err() != nil {
+		return 0, d.Err()
+	}
+	return i, nil
+}
+
+// readUint64s appends the n delta coded entries at the start of b to dict.  It
+// returns the extended dictionary and the bytes following the entries.
+func readUint64s(dict []uint64, b []byte, n int) ([]uint64, []byte, error) {
+	var prev uint64
+	for i := 0; i < n; i++ {
+		delta, l := binary.Uvarint(b)
+		if l <= 0 {
+			return nil, nil, fmt.Errorf("invalid delta for entry %d", i)
+		}
+		if i > 0 && (delta == 0 || prev+delta < prev) {
+			return nil, nil, fmt.Errorf("dictionary not sorted at entry %d", i)
+		}
+		b = b[l:]
+		prev += delta
+		dict = append(dict, prev)
+	}
+	return dict, b, nil
+}
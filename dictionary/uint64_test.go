@@ -0,0 +1,141 @@
+package dictionary_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jwilder/encoding/dictionary"
+)
+
+func testUint64RoundTrip(t *testing.T, values []uint64) []byte {
+	b, err := dictionary.EncodeAllUint64(values)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n, err := dictionary.CountBytes(b); err != nil || n != len(values) {
+		t.Fatalf("Count mismatch: got %v %v, exp %v", n, err, len(values))
+	}
+
+	got := make([]uint64, len(values))
+	n, err := dictionary.DecodeAllUint64(got, b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(values) {
+		t.Fatalf("Len mismatch: got %v, exp %v", n, len(values))
+	}
+	for i := range values {
+		if got[i] != values[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, values[i], got[i])
+		}
+	}
+	return b
+}
+
+func Test_EncodeUint64_NoValues(t *testing.T) {
+	testUint64RoundTrip(t, nil)
+}
+
+func Test_EncodeUint64_Wide(t *testing.T) {
+	values := []uint64{math.MaxUint64, 0, 1 << 63, math.MaxUint64, 0, 1 << 63, 42}
+	testUint64RoundTrip(t, values)
+}
+
+func Test_EncodeUint64_LowCardinality(t *testing.T) {
+	values := make([]uint64, 5000)
+	for i := range values {
+		values[i] = uint64(i%4) * 1e15
+	}
+
+	b := testUint64RoundTrip(t, values)
+
+	// Four codes use 2 bits each, 30 per word.
+	if max := 4 + 4*8 + (5000+29)/30*8; len(b) > max {
+		t.Fatalf("Encoded len too large: got %v, exp at most %v", len(b), max)
+	}
+}
+
+func Test_Uint64Decoder_Codes(t *testing.T) {
+	values := []uint64{300, 100, 200, 100, 300}
+	b, _ := dictionary.EncodeAllUint64(values)
+
+	dec := dictionary.NewUint64Decoder(b)
+	exp := []uint64{2, 0, 1, 0, 2}
+	i := 0
+	for dec.Next() {
+		if dec.Code() != exp[i] {
+			t.Fatalf("Code[%d] != %v, got %v", i, exp[i], dec.Code())
+		}
+		if dec.Read() != values[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, values[i], dec.Read())
+		}
+		i++
+	}
+	if dec.Err() != nil {
+		t.Fatalf("Unexpected error: %v", dec.Err())
+	}
+	if i != len(values) {
+		t.Fatalf("Len mismatch: got %v, exp %v", i, len(values))
+	}
+}
+
+func Test_DecodeUint64_Truncated(t *testing.T) {
+	b, _ := dictionary.EncodeAllUint64([]uint64{1, 1 << 40, 1, 7})
+	for n := 1; n < len(b); n++ {
+		if _, err := dictionary.DecodeAllUint64(make([]uint64, 4), b[:n]); err == nil {
+			t.Fatalf("Expected error for input truncated to %d bytes, got nil", n)
+		}
+	}
+}
+
+func Test_DecodeUint64_InvalidCode(t *testing.T) {
+	b, _ := dictionary.EncodeAllUint64([]uint64{5, 6})
+
+	// Replace the codes with a word holding the single code 2, using selector 15.
+	b = append(b[:len(b)-8], 0xf0, 0, 0, 0, 0, 0, 0, 2)
+	if _, err := dictionary.DecodeAllUint64(make([]uint64, 2), b); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func FuzzDecodeAllUint64(f *testing.F) {
+	for _, in := range [][]uint64{nil, {1}, {5, 5, 1 << 63, 7, 5}} {
+		b, _ := dictionary.EncodeAllUint64(in)
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := dictionary.CountBytes(b)
+		if err != nil || n > 1<<20 {
+			return
+		}
+
+		dst := make([]uint64, n)
+		got, err := dictionary.DecodeAllUint64(dst, b)
+		if err != nil {
+			dec := dictionary.NewUint64Decoder(b)
+			for dec.Next() {
+			}
+			if dec.Err() == nil {
+				t.Fatalf("Expected Decoder error, got nil")
+			}
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAllUint64 mismatch: got %v, exp %v", got, n)
+		}
+
+		dec := dictionary.NewUint64Decoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] || dec.Code() >= uint64(len(dec.Dict())) {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
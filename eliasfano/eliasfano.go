@@ -0,0 +1,373 @@
+// Package eliasfano implements the Elias-Fano representation of non-decreasing
+// sequences of integers, as used for quasi-succinct indexes by Vigna in
+// "Quasi-Succinct Indices", WSDM 2013.
+//
+// Each value is split into l low bits, stored verbatim, and the remaining high
+// bits, stored in unary as the gaps between consecutive ones of a bitvector.  With
+// l = ⌊log2(u/n)⌋ for n values below u, a sequence uses at most 2 + ⌈log2(u/n)⌉ bits
+// per value.  Sampled positions of the ones and zeros of the high bits give
+// constant time random access, and successor queries that only search the values
+// sharing the high bits of the query.
+package eliasfano
+
+// The serialized form starts with the number of values as a uvarint and the
+// number of low bits as a byte, followed by the low and then high bit words as
+// 8 byte big endian integers.
+//
+// ┌──────────┬─────────┬────────────────────────────┬─────────────────────────────┐
+// │  Count   │ Low Len │          Low bits          │          High bits          │
+// ├──────────┼─────────┼────────────────────────────┼─────────────────────────────┤
+// │ uvarint  │ 1 byte  │  ⌈n·l/64⌉ words            │  remaining words            │
+// └──────────┴─────────┴────────────────────────────┴─────────────────────────────┘
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/jwilder/encoding/bitops"
+)
+
+// sampleRate is the number of ones, or zeros, of the high bits between sampled
+// positions.
+const sampleRate = 256
+
+// Sequence is an Elias-Fano encoded non-decreasing sequence of uint64 values.
+type Sequence struct {
+	n int
+
+	// number of low bits per value
+	l uint
+
+	low  []uint64
+	high []uint64
+
+	// ones[k] is the position of one number k*sampleRate in high, and zeros[k] the
+	// position of zero number k*sampleRate.
+	ones  []int
+	zeros []int
+}
+
+// New returns a Sequence holding the values from src.  An error is returned if src
+// is not non-decreasing.
+func New(src []uint64) (*Sequence, error) {
+	for i := 1; i < len(src); i++ {
+		if src[i] < src[i-1] {
+			return nil, fmt.Errorf("values not sorted: %d follows %d", src[i], src[i-1])
+		}
+	}
+
+	s := &Sequence{n: len(src)}
+	if len(src) > 0 {
+		if r := src[len(src)-1] / uint64(len(src)); r > 0 {
+			s.l = uint(msb(r))
+		}
+	}
+
+	s.low = make([]uint64, (uint(len(src))*s.l+63)/64)
+	var maxHigh uint64
+	if len(src) > 0 {
+		maxHigh = src[len(src)-1] >> s.l
+	}
+	s.high = make([]uint64, (uint64(len(src))+maxHigh+1+63)/64)
+
+	mask := uint64(1)<<s.l - 1
+	for i, v := range src {
+		s.setLow(i, v&mask)
+		p := (v >> s.l) + uint64(i)
+		s.high[p/64] |= 1 << (p % 64)
+	}
+
+	s.buildSamples()
+	return s, nil
+}
+
+// NewBytes returns a Sequence from the bytes returned by Bytes.
+func NewBytes(b []byte) (*Sequence, error) {
+	count, h := binary.Uvarint(b)
+	if h <= 0 || len(b) < h+1 {
+		return nil, fmt.Errorf("invalid header")
+	}
+	l := uint(b[h])
+	b = b[h+1:]
+	if l > 63 {
+		return nil, fmt.Errorf("invalid low bits: %d", l)
+	}
+	if len(b)%8 != 0 {
+		return nil, fmt.Errorf("invalid slice len remaining: %v", len(b)%8)
+	}
+
+	words := len(b) / 8
+	if count > uint64(words)*64 {
+		return nil, fmt.Errorf("invalid count %d for %d words", count, words)
+	}
+	n := int(count)
+
+	lowWords := (n*int(l) + 63) / 64
+	if lowWords > words {
+		return nil, fmt.Errorf("unexpected end of low bits")
+	}
+
+	s := &Sequence{
+		n:    n,
+		l:    l,
+		low:  make([]uint64, lowWords),
+		high: make([]uint64, words-lowWords),
+	}
+	for i := range s.low {
+		s.low[i] = binary.BigEndian.Uint64(b[i*8:])
+	}
+	b = b[lowWords*8:]
+
+	ones := 0
+	for i := range s.high {
+		s.high[i] = binary.BigEndian.Uint64(b[i*8:])
+		ones += bitops.PopCount64(s.high[i])
+	}
+	if ones != n {
+		return nil, fmt.Errorf("invalid high bits: %d ones for %d values", ones, n)
+	}
+
+	// NextGEQ relies on the values being sorted, which crafted bits may not be.
+	var prev uint64
+	pos := 0
+	for i := 0; i < n; i++ {
+		pos = s.nextOne(pos)
+		high := uint64(pos - i)
+		if high > math.MaxUint64>>l {
+			return nil, fmt.Errorf("invalid high bits: value %d overflows", i)
+		}
+		v := high<<l | s.getLow(i)
+		if v < prev {
+			return nil, fmt.Errorf("invalid sequence: value %d is out of order", i)
+		}
+		prev = v
+		pos++
+	}
+
+	s.buildSamples()
+	return s, nil
+}
+
+// Bytes returns the serialized sequence.
+func (s *Sequence) Bytes() []byte {
+	b := make([]byte, binary.MaxVarintLen64+1, binary.MaxVarintLen64+1+8*(len(s.low)+len(s.high)))
+	h := binary.PutUvarint(b, uint64(s.n))
+	b[h] = byte(s.l)
+	b = b[:h+1]
+
+	var buf [8]byte
+	for _, w := range s.low {
+		binary.BigEndian.PutUint64(buf[:], w)
+		b = append(b, buf[:]...)
+	}
+	for _, w := range s.high {
+		binary.BigEndian.PutUint64(buf[:], w)
+		b = append(b, buf[:]...)
+	}
+	return b
+}
+
+// Len returns the number of values in the sequence.
+func (s *Sequence) Len() int {
+	return s.n
+}
+
+// Size returns the number of bytes used by the low and high bits.
+func (s *Sequence) Size() int {
+	return 8 * (len(s.low) + len(s.high))
+}
+
+// Access returns the value at index i, which as the sequence is sorted is also the
+// value with rank i.  It panics if i is out of range.
+func (s *Sequence) Access(i int) uint64 {
+	if i < 0 || i >= s.n {
+		panic(fmt.Sprintf("eliasfano: index out of range [%d] with length %d", i, s.n))
+	}
+
+	high := uint64(s.select1(i) - i)
+	return high<<s.l | s.getLow(i)
+}
+
+// Rank returns the number of values less than x.
+func (s *Sequence) Rank(x uint64) int {
+	i, _, ok := s.NextGEQ(x)
+	if !ok {
+		return s.n
+	}
+	return i
+}
+
+// NextGEQ returns the index and value of the first value greater than or equal to
+// x.  It returns false if there is no such value.  The values sharing the high bits
+// of x follow zero number hx-1 of the high bits and are binary searched on their
+// low bits, so for b such values it takes O(log b) time plus a scan of b/64 words.
+func (s *Sequence) NextGEQ(x uint64) (int, uint64, bool) {
+	hx := x >> s.l
+	if hx >= uint64(len(s.high))*64 {
+		return 0, 0, false
+	}
+
+	pos, i := 0, 0
+	if hx > 0 {
+		p := s.select0(int(hx - 1))
+		if p < 0 {
+			return 0, 0, false
+		}
+		pos = p + 1
+		i = pos - int(hx)
+	}
+
+	// Values i up to j have the high bits of x, and later values are larger than x.
+	z := s.nextZero(pos)
+	j := z - int(hx)
+	lx := x & (1<<s.l - 1)
+	lo, hi := i, j
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if s.getLow(m) < lx {
+			lo = m + 1
+		} else {
+			hi = m
+		}
+	}
+	if lo < j {
+		return lo, hx<<s.l | s.getLow(lo), true
+	}
+
+	if j >= s.n {
+		return 0, 0, false
+	}
+	pos = s.nextOne(z)
+	return j, uint64(pos-j)<<s.l | s.getLow(j), true
+}
+
+// select1 returns the position of one number i in high.
+func (s *Sequence) select1(i int) int {
+	p := s.ones[i/sampleRate]
+	r := i % sampleRate
+
+	w := p / 64
+	x := s.high[w] &^ (1<<uint(p%64) - 1)
+	for {
+		c := bitops.PopCount64(x)
+		if r < c {
+			return w*64 + bitops.Select64(x, r)
+		}
+		r -= c
+		w++
+		x = s.high[w]
+	}
+}
+
+// select0 returns the position of zero number i in high, or -1 if there are not
+// enough zeros.
+func (s *Sequence) select0(i int) int {
+	if i/sampleRate >= len(s.zeros) {
+		return -1
+	}
+	p := s.zeros[i/sampleRate]
+	r := i % sampleRate
+
+	w := p / 64
+	x := ^s.high[w] &^ (1<<uint(p%64) - 1)
+	for {
+		c := bitops.PopCount64(x)
+		if r < c {
+			return w*64 + bitops.Select64(x, r)
+		}
+		r -= c
+		w++
+		if w >= len(s.high) {
+			return -1
+		}
+		x = ^s.high[w]
+	}
+}
+
+// nextZero returns the position of the first zero in high at or after p, or the
+// length of high in bits if there is none.
+func (s *Sequence) nextZero(p int) int {
+	w := p / 64
+	if w >= len(s.high) {
+		return len(s.high) * 64
+	}
+	x := ^s.high[w] &^ (1<<uint(p%64) - 1)
+	for x == 0 {
+		w++
+		if w >= len(s.high) {
+			return len(s.high) * 64
+		}
+		x = ^s.high[w]
+	}
+	return w*64 + bitops.Select64(x, 0)
+}
+
+// nextOne returns the position of the first one in high at or after p, which must
+// exist.
+func (s *Sequence) nextOne(p int) int {
+	w := p / 64
+	x := s.high[w] &^ (1<<uint(p%64) - 1)
+	for x == 0 {
+		w++
+		x = s.high[w]
+	}
+	return w*64 + bitops.Select64(x, 0)
+}
+
+// buildSamples records the position of every sampleRate'th one and zero in high.
+func (s *Sequence) buildSamples() {
+	s.ones = s.ones[:0]
+	s.zeros = s.zeros[:0]
+
+	ones, zeros := 0, 0
+	for w, x := range s.high {
+		for b := 0; b < 64; b++ {
+			if x>>uint(b)&1 == 1 {
+				if ones%sampleRate == 0 {
+					s.ones = append(s.ones, w*64+b)
+				}
+				ones++
+			} else {
+				if zeros%sampleRate == 0 {
+					s.zeros = append(s.zeros, w*64+b)
+				}
+				zeros++
+			}
+		}
+	}
+}
+
+func (s *Sequence) setLow(i int, v uint64) {
+	if s.l == 0 {
+		return
+	}
+	p := uint(i) * s.l
+	w, off := p/64, p%64
+	s.low[w] |= v << off
+	if off+s.l > 64 {
+		s.low[w+1] |= v >> (64 - off)
+	}
+}
+
+func (s *Sequence) getLow(i int) uint64 {
+	if s.l == 0 {
+		return 0
+	}
+	p := uint(i) * s.l
+	w, off := p/64, p%64
+	v := s.low[w] >> off
+	if off+s.l > 64 {
+		v |= s.low[w+1] << (64 - off)
+	}
+	return v & (1<<s.l - 1)
+}
+
+// msb returns the position of the most significant set bit of v, which must be
+// non-zero.
+func msb(v uint64) int {
+	n := -1
+	for ; v != 0; v >>= 1 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,215 @@
+package eliasfano_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/jwilder/encoding/eliasfano"
+)
+
+func randomSorted(n int, gap int64) []uint64 {
+	values := make([]uint64, n)
+	var v uint64
+	for i := range values {
+		v += uint64(rand.Int63n(gap))
+		values[i] = v
+	}
+	return values
+}
+
+func newSequence(t *testing.T, values []uint64) *eliasfano.Sequence {
+	s, err := eliasfano.New(values)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return s
+}
+
+func Test_Access(t *testing.T) {
+	for _, gap := range []int64{1, 2, 10, 1000, 1 << 40} {
+		values := randomSorted(5000, gap)
+		s := newSequence(t, values)
+
+		if s.Len() != len(values) {
+			t.Fatalf("Len mismatch: got %v, exp %v", s.Len(), len(values))
+		}
+		for i, v := range values {
+			if got := s.Access(i); got != v {
+				t.Fatalf("gap %d: Access(%d) = %v, exp %v", gap, i, got, v)
+			}
+		}
+	}
+}
+
+func Test_NextGEQ(t *testing.T) {
+	for _, gap := range []int64{1, 3, 100, 1 << 30} {
+		values := randomSorted(3000, gap)
+		s := newSequence(t, values)
+		max := values[len(values)-1]
+
+		for k := 0; k < 5000; k++ {
+			x := uint64(rand.Int63n(int64(max) + 10))
+			exp := sort.Search(len(values), func(i int) bool { return values[i] >= x })
+
+			i, v, ok := s.NextGEQ(x)
+			if exp == len(values) {
+				if ok {
+					t.Fatalf("gap %d: NextGEQ(%d) expected false, got %v %v", gap, x, i, v)
+				}
+			} else if !ok || i != exp || v != values[exp] {
+				t.Fatalf("gap %d: NextGEQ(%d) = %v %v %v, exp %v %v", gap, x, i, v, ok, exp, values[exp])
+			}
+
+			if got := s.Rank(x); got != exp {
+				t.Fatalf("gap %d: Rank(%d) = %v, exp %v", gap, x, got, exp)
+			}
+		}
+	}
+}
+
+func Test_NextGEQ_Bucket(t *testing.T) {
+	// The large last value gives enough low bits for the other values to share
+	// one bucket.
+	values := make([]uint64, 0, 4097)
+	for i := 0; i < 4096; i++ {
+		values = append(values, uint64(i*3))
+	}
+	values = append(values, 1<<40)
+	s := newSequence(t, values)
+
+	for x := uint64(0); x < 4096*3+1; x++ {
+		exp := sort.Search(len(values), func(i int) bool { return values[i] >= x })
+		if i, v, ok := s.NextGEQ(x); !ok || i != exp || v != values[exp] {
+			t.Fatalf("NextGEQ(%d) = %v %v %v, exp %v %v", x, i, v, ok, exp, values[exp])
+		}
+	}
+	if i, _, ok := s.NextGEQ(1<<40 + 1); ok {
+		t.Fatalf("NextGEQ(%d) expected false, got %v", uint64(1<<40+1), i)
+	}
+}
+
+func Test_Duplicates(t *testing.T) {
+	values := []uint64{0, 0, 5, 5, 5, 9, math.MaxUint64, math.MaxUint64}
+	s := newSequence(t, values)
+	for i, v := range values {
+		if got := s.Access(i); got != v {
+			t.Fatalf("Access(%d) = %v, exp %v", i, got, v)
+		}
+	}
+
+	if i, v, ok := s.NextGEQ(5); !ok || i != 2 || v != 5 {
+		t.Fatalf("NextGEQ(5) = %v %v %v, exp 2 5 true", i, v, ok)
+	}
+	if i, _, ok := s.NextGEQ(10); !ok || i != 6 {
+		t.Fatalf("NextGEQ(10) = %v %v, exp 6 true", i, ok)
+	}
+}
+
+func Test_Empty(t *testing.T) {
+	s := newSequence(t, nil)
+	if s.Len() != 0 {
+		t.Fatalf("Len mismatch: got %v, exp 0", s.Len())
+	}
+	if _, _, ok := s.NextGEQ(0); ok {
+		t.Fatalf("Expected NextGEQ to return false")
+	}
+	if s.Rank(10) != 0 {
+		t.Fatalf("Rank mismatch: got %v, exp 0", s.Rank(10))
+	}
+}
+
+func Test_Unsorted(t *testing.T) {
+	if _, err := eliasfano.New([]uint64{3, 2}); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_Size(t *testing.T) {
+	values := randomSorted(10000, 1000)
+	s := newSequence(t, values)
+
+	// At most 2 + ⌈log2(u/n)⌉ bits per value, plus word padding.
+	u := float64(values[len(values)-1])
+	bound := float64(len(values))*(2+math.Ceil(math.Log2(u/float64(len(values)))))/8 + 16
+	if float64(s.Size()) > bound {
+		t.Fatalf("Size %d exceeds bound %v", s.Size(), bound)
+	}
+}
+
+func Test_Bytes(t *testing.T) {
+	values := randomSorted(1000, 50)
+	s := newSequence(t, values)
+
+	s2, err := eliasfano.NewBytes(s.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s2.Len() != len(values) {
+		t.Fatalf("Len mismatch: got %v, exp %v", s2.Len(), len(values))
+	}
+	for i, v := range values {
+		if got := s2.Access(i); got != v {
+			t.Fatalf("Access(%d) = %v, exp %v", i, got, v)
+		}
+	}
+
+	b := s.Bytes()
+	if _, err := eliasfano.NewBytes(b[:len(b)-8]); err == nil {
+		t.Fatalf("Expected error for truncated bytes, got nil")
+	}
+	if _, err := eliasfano.NewBytes(b[:len(b)-1]); err == nil {
+		t.Fatalf("Expected error for partial word, got nil")
+	}
+}
+
+func FuzzNewBytes(f *testing.F) {
+	for _, values := range [][]uint64{nil, {3}, {1, 5, 5, 9, 1 << 40}} {
+		s, _ := eliasfano.New(values)
+		f.Add(s.Bytes())
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		s, err := eliasfano.NewBytes(b)
+		if err != nil {
+			return
+		}
+
+		var prev uint64
+		for i := 0; i < s.Len(); i++ {
+			v := s.Access(i)
+			if v < prev {
+				t.Fatalf("Access(%d) = %v, less than %v", i, v, prev)
+			}
+			if j, got, ok := s.NextGEQ(v); !ok || got != v || j > i {
+				t.Fatalf("NextGEQ(%d) = %v %v %v, exp %v at %v", v, j, got, ok, v, i)
+			}
+			prev = v
+		}
+		if _, _, ok := s.NextGEQ(prev + 1); ok && prev+1 != 0 {
+			t.Fatalf("NextGEQ(%d) expected false", prev+1)
+		}
+	})
+}
+
+func BenchmarkAccess(b *testing.B) {
+	values := randomSorted(1<<20, 100)
+	s, _ := eliasfano.New(values)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Access(i & (1<<20 - 1))
+	}
+}
+
+func BenchmarkNextGEQ(b *testing.B) {
+	values := randomSorted(1<<20, 100)
+	s, _ := eliasfano.New(values)
+	max := int64(values[len(values)-1])
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.NextGEQ(uint64(rand.Int63n(max)))
+	}
+}
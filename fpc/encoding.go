@@ -0,0 +1,402 @@
+// Package fpc implements the lossless double precision floating point compression
+// algorithm as published by Burtscher and Ratanaworabhan in "FPC: A High-Speed
+// Compressor for Double-Precision Floating-Point Data", IEEE Transactions on
+// Computers, Vol. 58, No. 1, January 2009.
+//
+// Each value is predicted by a finite context method (FCM) predictor and a
+// differential finite context method (DFCM) predictor.  The prediction closest to
+// the actual value is XORed with it and only the non-zero low order bytes of the
+// residual are stored, along with a 4 bit code recording the predictor used and
+// the number of leading zero bytes.
+package fpc
+
+// The encoded form starts with a one byte table size (log2 of the number of
+// predictor entries) followed by the number of values as a uvarint.  Values are then
+// encoded in pairs: one header byte holding a 4 bit code for each value followed by
+// the residual bytes of the first and then the second value.
+//
+// ┌─────────────┬───────────────────────────────────────────────────────────────┐
+// │    Code     │       0   1   2   3   4   5   6   7                           │
+// ├─────────────┼───────────────────────────────────────────────────────────────┤
+// │ Zero Bytes  │       0   1   2   3   5   6   7   8                           │
+// └─────────────┴───────────────────────────────────────────────────────────────┘
+//
+// Four leading zero bytes cannot be represented and are stored as three.  The most
+// significant bit of the code is set when the DFCM prediction was used.
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// TableBits is log2 of the number of entries in each predictor hash table.
+const TableBits = 12
+
+// maxTableBits bounds the table size read from a header, as both tables are
+// allocated before any values are decoded.
+const maxTableBits = 20
+
+// Encoder converts a stream of float64 values to a compressed byte slice.
+type Encoder struct {
+	p predictor
+
+	// pending holds the code and residual of the first value in an incomplete pair.
+	pending  bool
+	code     byte
+	residual uint64
+
+	// number of values written
+	n int
+
+	// encoded pairs written so far
+	bytes []byte
+
+	// encoded header and pairs returned by Bytes
+	out []byte
+}
+
+// NewEncoder returns an Encoder able to convert float64s to compressed byte slices
+func NewEncoder() *Encoder {
+	return &Encoder{
+		p:     newPredictor(TableBits),
+		bytes: make([]byte, 0, 128),
+	}
+}
+
+// Reset clears the encoder so it can be reused.
+func (e *Encoder) Reset() {
+	e.p.reset()
+	e.pending = false
+	e.n = 0
+	e.bytes = e.bytes[:0]
+}
+
+// Write adds v to the encoder.
+func (e *Encoder) Write(v float64) error {
+	code, residual := e.p.encode(math.Float64bits(v))
+	e.n += 1
+
+	if !e.pending {
+		e.code, e.residual = code, residual
+		e.pending = true
+		return nil
+	}
+
+	e.bytes = appendPair(e.bytes, e.code, e.residual, code, residual)
+	e.pending = false
+	return nil
+}
+
+// Bytes returns the encoded values written to the encoder.  Values may continue to
+// be written after calling Bytes.
+func (e *Encoder) Bytes() ([]byte, error) {
+	var b [binary.MaxVarintLen64]byte
+	e.out = append(e.out[:0], TableBits)
+	e.out = append(e.out, b[:binary.PutUvarint(b[:], uint64(e.n))]...)
+	e.out = append(e.out, e.bytes...)
+
+	// An odd value is paired with an empty residual that is never decoded.
+	if e.pending {
+		e.out = appendPair(e.out, e.code, e.residual, 7, 0)
+	}
+	return e.out, nil
+}
+
+// Decoder converts a compressed byte slice to a stream of float64 values.
+type Decoder struct {
+	p     predictor
+	bytes []byte
+	buf   [2]uint64
+	i     int
+	n     int
+
+	// number of values remaining
+	remaining int
+
+	err error
+}
+
+// NewDecoder returns a Decoder from a byte slice
+func NewDecoder(b []byte) *Decoder {
+	d := &Decoder{}
+	d.SetBytes(b)
+	return d
+}
+
+// SetBytes resets the decoder to read from b.
+func (d *Decoder) SetBytes(b []byte) {
+	d.i = 0
+	d.n = 0
+	d.err = nil
+
+	bits, count, b, err := readHeader(b)
+	if err != nil {
+		d.bytes = nil
+		d.remaining = 0
+		d.err = err
+		return
+	}
+
+	if d.p.bits != bits {
+		d.p = newPredictor(bits)
+	} else {
+		d.p.reset()
+	}
+	d.bytes = b
+	d.remaining = count
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *Decoder) Next() bool {
+	d.i += 1
+
+	if d.i >= d.n {
+		d.read()
+	}
+
+	return d.i < d.n
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (d *Decoder) Read() float64 {
+	return math.Float64frombits(d.buf[d.i])
+}
+
+// Err returns the first error encountered while decoding.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+func (d *Decoder) read() {
+	d.i = 0
+	d.n = 0
+	if d.err != nil || d.remaining == 0 {
+		return
+	}
+
+	n := 2
+	if d.remaining < n {
+		n = d.remaining
+	}
+
+	b, err := d.p.decodePair(d.buf[:n], d.bytes)
+	if err != nil {
+		d.err = err
+		return
+	}
+	d.bytes = b
+	d.remaining -= n
+	d.n = n
+}
+
+// EncodeAll returns the compressed form of the values in src.
+func EncodeAll(src []float64) ([]byte, error) {
+	enc := NewEncoder()
+	for _, v := range src {
+		if err := enc.Write(v); err != nil {
+			return nil, err
+		}
+	}
+	return enc.Bytes()
+}
+
+// DecodeAll writes the uncompressed values from src to dst.  It returns the number
+// of values written or an error.
+func DecodeAll(dst []float64, src []byte) (int, error) {
+	bits, count, src, err := readHeader(src)
+	if err != nil {
+		return 0, err
+	}
+	if len(dst) < count {
+		return 0, fmt.Errorf("dst too small: need %d values, have %d", count, len(dst))
+	}
+
+	p := newPredictor(bits)
+	var buf [2]uint64
+	for j := 0; j < count; j += 2 {
+		n := 2
+		if count-j < n {
+			n = count - j
+		}
+
+		src, err = p.decodePair(buf[:n], src)
+		if err != nil {
+			return 0, err
+		}
+
+		for i := 0; i < n; i++ {
+			dst[j+i] = math.Float64frombits(buf[i])
+		}
+	}
+	return count, nil
+}
+
+// CountBytes returns the number of values encoded in the byte slice
+func CountBytes(b []byte) (int, error) {
+	_, count, _, err := readHeader(b)
+	return count, err
+}
+
+// readHeader returns the table size and value count from b and the remaining bytes.
+func readHeader(b []byte) (bits uint, count int, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, 0, nil, fmt.Errorf("header truncated: %d bytes", len(b))
+	}
+
+	bits = uint(b[0])
+	if bits == 0 || bits > maxTableBits {
+		return 0, 0, nil, fmt.Errorf("invalid table size: %d", bits)
+	}
+
+	v, n := binary.Uvarint(b[1:])
+	if n <= 0 || v > math.MaxInt32 {
+		return 0, 0, nil, fmt.Errorf("invalid value count")
+	}
+
+	// Each value updates one entry of each table, so tables larger than the default
+	// are only useful with at least as many values as entries.
+	if bits > TableBits && uint64(1)<<bits > v {
+		return 0, 0, nil, fmt.Errorf("invalid table size: %d for %d values", bits, v)
+	}
+	return bits, int(v), b[1+n:], nil
+}
+
+// appendPair appends the header byte and residuals of two values to b.
+func appendPair(b []byte, code1 byte, residual1 uint64, code2 byte, residual2 uint64) []byte {
+	b = append(b, code1<<4|code2)
+	b = appendResidual(b, code1, residual1)
+	return appendResidual(b, code2, residual2)
+}
+
+// appendResidual appends the non-zero low order bytes of v to b.
+func appendResidual(b []byte, code byte, v uint64) []byte {
+	for i := 0; i < residualLen(code); i++ {
+		b = append(b, byte(v))
+		v >>= 8
+	}
+	return b
+}
+
+// residualLen returns the number of residual bytes stored for code.
+func residualLen(code byte) int {
+	lzb := int(code & 7)
+	if lzb > 3 {
+		lzb += 1
+	}
+	return 8 - lzb
+}
+
+// predictor holds the FCM and DFCM predictor state shared by the encoder and
+// decoder.
+type predictor struct {
+	bits uint
+	mask uint64
+	fcm  []uint64
+	dfcm []uint64
+
+	fcmHash  uint64
+	dfcmHash uint64
+	last     uint64
+}
+
+func newPredictor(bits uint) predictor {
+	return predictor{
+		bits: bits,
+		mask: 1<<bits - 1,
+		fcm:  make([]uint64, 1<<bits),
+		dfcm: make([]uint64, 1<<bits),
+	}
+}
+
+func (p *predictor) reset() {
+	for i := range p.fcm {
+		p.fcm[i] = 0
+		p.dfcm[i] = 0
+	}
+	p.fcmHash = 0
+	p.dfcmHash = 0
+	p.last = 0
+}
+
+// predict returns the FCM and DFCM predictions of the next value.
+func (p *predictor) predict() (fcm, dfcm uint64) {
+	return p.fcm[p.fcmHash], p.dfcm[p.dfcmHash] + p.last
+}
+
+// update records v as the actual value following the last prediction.
+func (p *predictor) update(v uint64) {
+	p.fcm[p.fcmHash] = v
+	p.fcmHash = (p.fcmHash<<6 ^ v>>48) & p.mask
+
+	delta := v - p.last
+	p.dfcm[p.dfcmHash] = delta
+	p.dfcmHash = (p.dfcmHash<<2 ^ delta>>40) & p.mask
+	p.last = v
+}
+
+// encode returns the code and residual for v using the better of the two predictions.
+func (p *predictor) encode(v uint64) (byte, uint64) {
+	fcm, dfcm := p.predict()
+	p.update(v)
+
+	var code byte
+	residual := v ^ fcm
+	if x := v ^ dfcm; x < residual {
+		code = 8
+		residual = x
+	}
+
+	lzb := byte(leadingZeroBytes(residual))
+	if lzb == 4 {
+		lzb = 3
+	} else if lzb > 4 {
+		lzb -= 1
+	}
+	return code | lzb, residual
+}
+
+// decodePair decodes len(dst) values using the pair header at the start of b.  It
+// returns the remaining bytes.
+func (p *predictor) decodePair(dst []uint64, b []byte) ([]byte, error) {
+	if len(b) < 1 {
+		return nil, fmt.Errorf("pair header truncated")
+	}
+	codes := [2]byte{b[0] >> 4, b[0] & 0xf}
+	b = b[1:]
+
+	for i := range dst {
+		n := residualLen(codes[i])
+		if len(b) < n {
+			return nil, fmt.Errorf("residual truncated: need %d bytes, have %d", n, len(b))
+		}
+
+		var residual uint64
+		for j := n - 1; j >= 0; j-- {
+			residual = residual<<8 | uint64(b[j])
+		}
+		b = b[n:]
+
+		fcm, dfcm := p.predict()
+		v := residual ^ fcm
+		if codes[i]&8 != 0 {
+			v = residual ^ dfcm
+		}
+		p.update(v)
+		dst[i] = v
+	}
+
+	// The residual of an unused second value is always empty.
+	return b, nil
+}
+
+// leadingZeroBytes returns the number of most significant zero bytes in v.
+func leadingZeroBytes(v uint64) int {
+	n := 0
+	for n < 8 && v>>56 == 0 {
+		v <<= 8
+		n++
+	}
+	return n
+}
@@ -0,0 +1,237 @@
+package fpc_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/jwilder/encoding/fpc"
+)
+
+func Test_Encode_NoValues(t *testing.T) {
+	testEncode(t, nil)
+}
+
+func Test_Encode_Single(t *testing.T) {
+	testEncode(t, []float64{3.14159})
+}
+
+func Test_Encode_Constant(t *testing.T) {
+	in := make([]float64, 1000)
+	for i := range in {
+		in[i] = 42.5
+	}
+	testEncode(t, in)
+
+	b, _ := fpc.EncodeAll(in)
+	// Every value after the first few is predicted exactly and needs only
+	// half a header byte.
+	if got, max := len(b), 520; got > max {
+		t.Fatalf("Encoded len too large: got %v, exp <= %v", got, max)
+	}
+}
+
+func Test_Encode_Linear(t *testing.T) {
+	in := make([]float64, 1001)
+	for i := range in {
+		in[i] = float64(i) * 0.25
+	}
+	testEncode(t, in)
+}
+
+func Test_Encode_Random(t *testing.T) {
+	rand.Seed(1)
+	in := make([]float64, 999)
+	for i := range in {
+		in[i] = rand.NormFloat64() * 1e6
+	}
+	testEncode(t, in)
+}
+
+func Test_Encode_Special(t *testing.T) {
+	testEncode(t, []float64{
+		0, math.Copysign(0, -1), math.Inf(1), math.Inf(-1), math.MaxFloat64,
+		math.SmallestNonzeroFloat64, -1, 1,
+	})
+
+	b, _ := fpc.EncodeAll([]float64{math.NaN()})
+	dec := fpc.NewDecoder(b)
+	if !dec.Next() || !math.IsNaN(dec.Read()) {
+		t.Fatalf("Expected NaN")
+	}
+}
+
+func Test_Decode_Truncated(t *testing.T) {
+	in := make([]float64, 10)
+	for i := range in {
+		in[i] = rand.Float64()
+	}
+	b, _ := fpc.EncodeAll(in)
+
+	dec := fpc.NewDecoder(b[:len(b)-1])
+	for dec.Next() {
+	}
+	if dec.Err() == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+
+	if _, err := fpc.DecodeAll(make([]float64, len(in)), b[:len(b)-1]); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_Decode_InvalidTableSize(t *testing.T) {
+	// Large tables for few values are rejected before they are allocated.
+	for _, b := range [][]byte{{0, 1}, {21, 1}, {24, 1}, {20, 1}, {13, 0x80, 0x20}} {
+		if _, err := fpc.CountBytes(b); err == nil {
+			t.Fatalf("Expected error for header %v, got nil", b)
+		}
+		if dec := fpc.NewDecoder(b); dec.Next() || dec.Err() == nil {
+			t.Fatalf("Expected decoder error for header %v, got nil", b)
+		}
+	}
+
+	// The default table size is accepted for any count.
+	if n, err := fpc.CountBytes([]byte{fpc.TableBits, 1}); err != nil || n != 1 {
+		t.Fatalf("CountBytes mismatch: got %v %v, exp 1", n, err)
+	}
+}
+
+func testEncode(t *testing.T, in []float64) {
+	enc := fpc.NewEncoder()
+	for _, v := range in {
+		if err := enc.Write(v); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	encoded, err := enc.Bytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := fpc.NewDecoder(encoded)
+	i := 0
+	for dec.Next() {
+		if i >= len(in) {
+			t.Fatalf("Decoded too many values: got %v, exp %v", i, len(in))
+		}
+
+		if math.Float64bits(dec.Read()) != math.Float64bits(in[i]) {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], dec.Read())
+		}
+		i += 1
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if exp, got := len(in), i; got != exp {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", exp, got)
+	}
+
+	got, err := fpc.CountBytes(encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error in Count: %v", err)
+	}
+	if got != len(in) {
+		t.Fatalf("Count mismatch: got %v, exp %v", got, len(in))
+	}
+
+	decoded := make([]float64, len(in))
+	n, err := fpc.DecodeAll(decoded, encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("DecodeAll len mismatch: exp %v, got %v", len(in), n)
+	}
+	for i := range in {
+		if math.Float64bits(decoded[i]) != math.Float64bits(in[i]) {
+			t.Fatalf("DecodeAll[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	for _, in := range [][]float64{nil, {1.5, 2.5, math.NaN()}, {0, 0, 0, math.Inf(1), -1e300}} {
+		encoded, _ := fpc.EncodeAll(in)
+		f.Add(encoded)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := fpc.CountBytes(b)
+		if err != nil || n > 1<<20 {
+			return
+		}
+
+		dst := make([]float64, n)
+		got, err := fpc.DecodeAll(dst, b)
+		if err != nil {
+			// The count is read from the header, so the values may still be invalid.
+			dec := fpc.NewDecoder(b)
+			for dec.Next() {
+			}
+			if dec.Err() == nil {
+				t.Fatalf("Expected Decoder error, got nil")
+			}
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAll mismatch: got %v, exp %v", got, n)
+		}
+		if n > 0 {
+			if _, err := fpc.DecodeAll(make([]float64, n-1), b); err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+		}
+
+		dec := fpc.NewDecoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || math.Float64bits(dec.Read()) != math.Float64bits(dst[i]) {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	x := make([]float64, 1024)
+	for i := 0; i < len(x); i++ {
+		x[i] = float64(i) * 0.1
+	}
+
+	enc := fpc.NewEncoder()
+	b.ResetTimer()
+	b.SetBytes(int64(len(x) * 8))
+	for i := 0; i < b.N; i++ {
+		enc.Reset()
+		for _, v := range x {
+			enc.Write(v)
+		}
+		enc.Bytes()
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	x := make([]float64, 1024)
+	for i := 0; i < len(x); i++ {
+		x[i] = float64(i) * 0.1
+	}
+	y, _ := fpc.EncodeAll(x)
+
+	b.ResetTimer()
+	b.SetBytes(int64(len(x) * 8))
+
+	dec := fpc.NewDecoder(y)
+	for i := 0; i < b.N; i++ {
+		dec.SetBytes(y)
+		for dec.Next() {
+		}
+	}
+}
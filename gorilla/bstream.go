@@ -0,0 +1,86 @@
+package gorilla
+
+import "io"
+
+// bitWriter appends bits to a byte slice, most significant bit first.
+type bitWriter struct {
+	b []byte
+
+	// number of unused low bits in the last byte of b
+	free uint
+}
+
+func (w *bitWriter) reset() {
+	w.b = w.b[:0]
+	w.free = 0
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if bit {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+}
+
+// writeBits writes the low n bits of v.
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	for n > 0 {
+		if w.free == 0 {
+			w.b = append(w.b, 0)
+			w.free = 8
+		}
+
+		k := n
+		if k > w.free {
+			k = w.free
+		}
+
+		// Write the top k of the remaining n bits into the free bits of the last byte.
+		chunk := byte(v>>(n-k)) & (1<<k - 1)
+		w.b[len(w.b)-1] |= chunk << (w.free - k)
+		w.free -= k
+		n -= k
+	}
+}
+
+// bitReader reads bits from a byte slice, most significant bit first.
+type bitReader struct {
+	b []byte
+
+	// position of the next bit to read
+	pos uint
+}
+
+func (r *bitReader) reset(b []byte) {
+	r.b = b
+	r.pos = 0
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	v, err := r.readBits(1)
+	return v == 1, err
+}
+
+// readBits reads n bits, up to 64, into the low bits of the returned value.
+func (r *bitReader) readBits(n uint) (uint64, error) {
+	if r.pos+n > uint(len(r.b))*8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	var v uint64
+	for n > 0 {
+		used := r.pos % 8
+		k := 8 - used
+		if k > n {
+			k = n
+		}
+
+		// Take k bits following the used bits of the current byte.
+		chunk := r.b[r.pos/8] >> (8 - used - k) & (1<<k - 1)
+		v = v<<k | uint64(chunk)
+		r.pos += k
+		n -= k
+	}
+	return v, nil
+}
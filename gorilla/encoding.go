@@ -0,0 +1,406 @@
+// Package gorilla implements the time series compression published by Pelkonen et
+// al. in "Gorilla: A Fast, Scalable, In-Memory Time Series Database", VLDB 2015.
+//
+// Each point is a timestamp and a float64 value.  Timestamps are stored as the
+// delta-of-delta from the previous point using a variable length prefix code.
+// Values are XORed with the previous value and only the meaningful bits between
+// the leading and trailing zeros of the result are stored, reusing the previous
+// window of meaningful bits when the new bits fit within it.
+package gorilla
+
+// The encoded form starts with the number of points as a uvarint, followed by a bit
+// stream written most significant bit first.  The first point is stored as a 64 bit
+// timestamp and a 64 bit value.  Each later timestamp is stored as the difference
+// D between its delta and the previous delta, which is zero for the first point:
+//
+// ┌──────────┬──────────────────────────────────┬────────────┐
+// │  Prefix  │             Range of D           │  D Bits    │
+// ├──────────┼──────────────────────────────────┼────────────┤
+// │     0    │               0                  │     0      │
+// │    10    │           [-63, 64]              │     7      │
+// │   110    │          [-255, 256]             │     9      │
+// │  1110    │         [-2047, 2048]            │    12      │
+// │  1111    │             any                  │    64      │
+// └──────────┴──────────────────────────────────┴────────────┘
+//
+// The last bucket holds 64 bits rather than the 32 of the paper, so that nanosecond
+// timestamps at any interval can be stored.  Each later value is stored as the XOR
+// X with the previous value:
+//
+// ┌──────────┬──────────────────────────────────────────────────────────────┐
+// │  Prefix  │                           Followed by                        │
+// ├──────────┼──────────────────────────────────────────────────────────────┤
+// │     0    │ nothing, X is zero                                           │
+// │    10    │ the meaningful bits of X within the previous window          │
+// │    11    │ 5 bits of leading zeros, 6 bits of meaningful bit count (64  │
+// │          │ stored as 0) and the meaningful bits, starting a new window  │
+// └──────────┴──────────────────────────────────────────────────────────────┘
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Encoder converts a stream of points to a compressed byte slice.
+type Encoder struct {
+	w bitWriter
+
+	// number of points written
+	n int
+
+	// previous timestamp and delta
+	t     int64
+	delta int64
+
+	// previous value and window of meaningful bits
+	v        uint64
+	leading  uint
+	trailing uint
+
+	// encoded header and bit stream returned by Bytes
+	out []byte
+}
+
+// NewEncoder returns an Encoder able to convert points to compressed byte slices
+func NewEncoder() *Encoder {
+	return &Encoder{
+		w: bitWriter{b: make([]byte, 0, 128)},
+	}
+}
+
+// Reset clears the encoder so it can be reused.
+func (e *Encoder) Reset() {
+	e.w.reset()
+	e.n = 0
+	e.delta = 0
+}
+
+// Write adds the point with timestamp t and value v to the encoder.
+func (e *Encoder) Write(t int64, v float64) error {
+	bits := math.Float64bits(v)
+	if e.n == 0 {
+		e.w.writeBits(uint64(t), 64)
+		e.w.writeBits(bits, 64)
+		e.t, e.v = t, bits
+
+		// No window is open until the first non-zero XOR.
+		e.leading, e.trailing = 64, 0
+		e.n += 1
+		return nil
+	}
+
+	delta := t - e.t
+	e.writeTimestamp(delta - e.delta)
+	e.writeValue(bits ^ e.v)
+
+	e.t, e.delta, e.v = t, delta, bits
+	e.n += 1
+	return nil
+}
+
+// WriteTime adds the point with time t and value v to the encoder.
+func (e *Encoder) WriteTime(t time.Time, v float64) error {
+	return e.Write(t.UnixNano(), v)
+}
+
+func (e *Encoder) writeTimestamp(dod int64) {
+	switch {
+	case dod == 0:
+		e.w.writeBit(false)
+	case -63 <= dod && dod <= 64:
+		e.w.writeBits(0x2, 2)
+		e.w.writeBits(uint64(dod), 7)
+	case -255 <= dod && dod <= 256:
+		e.w.writeBits(0x6, 3)
+		e.w.writeBits(uint64(dod), 9)
+	case -2047 <= dod && dod <= 2048:
+		e.w.writeBits(0xe, 4)
+		e.w.writeBits(uint64(dod), 12)
+	default:
+		e.w.writeBits(0xf, 4)
+		e.w.writeBits(uint64(dod), 64)
+	}
+}
+
+func (e *Encoder) writeValue(x uint64) {
+	if x == 0 {
+		e.w.writeBit(false)
+		return
+	}
+
+	leading := uint(bits.LeadingZeros64(x))
+	trailing := uint(bits.TrailingZeros64(x))
+
+	// Only 5 bits are available for the leading zeros.
+	if leading > 31 {
+		leading = 31
+	}
+
+	if e.leading <= leading && e.trailing <= trailing {
+		e.w.writeBits(0x2, 2)
+		e.w.writeBits(x>>e.trailing, 64-e.leading-e.trailing)
+		return
+	}
+
+	e.leading, e.trailing = leading, trailing
+	sigbits := 64 - leading - trailing
+	e.w.writeBits(0x3, 2)
+	e.w.writeBits(uint64(leading), 5)
+	e.w.writeBits(uint64(sigbits), 6)
+	e.w.writeBits(x>>trailing, sigbits)
+}
+
+// Bytes returns the encoded points written to the encoder.  Points may continue to
+// be written after calling Bytes.
+func (e *Encoder) Bytes() ([]byte, error) {
+	var b [binary.MaxVarintLen64]byte
+	e.out = append(e.out[:0], b[:binary.PutUvarint(b[:], uint64(e.n))]...)
+	e.out = append(e.out, e.w.b...)
+	return e.out, nil
+}
+
+// Decoder iterates over the points of a compressed byte slice.
+type Decoder struct {
+	r bitReader
+
+	// number of points remaining
+	remaining int
+
+	// current timestamp, delta and value
+	t     int64
+	delta int64
+	v     uint64
+
+	// window of meaningful bits
+	leading  uint
+	trailing uint
+
+	first bool
+	err   error
+}
+
+// NewDecoder returns a Decoder from a byte slice
+func NewDecoder(b []byte) *Decoder {
+	d := &Decoder{}
+	d.SetBytes(b)
+	return d
+}
+
+// SetBytes resets the decoder to read from b.
+func (d *Decoder) SetBytes(b []byte) {
+	d.err = nil
+	d.first = true
+	d.delta = 0
+
+	count, b, err := readHeader(b)
+	if err != nil {
+		d.r.reset(nil)
+		d.remaining = 0
+		d.err = err
+		return
+	}
+	d.r.reset(b)
+	d.remaining = count
+}
+
+// Next returns true if there are remaining points to be read.  Successive calls to
+// Next advance the current point.
+func (d *Decoder) Next() bool {
+	if d.err != nil || d.remaining == 0 {
+		return false
+	}
+
+	if d.first {
+		d.first = false
+		d.err = d.readFirst()
+	} else {
+		d.err = d.readNext()
+	}
+	if d.err != nil {
+		return false
+	}
+
+	d.remaining -= 1
+	return true
+}
+
+// Read returns the timestamp and value of the current point.  Successive calls to
+// Read return the same point.
+func (d *Decoder) Read() (int64, float64) {
+	return d.t, math.Float64frombits(d.v)
+}
+
+// ReadTime returns the time and value of the current point.
+func (d *Decoder) ReadTime() (time.Time, float64) {
+	return time.Unix(0, d.t), math.Float64frombits(d.v)
+}
+
+// Err returns the first error encountered while decoding.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+func (d *Decoder) readFirst() error {
+	t, err := d.r.readBits(64)
+	if err != nil {
+		return err
+	}
+	v, err := d.r.readBits(64)
+	if err != nil {
+		return err
+	}
+
+	d.t, d.v = int64(t), v
+	d.leading, d.trailing = 64, 0
+	return nil
+}
+
+func (d *Decoder) readNext() error {
+	dod, err := d.readTimestamp()
+	if err != nil {
+		return err
+	}
+	d.delta += dod
+	d.t += d.delta
+
+	return d.readValue()
+}
+
+func (d *Decoder) readTimestamp() (int64, error) {
+	// Count the leading ones of the prefix, up to 4.
+	var ones int
+	for ones < 4 {
+		bit, err := d.r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+		ones++
+	}
+
+	var n uint
+	switch ones {
+	case 0:
+		return 0, nil
+	case 1:
+		n = 7
+	case 2:
+		n = 9
+	case 3:
+		n = 12
+	default:
+		v, err := d.r.readBits(64)
+		return int64(v), err
+	}
+
+	v, err := d.r.readBits(n)
+	if err != nil {
+		return 0, err
+	}
+
+	// Values above the range of the bucket are negative.
+	if v > 1<<(n-1) {
+		return int64(v) - 1<<n, nil
+	}
+	return int64(v), nil
+}
+
+func (d *Decoder) readValue() error {
+	bit, err := d.r.readBit()
+	if err != nil || !bit {
+		return err
+	}
+
+	if bit, err = d.r.readBit(); err != nil {
+		return err
+	}
+
+	if bit {
+		leading, err := d.r.readBits(5)
+		if err != nil {
+			return err
+		}
+		sigbits, err := d.r.readBits(6)
+		if err != nil {
+			return err
+		}
+		if sigbits == 0 {
+			sigbits = 64
+		}
+		if leading+sigbits > 64 {
+			return fmt.Errorf("invalid window: %d leading zeros, %d bits", leading, sigbits)
+		}
+		d.leading, d.trailing = uint(leading), uint(64-leading-sigbits)
+	} else if d.leading == 64 {
+		return fmt.Errorf("value uses window before one is set")
+	}
+
+	x, err := d.r.readBits(64 - d.leading - d.trailing)
+	if err != nil {
+		return err
+	}
+	d.v ^= x << d.trailing
+	return nil
+}
+
+// EncodeAll returns the compressed form of the points with timestamps ts and values
+// vs.  An error is returned if ts and vs differ in length.
+func EncodeAll(ts []int64, vs []float64) ([]byte, error) {
+	if len(ts) != len(vs) {
+		return nil, fmt.Errorf("length mismatch: %d timestamps, %d values", len(ts), len(vs))
+	}
+
+	enc := NewEncoder()
+	for i, t := range ts {
+		if err := enc.Write(t, vs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return enc.Bytes()
+}
+
+// DecodeAll writes the timestamps and values of the points in src to ts and vs.  It
+// returns the number of points written or an error.
+func DecodeAll(ts []int64, vs []float64, src []byte) (int, error) {
+	count, _, err := readHeader(src)
+	if err != nil {
+		return 0, err
+	}
+	if len(ts) < count || len(vs) < count {
+		return 0, fmt.Errorf("dst too small: need %d points, have %d and %d", count, len(ts), len(vs))
+	}
+
+	d := NewDecoder(src)
+	i := 0
+	for d.Next() {
+		ts[i], vs[i] = d.Read()
+		i++
+	}
+	if d.Err() != nil {
+		return 0, d.Err()
+	}
+	return i, nil
+}
+
+// CountBytes returns the number of points encoded in the byte slice
+func CountBytes(b []byte) (int, error) {
+	count, _, err := readHeader(b)
+	return count, err
+}
+
+// readHeader returns the point count from b and the remaining bytes.
+func readHeader(b []byte) (count int, rest []byte, err error) {
+	if len(b) == 0 {
+		return 0, nil, nil
+	}
+
+	v, n := binary.Uvarint(b)
+	if n <= 0 || v > math.MaxInt32 {
+		return 0, nil, fmt.Errorf("invalid point count")
+	}
+	return int(v), b[n:], nil
+}
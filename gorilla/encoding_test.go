@@ -0,0 +1,254 @@
+package gorilla_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/jwilder/encoding/gorilla"
+)
+
+func testRoundTrip(t *testing.T, ts []int64, vs []float64) []byte {
+	b, err := gorilla.EncodeAll(ts, vs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n, err := gorilla.CountBytes(b); err != nil || n != len(ts) {
+		t.Fatalf("Count mismatch: got %v %v, exp %v", n, err, len(ts))
+	}
+
+	gotTs := make([]int64, len(ts))
+	gotVs := make([]float64, len(vs))
+	n, err := gorilla.DecodeAll(gotTs, gotVs, b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(ts) {
+		t.Fatalf("Len mismatch: got %v, exp %v", n, len(ts))
+	}
+	for i := range ts {
+		if gotTs[i] != ts[i] {
+			t.Fatalf("Timestamp[%d] != %v, got %v", i, ts[i], gotTs[i])
+		}
+		if math.Float64bits(gotVs[i]) != math.Float64bits(vs[i]) {
+			t.Fatalf("Value[%d] != %v, got %v", i, vs[i], gotVs[i])
+		}
+	}
+	return b
+}
+
+func Test_Encode_NoValues(t *testing.T) {
+	testRoundTrip(t, nil, nil)
+}
+
+func Test_Encode_Regular(t *testing.T) {
+	ts := make([]int64, 1000)
+	vs := make([]float64, 1000)
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+	for i := range ts {
+		ts[i] = start + int64(i)*int64(10*time.Second)
+		vs[i] = 12
+	}
+
+	b := testRoundTrip(t, ts, vs)
+
+	// After the first point and first delta, regular timestamps and repeated values
+	// use 2 bits per point.
+	if exp := 2 + (128+68+1+998*2+7)/8; len(b) != exp {
+		t.Fatalf("Encoded len mismatch: got %v, exp %v", len(b), exp)
+	}
+}
+
+func Test_Encode_Buckets(t *testing.T) {
+	// Each delta-of-delta bucket at its boundaries, plus a large jump.
+	ts := []int64{0, 10, 20}
+	for _, dod := range []int64{-63, 64, -255, 256, -2047, 2048, 1 << 40, -1 << 40, 0} {
+		n := len(ts)
+		ts = append(ts, ts[n-1]+(ts[n-1]-ts[n-2])+dod)
+	}
+	ts = append(ts, math.MaxInt64, math.MinInt64)
+
+	vs := make([]float64, len(ts))
+	for i := range vs {
+		vs[i] = float64(i)
+	}
+	testRoundTrip(t, ts, vs)
+}
+
+func Test_Encode_Values(t *testing.T) {
+	vs := []float64{
+		0, -0, 1, 1, 1.5, -1.5, math.Pi, math.MaxFloat64, math.SmallestNonzeroFloat64,
+		math.Inf(1), math.Inf(-1), math.NaN(), 0, 1e-300, 3,
+	}
+	ts := make([]int64, len(vs))
+	for i := range ts {
+		ts[i] = int64(i)
+	}
+	testRoundTrip(t, ts, vs)
+}
+
+func Test_Encode_Random(t *testing.T) {
+	ts := make([]int64, 5000)
+	vs := make([]float64, 5000)
+	var tm int64 = 1e18
+	for i := range ts {
+		tm += rand.Int63n(1e9)
+		ts[i] = tm
+		vs[i] = math.Round(rand.NormFloat64()*1000) / 100
+	}
+	testRoundTrip(t, ts, vs)
+}
+
+func Test_Encoder_Decoder(t *testing.T) {
+	enc := gorilla.NewEncoder()
+	start := time.Unix(1500000000, 0)
+	for i := 0; i < 100; i++ {
+		if err := enc.WriteTime(start.Add(time.Duration(i)*time.Minute), float64(i)/2); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	b, err := enc.Bytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := gorilla.NewDecoder(b)
+	i := 0
+	for dec.Next() {
+		tm, v := dec.ReadTime()
+		if !tm.Equal(start.Add(time.Duration(i) * time.Minute)) {
+			t.Fatalf("Time[%d] mismatch: got %v", i, tm)
+		}
+		if v != float64(i)/2 {
+			t.Fatalf("Value[%d] != %v, got %v", i, float64(i)/2, v)
+		}
+		i++
+	}
+	if dec.Err() != nil {
+		t.Fatalf("Unexpected error: %v", dec.Err())
+	}
+	if i != 100 {
+		t.Fatalf("Len mismatch: got %v, exp 100", i)
+	}
+
+	enc.Reset()
+	enc.Write(1, 2)
+	b, _ = enc.Bytes()
+	dec.SetBytes(b)
+	if !dec.Next() {
+		t.Fatalf("Expected value after reset")
+	}
+	if tm, v := dec.Read(); tm != 1 || v != 2 || dec.Next() {
+		t.Fatalf("Read mismatch after reset: got %v %v", tm, v)
+	}
+}
+
+func Test_Decode_Truncated(t *testing.T) {
+	ts := []int64{1, 2, 3, 100, 1000}
+	vs := []float64{1, 2.5, 3, 1e10, -4}
+	b, _ := gorilla.EncodeAll(ts, vs)
+
+	for n := 1; n < len(b); n++ {
+		if _, err := gorilla.DecodeAll(make([]int64, 5), make([]float64, 5), b[:n]); err == nil {
+			t.Fatalf("Expected error for input truncated to %d bytes, got nil", n)
+		}
+	}
+}
+
+func Test_EncodeAll_LengthMismatch(t *testing.T) {
+	if _, err := gorilla.EncodeAll([]int64{1}, nil); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	f.Add([]byte(nil))
+	for _, n := range []int{1, 2, 100} {
+		ts := make([]int64, n)
+		vs := make([]float64, n)
+		for i := range ts {
+			ts[i] = 1500000000 + int64(i)*10
+			vs[i] = float64(i%7) * 1.5
+		}
+		encoded, _ := gorilla.EncodeAll(ts, vs)
+		f.Add(encoded)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := gorilla.CountBytes(b)
+		if err != nil || n > 1<<20 {
+			return
+		}
+
+		ts := make([]int64, n)
+		vs := make([]float64, n)
+		got, err := gorilla.DecodeAll(ts, vs, b)
+		if err != nil {
+			// The count is read from the header, so the points may still be invalid.
+			dec := gorilla.NewDecoder(b)
+			for dec.Next() {
+			}
+			if dec.Err() == nil {
+				t.Fatalf("Expected Decoder error, got nil")
+			}
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAll mismatch: got %v, exp %v", got, n)
+		}
+
+		dec := gorilla.NewDecoder(b)
+		i := 0
+		for dec.Next() {
+			tt, v := dec.Read()
+			if i >= n || tt != ts[i] || math.Float64bits(v) != math.Float64bits(vs[i]) {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	ts := make([]int64, 1024)
+	vs := make([]float64, 1024)
+	for i := range ts {
+		ts[i] = int64(i) * int64(time.Second)
+		vs[i] = float64(i % 17)
+	}
+
+	enc := gorilla.NewEncoder()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enc.Reset()
+		for j := range ts {
+			enc.Write(ts[j], vs[j])
+		}
+		enc.Bytes()
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	ts := make([]int64, 1024)
+	vs := make([]float64, 1024)
+	for i := range ts {
+		ts[i] = int64(i) * int64(time.Second)
+		vs[i] = float64(i % 17)
+	}
+	buf, _ := gorilla.EncodeAll(ts, vs)
+
+	dec := gorilla.NewDecoder(buf)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dec.SetBytes(buf)
+		for dec.Next() {
+			dec.Read()
+		}
+	}
+}
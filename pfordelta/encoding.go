@@ -0,0 +1,493 @@
+// Package pfordelta implements the Patched Frame-of-Reference with delta (PFORDelta)
+// encoding as described by Zukowski, Heman, Nes and Boncz in "Super-Scalar RAM-CPU
+// Cache Compression", ICDE 2006, with the exception layout of Yan, Ding and Suel's
+// NewPFD from "Inverted Index Compression and Query Processing with Optimized
+// Document Ordering", WWW 2009.
+//
+// Values are delta encoded and split into blocks of up to BlockSize deltas.  Each
+// block picks a single bit width b and stores the low b bits of every delta in a
+// tightly packed frame.  Deltas that do not fit in b bits are exceptions: their
+// positions and high bits are stored after the frame using simple8b, which lets a
+// few large outliers be patched in without widening the rest of the block.
+package pfordelta
+
+// Each block is encoded as a sequence of 64bit words:
+//
+// ┌────────────┬─────────────────────┬──────────────────────┬──────────────────────┐
+// │   Header   │        Frame        │ Exception Positions  │   Exception Values   │
+// ├────────────┼─────────────────────┼──────────────────────┼──────────────────────┤
+// │   1 word   │  ⌈n × b / 64⌉ words │    simple8b words    │    simple8b words    │
+// └────────────┴─────────────────────┴──────────────────────┴──────────────────────┘
+//
+// The header stores the bit width b in bits 0-6, the number of values n in bits
+// 8-15 and the number of exceptions e in bits 16-23.  Exception positions are
+// stored as gaps from the previous exception and exception values hold the bits
+// of the delta above b.
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+// BlockSize is the maximum number of values encoded in a single block.
+const BlockSize = 128
+
+// Encoder converts a stream of unsigned 64bit integers to a compressed byte slice.
+type Encoder struct {
+	// most recently written deltas that have not been flushed
+	buf []uint64
+
+	// the last value written, used to compute the next delta
+	prev uint64
+
+	// scratch space used to encode a block
+	words []uint64
+
+	// current bytes written and flushed
+	bytes []byte
+}
+
+// NewEncoder returns an Encoder able to convert uint64s to compressed byte slices
+func NewEncoder() *Encoder {
+	return &Encoder{
+		buf:   make([]uint64, 0, BlockSize),
+		words: make([]uint64, 0, 2*BlockSize+1),
+		bytes: make([]byte, 0, 128),
+	}
+}
+
+// Reset clears the encoder so it can be reused.
+func (e *Encoder) Reset() {
+	e.buf = e.buf[:0]
+	e.prev = 0
+	e.bytes = e.bytes[:0]
+}
+
+// Write adds v to the encoder.
+func (e *Encoder) Write(v uint64) error {
+	if len(e.buf) == BlockSize {
+		if err := e.flush(); err != nil {
+			return err
+		}
+	}
+
+	e.buf = append(e.buf, v-e.prev)
+	e.prev = v
+	return nil
+}
+
+func (e *Encoder) flush() error {
+	if len(e.buf) == 0 {
+		return nil
+	}
+
+	e.words = encodeBlock(e.words[:0], e.buf)
+	for _, w := range e.words {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], w)
+		e.bytes = append(e.bytes, b[:]...)
+	}
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// Bytes returns the encoded values written to the encoder.
+func (e *Encoder) Bytes() ([]byte, error) {
+	if err := e.flush(); err != nil {
+		return nil, err
+	}
+
+	return e.bytes, nil
+}
+
+// Decoder converts a compressed byte slice to a stream of unsigned 64bit integers.
+type Decoder struct {
+	bytes []byte
+	words []uint64
+	buf   [BlockSize]uint64
+	prev  uint64
+	i     int
+	n     int
+	err   error
+}
+
+// NewDecoder returns a Decoder from a byte slice
+func NewDecoder(b []byte) *Decoder {
+	d := &Decoder{}
+	d.SetBytes(b)
+	return d
+}
+
+// SetBytes resets the decoder to read from b.
+func (d *Decoder) SetBytes(b []byte) {
+	d.bytes = b
+	d.prev = 0
+	d.i = 0
+	d.n = 0
+	d.err = nil
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *Decoder) Next() bool {
+	d.i += 1
+
+	if d.i >= d.n {
+		d.read()
+	}
+
+	return d.i < d.n
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (d *Decoder) Read() uint64 {
+	return d.buf[d.i]
+}
+
+// Err returns the first error encountered while decoding.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+func (d *Decoder) read() {
+	d.i = 0
+	d.n = 0
+	if d.err != nil || len(d.bytes) < 8 {
+		return
+	}
+
+	size, err := blockLen(d.bytes)
+	if err != nil {
+		d.err = err
+		return
+	}
+
+	d.words = d.words[:0]
+	for i := 0; i < size; i++ {
+		d.words = append(d.words, binary.BigEndian.Uint64(d.bytes[i*8:]))
+	}
+	d.bytes = d.bytes[size*8:]
+
+	n, _, err := decodeBlock(d.buf[:], d.words)
+	if err != nil {
+		d.err = err
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		d.prev += d.buf[i]
+		d.buf[i] = d.prev
+	}
+	d.n = n
+}
+
+// EncodeAll returns a packed slice of the values from src.
+func EncodeAll(src []uint64) ([]uint64, error) {
+	deltas := make([]uint64, BlockSize)
+	dst := make([]uint64, 0, len(src)/2+1)
+
+	var prev uint64
+	for len(src) > 0 {
+		n := BlockSize
+		if len(src) < n {
+			n = len(src)
+		}
+
+		for i, v := range src[:n] {
+			deltas[i] = v - prev
+			prev = v
+		}
+		dst = encodeBlock(dst, deltas[:n])
+		src = src[n:]
+	}
+	return dst, nil
+}
+
+// DecodeAll writes the uncompressed values from src to dst.  It returns the number
+// of values written or an error.
+func DecodeAll(dst, src []uint64) (int, error) {
+	var prev uint64
+	j := 0
+	for len(src) > 0 {
+		n, size, err := decodeBlock(dst[j:], src)
+		if err != nil {
+			return 0, err
+		}
+
+		for i := j; i < j+n; i++ {
+			prev += dst[i]
+			dst[i] = prev
+		}
+		j += n
+		src = src[size:]
+	}
+	return j, nil
+}
+
+// CountBytes returns the number of integers encoded in the byte slice
+func CountBytes(b []byte) (int, error) {
+	var count int
+	for len(b) >= 8 {
+		size, err := blockLen(b)
+		if err != nil {
+			return 0, err
+		}
+
+		count += int(binary.BigEndian.Uint64(b) >> 8 & 0xff)
+		b = b[size*8:]
+	}
+
+	if len(b) > 0 {
+		return 0, fmt.Errorf("invalid slice len remaining: %v", len(b))
+	}
+	return count, nil
+}
+
+// header returns the bit width, value count and exception count of a block.
+func header(v uint64) (bits, n, exceptions int, err error) {
+	bits = int(v & 0x7f)
+	n = int(v >> 8 & 0xff)
+	exceptions = int(v >> 16 & 0xff)
+	if bits > 64 || n == 0 || n > BlockSize || exceptions > n || v>>24 != 0 {
+		return 0, 0, 0, fmt.Errorf("invalid block header: %x", v)
+	}
+	return bits, n, exceptions, nil
+}
+
+// blockLen returns the number of words used by the block at the start of b.
+func blockLen(b []byte) (int, error) {
+	bits, n, exceptions, err := header(binary.BigEndian.Uint64(b))
+	if err != nil {
+		return 0, err
+	}
+
+	size := 1 + frameLen(n, bits)
+	if len(b) < size*8 {
+		return 0, fmt.Errorf("block truncated: need %d bytes, have %d", size*8, len(b))
+	}
+
+	// Exception positions and values are each followed by enough simple8b
+	// words to hold all exceptions.
+	for k := 0; k < 2; k++ {
+		for c := 0; c < exceptions; {
+			if len(b) < (size+1)*8 {
+				return 0, fmt.Errorf("block truncated: need %d bytes, have %d", (size+1)*8, len(b))
+			}
+			m, err := simple8b.Count(binary.BigEndian.Uint64(b[size*8:]))
+			if err != nil {
+				return 0, err
+			}
+			c += m
+			size++
+		}
+	}
+	return size, nil
+}
+
+// frameLen returns the number of words needed to pack n values using bits per value.
+func frameLen(n, bits int) int {
+	return (n*bits + 63) / 64
+}
+
+// encodeBlock appends the encoded form of src to dst.  src must contain between 1
+// and BlockSize values.
+func encodeBlock(dst []uint64, src []uint64) []uint64 {
+	bits := bitWidth(src)
+
+	var positions, values []uint64
+	last := 0
+	for i, v := range src {
+		if bits < 64 && v>>uint(bits) != 0 {
+			positions = append(positions, uint64(i-last))
+			values = append(values, v>>uint(bits))
+			last = i
+		}
+	}
+
+	dst = append(dst, uint64(bits)|uint64(len(src))<<8|uint64(len(positions))<<16)
+	dst = pack(dst, src, bits)
+
+	// Positions are gaps from the previous exception, which are always within
+	// range of simple8b.  The bit width guarantees the values are as well.
+	positions, _ = simple8b.EncodeAll(positions)
+	values, _ = simple8b.EncodeAll(values)
+	dst = append(dst, positions...)
+	return append(dst, values...)
+}
+
+// decodeBlock decodes the block at the start of src into dst.  It returns the number
+// of values decoded and the number of words of src consumed.
+func decodeBlock(dst []uint64, src []uint64) (n, size int, err error) {
+	bits, n, exceptions, err := header(src[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	size = 1 + frameLen(n, bits)
+	if len(src) < size {
+		return 0, 0, fmt.Errorf("block truncated: need %d words, have %d", size, len(src))
+	}
+	if len(dst) < n {
+		return 0, 0, fmt.Errorf("dst too small: need %d values, have %d", n, len(dst))
+	}
+	unpack(dst[:n], src[1:size], bits)
+
+	if exceptions == 0 {
+		return n, size, nil
+	}
+
+	var positions, values [BlockSize]uint64
+	m, err := decodeExceptions(positions[:], src[size:], exceptions)
+	if err != nil {
+		return 0, 0, err
+	}
+	size += m
+
+	m, err = decodeExceptions(values[:], src[size:], exceptions)
+	if err != nil {
+		return 0, 0, err
+	}
+	size += m
+
+	pos := 0
+	for i := 0; i < exceptions; i++ {
+		pos += int(positions[i])
+		if pos >= n {
+			return 0, 0, fmt.Errorf("exception position out of range: %d", pos)
+		}
+		dst[pos] |= values[i] << uint(bits)
+	}
+	return n, size, nil
+}
+
+// decodeExceptions decodes simple8b words from src into dst until n values have been
+// read.  It returns the number of words consumed.
+func decodeExceptions(dst []uint64, src []uint64, n int) (int, error) {
+	var buf [240]uint64
+	j, i := 0, 0
+	for j < n {
+		if i >= len(src) {
+			return 0, fmt.Errorf("exceptions truncated: need %d values, have %d", n, j)
+		}
+
+		m, err := simple8b.Decode(&buf, src[i])
+		if err != nil {
+			return 0, err
+		}
+		if j+m > n {
+			return 0, fmt.Errorf("exceptions overflow: need %d values, have %d", n, j+m)
+		}
+		copy(dst[j:], buf[:m])
+		j += m
+		i++
+	}
+	return i, nil
+}
+
+// bitWidth returns the bit width that minimizes the estimated size of src once
+// exceptions are accounted for.
+func bitWidth(src []uint64) int {
+	var max uint64
+	for _, v := range src {
+		max |= v
+	}
+
+	// Exception values are stored using simple8b so the high bits must fit
+	// within simple8b.MaxValue.
+	width := bitLen(max)
+	min := width - 60
+	if min < 0 {
+		min = 0
+	}
+
+	best, bestSize := width, frameLen(len(src), width)*64
+	for bits := min; bits < width; bits++ {
+		size := frameLen(len(src), bits) * 64
+		for _, v := range src {
+			if high := v >> uint(bits); high != 0 {
+				// Each exception costs its high bits plus roughly a byte
+				// for its position.
+				size += bitLen(high) + 8
+			}
+		}
+
+		if size < bestSize {
+			best, bestSize = bits, size
+		}
+	}
+	return best
+}
+
+// bitLen returns the number of bits required to store v.
+func bitLen(v uint64) int {
+	n := 0
+	for ; v != 0; v >>= 1 {
+		n++
+	}
+	return n
+}
+
+// pack appends the low bits of each value in src to dst, using bits per value.
+func pack(dst []uint64, src []uint64, bits int) []uint64 {
+	if bits == 0 {
+		return dst
+	}
+
+	mask := uint64(1)<<uint(bits) - 1
+	if bits == 64 {
+		mask = ^uint64(0)
+	}
+
+	var w uint64
+	var used uint
+	for _, v := range src {
+		v &= mask
+		w |= v << used
+		used += uint(bits)
+		if used >= 64 {
+			dst = append(dst, w)
+			used -= 64
+			w = 0
+			if used > 0 {
+				w = v >> (uint(bits) - used)
+			}
+		}
+	}
+
+	if used > 0 {
+		dst = append(dst, w)
+	}
+	return dst
+}
+
+// unpack fills dst with values of bits width from src.
+func unpack(dst []uint64, src []uint64, bits int) {
+	if bits == 0 {
+		for i := range dst {
+			dst[i] = 0
+		}
+		return
+	}
+
+	mask := uint64(1)<<uint(bits) - 1
+	if bits == 64 {
+		mask = ^uint64(0)
+	}
+
+	var used uint
+	j := 0
+	for i := range dst {
+		v := src[j] >> used
+		used += uint(bits)
+		if used >= 64 {
+			j++
+			used -= 64
+			if used > 0 {
+				v |= src[j] << (uint(bits) - used)
+			}
+		}
+		dst[i] = v & mask
+	}
+}
@@ -0,0 +1,230 @@
+package pfordelta_test
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/jwilder/encoding/pfordelta"
+)
+
+func Test_Encode_NoValues(t *testing.T) {
+	var in []uint64
+	encoded, _ := pfordelta.EncodeAll(in)
+
+	decoded := make([]uint64, len(in))
+	n, _ := pfordelta.DecodeAll(decoded, encoded)
+
+	if len(in) != len(decoded[:n]) {
+		t.Fatalf("Len mismatch: got %v, exp %v", len(decoded), len(in))
+	}
+}
+
+func Test_Encode_Sorted(t *testing.T) {
+	in := make([]uint64, 1000)
+	for i := range in {
+		in[i] = uint64(i * 3)
+	}
+	testEncode(t, in)
+}
+
+func Test_Encode_Outliers(t *testing.T) {
+	in := make([]uint64, 300)
+	var v uint64
+	for i := range in {
+		v += uint64(i % 7)
+		if i%50 == 0 {
+			v += 1 << 40
+		}
+		in[i] = v
+	}
+	testEncode(t, in)
+
+	encoded, _ := pfordelta.EncodeAll(in)
+	// 300 deltas of 3 bits with 6 outliers should be far smaller than
+	// one word per outlier block.
+	if got, max := len(encoded), 30; got > max {
+		t.Fatalf("Encoded len too large: got %v, exp <= %v", got, max)
+	}
+}
+
+func Test_Encode_Unsorted(t *testing.T) {
+	rand.Seed(1)
+	in := make([]uint64, 513)
+	for i := range in {
+		in[i] = rand.Uint64()
+	}
+	testEncode(t, in)
+}
+
+func Test_Encode_Max(t *testing.T) {
+	testEncode(t, []uint64{^uint64(0), 0, ^uint64(0), 1, 2})
+}
+
+func Test_Encode_Zeros(t *testing.T) {
+	testEncode(t, make([]uint64, 250))
+}
+
+func Test_Decode_Truncated(t *testing.T) {
+	enc := pfordelta.NewEncoder()
+	for i := 0; i < 100; i++ {
+		enc.Write(uint64(i * 1000))
+	}
+	b, _ := enc.Bytes()
+
+	if _, err := pfordelta.CountBytes(b[:len(b)-8]); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+
+	dec := pfordelta.NewDecoder(b[:len(b)-8])
+	for dec.Next() {
+	}
+	if dec.Err() == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func testEncode(t *testing.T, in []uint64) {
+	enc := pfordelta.NewEncoder()
+	for _, v := range in {
+		enc.Write(v)
+	}
+
+	encoded, err := enc.Bytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := pfordelta.NewDecoder(encoded)
+	i := 0
+	for dec.Next() {
+		if i >= len(in) {
+			t.Fatalf("Decoded too many values: got %v, exp %v", i, len(in))
+		}
+
+		if dec.Read() != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], dec.Read())
+		}
+		i += 1
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if exp, got := len(in), i; got != exp {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", exp, got)
+	}
+
+	got, err := pfordelta.CountBytes(encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error in Count: %v", err)
+	}
+	if got != len(in) {
+		t.Fatalf("Count mismatch: got %v, exp %v", got, len(in))
+	}
+
+	words, err := pfordelta.EncodeAll(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp, got := len(encoded), len(words)*8; got != exp {
+		t.Fatalf("EncodeAll len mismatch: exp %v, got %v", exp, got)
+	}
+
+	decoded := make([]uint64, len(in))
+	n, err := pfordelta.DecodeAll(decoded, words)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("DecodeAll len mismatch: exp %v, got %v", len(in), n)
+	}
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("DecodeAll[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	for _, in := range [][]uint64{nil, {1, 2, 3}, {5, 1 << 40, 7, 9, 11}, make([]uint64, 300)} {
+		encoded, _ := pfordelta.EncodeAll(in)
+		b := make([]byte, 8*len(encoded))
+		for i, w := range encoded {
+			binary.BigEndian.PutUint64(b[i*8:], w)
+		}
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := pfordelta.CountBytes(b)
+		if err != nil {
+			return
+		}
+		src := make([]uint64, len(b)/8)
+		for i := range src {
+			src[i] = binary.BigEndian.Uint64(b[i*8:])
+		}
+
+		dst := make([]uint64, n)
+		got, err := pfordelta.DecodeAll(dst, src)
+		if err != nil {
+			dec := pfordelta.NewDecoder(b)
+			for dec.Next() {
+			}
+			if dec.Err() == nil {
+				t.Fatalf("Expected Decoder error, got nil")
+			}
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAll mismatch: got %v, exp %v", got, n)
+		}
+		if n > 0 {
+			if _, err := pfordelta.DecodeAll(make([]uint64, n-1), src); err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+		}
+
+		dec := pfordelta.NewDecoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
+
+func BenchmarkEncode(b *testing.B) {
+	x := make([]uint64, 1024)
+	for i := 0; i < len(x); i++ {
+		x[i] = uint64(i * 10)
+	}
+
+	b.ResetTimer()
+	b.SetBytes(int64(len(x) * 8))
+	for i := 0; i < b.N; i++ {
+		pfordelta.EncodeAll(x)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	x := make([]uint64, 1024)
+	for i := 0; i < len(x); i++ {
+		x[i] = uint64(i * 10)
+	}
+	y, _ := pfordelta.EncodeAll(x)
+
+	decoded := make([]uint64, len(x))
+
+	b.ResetTimer()
+	b.SetBytes(int64(len(x) * 8))
+	for i := 0; i < b.N; i++ {
+		_, _ = pfordelta.DecodeAll(decoded, y)
+	}
+}
@@ -0,0 +1,207 @@
+// Package postings implements a reader for ascending document IDs stored as
+// simple8b encoded deltas, as found in the postings lists of an inverted index.
+//
+// A sparse skip table records the largest document ID of every block of words,
+// and the ID preceding each word is kept as its base.  Advance gallops through the
+// skip table to the block that may hold a target ID, picks the word within it from
+// the bases and unpacks only that word.  This supports the galloping intersection
+// used to evaluate conjunctive queries.
+package postings
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+// SkipInterval is the number of words covered by each entry in the skip table.
+const SkipInterval = 4
+
+// Encode returns the deltas between the ascending document IDs in ids packed using
+// simple8b.  The first ID is stored relative to zero.  An error is returned if ids is
+// not sorted or a delta exceeds simple8b.MaxValue.
+func Encode(ids []uint64) ([]uint64, error) {
+	deltas := make([]uint64, len(ids))
+	var prev uint64
+	for i, v := range ids {
+		if v < prev {
+			return nil, fmt.Errorf("ids not sorted: %d follows %d", v, prev)
+		}
+		deltas[i] = v - prev
+		prev = v
+	}
+	return simple8b.EncodeAll(deltas)
+}
+
+// Reader iterates over the document IDs of a postings list.
+type Reader struct {
+	words []uint64
+
+	// last document ID of each block of SkipInterval words
+	skips []uint64
+
+	// last document ID before each word, which its first delta is relative to
+	bases []uint64
+
+	// index of the word in buf and the block holding it
+	w     int
+	block int
+
+	// absolute document IDs of word w
+	buf [240]uint64
+	i   int
+	n   int
+}
+
+// NewReader returns a Reader over the words produced by Encode.  The skip table and
+// bases are built with a single pass over the words.
+func NewReader(words []uint64) (*Reader, error) {
+	r := &Reader{
+		words: words,
+		skips: make([]uint64, 0, len(words)/SkipInterval+1),
+		bases: make([]uint64, len(words)),
+	}
+
+	var buf [240]uint64
+	var id uint64
+	for i, v := range words {
+		r.bases[i] = id
+		if i%SkipInterval == 0 {
+			r.skips = append(r.skips, 0)
+		}
+
+		n, err := simple8b.Decode(&buf, v)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range buf[:n] {
+			id += d
+		}
+		r.skips[len(r.skips)-1] = id
+	}
+
+	r.Reset()
+	return r, nil
+}
+
+// Reset moves the reader back to before the first document ID.
+func (r *Reader) Reset() {
+	r.w = -1
+	r.block = 0
+	r.i = 0
+	r.n = 0
+}
+
+// Next returns true if there are remaining document IDs to be read.  Successive
+// calls to Next advance the current element pointer.
+func (r *Reader) Next() bool {
+	r.i += 1
+
+	if r.i >= r.n {
+		if r.w+1 >= len(r.words) {
+			r.i = r.n
+			return false
+		}
+		r.load(r.w + 1)
+	}
+
+	return true
+}
+
+// Read returns the current document ID.  Successive calls to Read return the same
+// value.
+func (r *Reader) Read() uint64 {
+	return r.buf[r.i]
+}
+
+// Advance moves to the first document ID greater than or equal to target and returns
+// it.  It returns false if there is no such ID.  The reader never moves backwards, so
+// if the current ID is already at least target it is returned unchanged.
+func (r *Reader) Advance(target uint64) (uint64, bool) {
+	if r.i < r.n {
+		if r.buf[r.i] >= target {
+			return r.buf[r.i], true
+		}
+
+		// The target is within the current word.
+		if r.buf[r.n-1] >= target {
+			return r.seek(target), true
+		}
+	}
+
+	b := r.findBlock(target)
+	if b >= len(r.skips) {
+		r.w = len(r.words) - 1
+		r.i, r.n = 0, 0
+		return 0, false
+	}
+
+	w := b * SkipInterval
+	if w <= r.w {
+		w = r.w + 1
+	}
+
+	// The first word whose successor starts at or after target holds an ID at least
+	// target.  Block b ends with such an ID, so the search stays within one block.
+	for w+1 < len(r.words) && r.bases[w+1] < target {
+		w++
+	}
+	if w >= len(r.words) {
+		r.i, r.n = 0, 0
+		return 0, false
+	}
+
+	r.load(w)
+	if r.buf[r.n-1] < target {
+		r.i, r.n = 0, 0
+		return 0, false
+	}
+	return r.seek(target), true
+}
+
+// findBlock returns the first block at or after the current one whose largest ID is
+// at least target, galloping forward through the skip table before a binary search.
+func (r *Reader) findBlock(target uint64) int {
+	lo := r.block
+	if lo >= len(r.skips) || r.skips[lo] >= target {
+		return lo
+	}
+
+	// Double the step until a block reaching target is passed.
+	step := 1
+	hi := lo + step
+	for hi < len(r.skips) && r.skips[hi] < target {
+		lo = hi
+		step *= 2
+		hi = lo + step
+	}
+	if hi > len(r.skips) {
+		hi = len(r.skips)
+	}
+
+	return lo + 1 + sort.Search(hi-lo-1, func(k int) bool {
+		return r.skips[lo+1+k] >= target
+	})
+}
+
+// seek moves to the first ID in the current word at least target, which must exist.
+func (r *Reader) seek(target uint64) uint64 {
+	for r.buf[r.i] < target {
+		r.i++
+	}
+	return r.buf[r.i]
+}
+
+// load decodes word w into buf as absolute document IDs.
+func (r *Reader) load(w int) {
+	base := r.bases[w]
+	n, _ := simple8b.Decode(&r.buf, r.words[w])
+	for i := 0; i < n; i++ {
+		base += r.buf[i]
+		r.buf[i] = base
+	}
+
+	r.w, r.block = w, w/SkipInterval
+	r.i, r.n = 0, n
+}
@@ -0,0 +1,242 @@
+package postings_test
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/jwilder/encoding/postings"
+)
+
+func randomIDs(n int, gap int) []uint64 {
+	ids := make([]uint64, n)
+	var id uint64
+	for i := range ids {
+		id += uint64(rand.Intn(gap))
+		if rand.Intn(50) == 0 {
+			id += 1 << 20
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+func newReader(t *testing.T, ids []uint64) *postings.Reader {
+	words, err := postings.Encode(ids)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	r, err := postings.NewReader(words)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return r
+}
+
+func Test_Reader_Next(t *testing.T) {
+	rand.Seed(1)
+	ids := randomIDs(5000, 20)
+	r := newReader(t, ids)
+
+	i := 0
+	for r.Next() {
+		if r.Read() != ids[i] {
+			t.Fatalf("Read[%d] != %v, got %v", i, ids[i], r.Read())
+		}
+		i++
+	}
+	if i != len(ids) {
+		t.Fatalf("Read len mismatch: exp %v, got %v", len(ids), i)
+	}
+}
+
+func Test_Reader_Advance(t *testing.T) {
+	rand.Seed(1)
+	ids := randomIDs(5000, 20)
+	max := ids[len(ids)-1]
+
+	for k := 0; k < 100; k++ {
+		r := newReader(t, ids)
+		var target uint64
+		for {
+			target += uint64(rand.Int63n(int64(max/20 + 2)))
+
+			j := sort.Search(len(ids), func(i int) bool { return ids[i] >= target })
+			got, ok := r.Advance(target)
+			if j == len(ids) {
+				if ok {
+					t.Fatalf("Advance(%d) expected false, got %v", target, got)
+				}
+				break
+			}
+
+			if !ok || got != ids[j] {
+				t.Fatalf("Advance(%d) != %v, got %v %v", target, ids[j], got, ok)
+			}
+
+			// Mixing Next with Advance continues from the current position.
+			if rand.Intn(2) == 0 && j+1 < len(ids) {
+				if !r.Next() || r.Read() != ids[j+1] {
+					t.Fatalf("Next after Advance(%d) != %v, got %v", target, ids[j+1], r.Read())
+				}
+				target = ids[j+1]
+			}
+		}
+	}
+}
+
+func Test_Reader_Advance_Duplicates(t *testing.T) {
+	// Runs of equal IDs span several words and blocks.
+	var ids []uint64
+	for _, v := range []uint64{5, 9} {
+		for i := 0; i < 1000; i++ {
+			ids = append(ids, v)
+		}
+	}
+	ids = append(ids, 12)
+
+	for _, target := range []uint64{0, 5, 6, 9, 10, 12} {
+		j := sort.Search(len(ids), func(i int) bool { return ids[i] >= target })
+		r := newReader(t, ids)
+		got, ok := r.Advance(target)
+		if !ok || got != ids[j] {
+			t.Fatalf("Advance(%d) != %v, got %v %v", target, ids[j], got, ok)
+		}
+
+		n := 1
+		for r.Next() {
+			n++
+		}
+		if n != len(ids)-j {
+			t.Fatalf("Values after Advance(%d) = %v, exp %v", target, n, len(ids)-j)
+		}
+	}
+
+	r := newReader(t, ids)
+	for r.Next() {
+	}
+	if got, ok := r.Advance(12); ok {
+		t.Fatalf("Advance after end expected false, got %v", got)
+	}
+}
+
+func Test_Reader_Intersect(t *testing.T) {
+	rand.Seed(2)
+	a := randomIDs(20000, 3)
+	b := randomIDs(500, 200)
+
+	var exp []uint64
+	set := make(map[uint64]bool)
+	for _, v := range a {
+		set[v] = true
+	}
+	for i, v := range b {
+		if set[v] && (i == 0 || b[i-1] != v) {
+			exp = append(exp, v)
+		}
+	}
+
+	ra, rb := newReader(t, a), newReader(t, b)
+	var got []uint64
+	for rb.Next() {
+		v := rb.Read()
+		if len(got) > 0 && got[len(got)-1] == v {
+			continue
+		}
+		x, ok := ra.Advance(v)
+		if !ok {
+			break
+		}
+		if x == v {
+			got = append(got, v)
+		}
+	}
+
+	if len(got) != len(exp) {
+		t.Fatalf("Intersect len mismatch: exp %v, got %v", len(exp), len(got))
+	}
+	for i := range exp {
+		if got[i] != exp[i] {
+			t.Fatalf("Intersect[%d] != %v, got %v", i, exp[i], got[i])
+		}
+	}
+}
+
+func Test_Reader_Empty(t *testing.T) {
+	r := newReader(t, nil)
+	if r.Next() {
+		t.Fatalf("Expected Next to return false")
+	}
+	if _, ok := r.Advance(0); ok {
+		t.Fatalf("Expected Advance to return false")
+	}
+}
+
+func Test_Encode_Unsorted(t *testing.T) {
+	if _, err := postings.Encode([]uint64{5, 3}); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	for _, ids := range [][]uint64{nil, {3}, {1, 5, 9, 1000, 1 << 40}} {
+		words, _ := postings.Encode(ids)
+		b := make([]byte, 8*len(words))
+		for i, w := range words {
+			binary.BigEndian.PutUint64(b[i*8:], w)
+		}
+		f.Add(b, uint64(7))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte, target uint64) {
+		words := make([]uint64, len(b)/8)
+		for i := range words {
+			words[i] = binary.BigEndian.Uint64(b[i*8:])
+		}
+		r, err := postings.NewReader(words)
+		if err != nil {
+			return
+		}
+
+		// Overflowing IDs are not sorted, so only check Advance on sorted lists.
+		var ids []uint64
+		sorted := true
+		for r.Next() {
+			if len(ids) > 0 && r.Read() < ids[len(ids)-1] {
+				sorted = false
+			}
+			ids = append(ids, r.Read())
+		}
+
+		r.Reset()
+		got, ok := r.Advance(target)
+		if !sorted {
+			return
+		}
+		i := sort.Search(len(ids), func(i int) bool { return ids[i] >= target })
+		if ok != (i < len(ids)) || (ok && got != ids[i]) {
+			t.Fatalf("Advance(%d) = %v %v, exp index %v of %v", target, got, ok, i, len(ids))
+		}
+	})
+}
+
+func BenchmarkAdvance(b *testing.B) {
+	ids := make([]uint64, 1<<20)
+	for i := range ids {
+		ids[i] = uint64(i * 3)
+	}
+	words, _ := postings.Encode(ids)
+	r, _ := postings.NewReader(words)
+
+	b.ResetTimer()
+	var target uint64
+	for i := 0; i < b.N; i++ {
+		target += 1000
+		if _, ok := r.Advance(target); !ok {
+			r.Reset()
+			target = 0
+		}
+	}
+}
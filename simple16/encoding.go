@@ -0,0 +1,357 @@
+// Package simple16 implements the 32bit integer encoding algorithm as published
+// by Zhang, Long and Suel in "Performance of Compressed Inverted List Caching in
+// Search Engines", WWW 2008.
+//
+// It is capable of encoding multiple integers with values between 0 and 2^28 - 1 in
+// a single word.
+package simple16
+
+// Simple16 is a 32bit word-sized encoder that packs multiple integers into a single
+// word using a 4 bit selector value and 28 bits for the remaining values.  Unlike
+// Simple9, a word may mix bit widths so none of the 28 bits are wasted.  Integers
+// are encoded using the following table, listing the number of values and bits per
+// value of each group from the least significant bits:
+//
+// ┌──────────────┬───────────────────────────┬─────┐
+// │   Selector   │        Values × Bits      │  N  │
+// ├──────────────┼───────────────────────────┼─────┤
+// │       0      │ 28×1                      │  28 │
+// │       1      │ 7×2, 14×1                 │  21 │
+// │       2      │ 7×1, 7×2, 7×1             │  21 │
+// │       3      │ 14×1, 7×2                 │  21 │
+// │       4      │ 14×2                      │  14 │
+// │       5      │ 1×4, 8×3                  │   9 │
+// │       6      │ 1×3, 4×4, 3×3             │   8 │
+// │       7      │ 7×4                       │   7 │
+// │       8      │ 4×5, 2×4                  │   6 │
+// │       9      │ 2×4, 4×5                  │   6 │
+// │      10      │ 3×6, 2×5                  │   5 │
+// │      11      │ 2×5, 3×6                  │   5 │
+// │      12      │ 4×7                       │   4 │
+// │      13      │ 1×10, 2×9                 │   3 │
+// │      14      │ 2×14                      │   2 │
+// │      15      │ 1×28                      │   1 │
+// └──────────────┴───────────────────────────┴─────┘
+//
+// Encoded words are serialized as 4 byte big endian integers.
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const MaxValue = (1 << 28) - 1
+
+// ErrShortBuffer is returned when dst is too small to hold the decoded values.
+var ErrShortBuffer = errors.New("simple16: short buffer")
+
+type packing struct {
+	n    int
+	bits []uint8
+}
+
+var selector [16]packing
+
+func init() {
+	groups := [16][][2]int{
+		{{28, 1}},
+		{{7, 2}, {14, 1}},
+		{{7, 1}, {7, 2}, {7, 1}},
+		{{14, 1}, {7, 2}},
+		{{14, 2}},
+		{{1, 4}, {8, 3}},
+		{{1, 3}, {4, 4}, {3, 3}},
+		{{7, 4}},
+		{{4, 5}, {2, 4}},
+		{{2, 4}, {4, 5}},
+		{{3, 6}, {2, 5}},
+		{{2, 5}, {3, 6}},
+		{{4, 7}},
+		{{1, 10}, {2, 9}},
+		{{2, 14}},
+		{{1, 28}},
+	}
+
+	for i, g := range groups {
+		for _, group := range g {
+			for j := 0; j < group[0]; j++ {
+				selector[i].bits = append(selector[i].bits, uint8(group[1]))
+			}
+		}
+		selector[i].n = len(selector[i].bits)
+	}
+}
+
+// Encoder converts a stream of unsigned 32bit integers to a compressed byte slice.
+type Encoder struct {
+	// most recently written integers that have not been flushed
+	buf []uint32
+
+	// index in buf of the head of the buf
+	h int
+
+	// index in buf of the tail of the buf
+	t int
+
+	// current bytes written and flushed
+	bytes []byte
+}
+
+// NewEncoder returns an Encoder able to convert uint32s to compressed byte slices
+func NewEncoder() *Encoder {
+	return &Encoder{
+		buf:   make([]uint32, 28),
+		bytes: make([]byte, 0, 128),
+	}
+}
+
+// SetValues resets the encoder to encode the values of v, which is used as the
+// buffer of the encoder until the next call to Reset.
+func (e *Encoder) SetValues(v []uint32) {
+	e.buf = v
+	e.t = len(v)
+	e.h = 0
+	e.bytes = e.bytes[:0]
+}
+
+// Reset clears the encoder so it can be reused.
+func (e *Encoder) Reset() {
+	e.t = 0
+	e.h = 0
+
+	e.buf = e.buf[:28]
+	e.bytes = e.bytes[:0]
+}
+
+// Write adds v to the encoder.  A value over MaxValue is reported by the call to
+// Write or Bytes that packs it.
+func (e *Encoder) Write(v uint32) error {
+	if e.t >= len(e.buf) {
+		if err := e.flush(); err != nil {
+			return err
+		}
+	}
+
+	// The buf is full but there is space at the front, just shift
+	// the values down for now.
+	if e.t >= len(e.buf) {
+		copy(e.buf, e.buf[e.h:])
+		e.t -= e.h
+		e.h = 0
+	}
+	e.buf[e.t] = v
+	e.t += 1
+	return nil
+}
+
+func (e *Encoder) flush() error {
+	if e.t == 0 {
+		return nil
+	}
+
+	// encode as many values into one as we can
+	encoded, n, err := Encode(e.buf[e.h:e.t])
+	if err != nil {
+		return err
+	}
+
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], encoded)
+	e.bytes = append(e.bytes, b[:]...)
+
+	// Move the head forward since we encoded those values
+	e.h += n
+
+	// If we encoded them all, reset the head/tail pointers to the beginning
+	if e.h == e.t {
+		e.h = 0
+		e.t = 0
+	}
+
+	return nil
+}
+
+// Bytes returns the packed words of the values written to the encoder.  Every
+// buffered value is flushed, so the last word may not be full.
+func (e *Encoder) Bytes() ([]byte, error) {
+	for e.t > 0 {
+		if err := e.flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.bytes, nil
+}
+
+// Decoder converts a compressed byte slice to a stream of unsigned 32bit integers.
+type Decoder struct {
+	bytes []byte
+	buf   [28]uint32
+	i     int
+	n     int
+	err   error
+}
+
+// NewDecoder returns a Decoder from a byte slice
+func NewDecoder(b []byte) *Decoder {
+	return &Decoder{
+		bytes: b,
+	}
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *Decoder) Next() bool {
+	d.i += 1
+
+	if d.i >= d.n {
+		d.read()
+	}
+
+	return d.err == nil && d.i < d.n
+}
+
+// SetBytes resets the decoder to read from b.
+func (d *Decoder) SetBytes(b []byte) {
+	d.bytes = b
+	d.i = 0
+	d.n = 0
+	d.err = nil
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (d *Decoder) Read() uint32 {
+	return d.buf[d.i]
+}
+
+func (d *Decoder) read() {
+	d.i = 0
+	d.n = 0
+	if len(d.bytes) < 4 {
+		if len(d.bytes) > 0 {
+			d.err = fmt.Errorf("invalid slice len remaining: %v", len(d.bytes))
+		}
+		return
+	}
+
+	v := binary.BigEndian.Uint32(d.bytes[:4])
+	d.bytes = d.bytes[4:]
+	d.n, d.err = Decode(&d.buf, v)
+}
+
+// Err returns the first error encountered while decoding.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// CountBytes returns the number of integers encoded in the byte slice
+func CountBytes(b []byte) (int, error) {
+	var count int
+	for len(b) >= 4 {
+		v := binary.BigEndian.Uint32(b[:4])
+		b = b[4:]
+		n, err := Count(v)
+		if err != nil {
+			return 0, err
+		}
+
+		count += n
+	}
+
+	if len(b) > 0 {
+		return 0, fmt.Errorf("invalid slice len remaining: %v", len(b))
+	}
+	return count, nil
+}
+
+// Count returns the number of integers encoded within an uint32.  All 16 selectors
+// are assigned a packing, so the error is always nil.
+func Count(v uint32) (int, error) {
+	return selector[v>>28].n, nil
+}
+
+// Encode packs as many values into a single uint32.  It returns the packed
+// uint32, how many values from src were packed, or an error if the values exceed
+// the maximum value range.
+func Encode(src []uint32) (value uint32, n int, err error) {
+	for sel := range selector {
+		if canPack(src, selector[sel].bits) {
+			return pack(uint32(sel), src), selector[sel].n, nil
+		}
+	}
+
+	if len(src) > 0 {
+		return 0, 0, fmt.Errorf("value out of bounds: %v", src)
+	}
+	return 0, 0, nil
+}
+
+// Decode writes the uncompressed values in v to dst.  It returns the number of
+// values written.
+func Decode(dst *[28]uint32, v uint32) (n int, err error) {
+	sel := v >> 28
+	var shift uint
+	for i, b := range selector[sel].bits {
+		dst[i] = (v >> shift) & (1<<b - 1)
+		shift += uint(b)
+	}
+	return selector[sel].n, nil
+}
+
+// EncodeAll returns a packed slice of the values from src.  If a value is over
+// 1 << 28, an error is returned.
+func EncodeAll(src []uint32) ([]uint32, error) {
+	dst := make([]uint32, 0, len(src)/2+1)
+	for len(src) > 0 {
+		v, n, err := Encode(src)
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, v)
+		src = src[n:]
+	}
+	return dst, nil
+}
+
+// DecodeAll writes the uncompressed values from src to dst.  ErrShortBuffer is
+// returned if dst cannot hold them.
+func DecodeAll(dst, src []uint32) error {
+	var buf [28]uint32
+	j := 0
+	for _, v := range src {
+		n, _ := Decode(&buf, v)
+		if j+n > len(dst) {
+			return ErrShortBuffer
+		}
+		copy(dst[j:j+n], buf[:n])
+		j += n
+	}
+	return nil
+}
+
+// canPack returns true if the next len(bits) values in src fit within the bits
+// given for each.
+func canPack(src []uint32, bits []uint8) bool {
+	if len(src) < len(bits) {
+		return false
+	}
+
+	for i, b := range bits {
+		if src[i]>>b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// pack packs the values from src using the bit widths of sel.
+func pack(sel uint32, src []uint32) uint32 {
+	v := sel << 28
+	var shift uint
+	for i, b := range selector[sel].bits {
+		v |= src[i] << shift
+		shift += uint(b)
+	}
+	return v
+}
@@ -0,0 +1,233 @@
+package simple16
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/jwilder/encoding/simple9"
+)
+
+func Test_Selectors(t *testing.T) {
+	for sel, p := range selector {
+		total := 0
+		for _, b := range p.bits {
+			total += int(b)
+		}
+		if total != 28 {
+			t.Fatalf("selector %d uses %d bits, exp 28", sel, total)
+		}
+	}
+}
+
+func Test_Selector_Max(t *testing.T) {
+	// Fill every slot of each selector with its largest value.
+	for sel, p := range selector {
+		in := make([]uint32, p.n)
+		for i, b := range p.bits {
+			in[i] = 1<<b - 1
+		}
+
+		v := pack(uint32(sel), in)
+		var out [28]uint32
+		n, _ := Decode(&out, v)
+		if n != p.n {
+			t.Fatalf("Decode len mismatch: exp %v, got %v", p.n, n)
+		}
+		for i := range in {
+			if out[i] != in[i] {
+				t.Fatalf("selector %d: Decoded[%d] != %v, got %v", sel, i, in[i], out[i])
+			}
+		}
+	}
+}
+
+func Test_EncodeAll(t *testing.T) {
+	rand.Seed(1)
+	in := make([]uint32, 5000)
+	for i := range in {
+		in[i] = uint32(rand.Intn(1 << uint(rand.Intn(29))))
+	}
+
+	encoded, err := EncodeAll(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded := make([]uint32, len(in))
+	_ = DecodeAll(decoded, encoded)
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
+func Test_Smaller_Than_Simple9(t *testing.T) {
+	// Mostly 1 bit values with a few 2 bit values fill simple9 words poorly.
+	in := make([]uint32, 2100)
+	for i := range in {
+		in[i] = uint32(i % 2)
+		if i%21 == 0 {
+			in[i] = 3
+		}
+	}
+
+	s16, _ := EncodeAll(in)
+	s9, _ := simple9.EncodeAll(in)
+	if len(s16) >= len(s9) {
+		t.Fatalf("Expected simple16 to be smaller: got %v, simple9 %v", len(s16), len(s9))
+	}
+}
+
+func Test_TooBig(t *testing.T) {
+	if _, err := EncodeAll([]uint32{MaxValue + 1}); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func Test_Encoder(t *testing.T) {
+	enc := NewEncoder()
+	in := make([]uint32, 1000)
+	for i := range in {
+		in[i] = uint32(i*i) % (1 << uint(i%29))
+		if err := enc.Write(in[i]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	b, err := enc.Bytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := NewDecoder(b)
+	i := 0
+	for dec.Next() {
+		if i >= len(in) {
+			t.Fatalf("Decoded too many values: got %v, exp %v", i, len(in))
+		}
+		if dec.Read() != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], dec.Read())
+		}
+		i += 1
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp, got := len(in), i; got != exp {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", exp, got)
+	}
+
+	got, err := CountBytes(b)
+	if err != nil {
+		t.Fatalf("Unexpected error in Count: %v", err)
+	}
+	if got != len(in) {
+		t.Fatalf("Count mismatch: got %v, exp %v", got, len(in))
+	}
+}
+
+func Test_Decoder_TrailingBytes(t *testing.T) {
+	for n := 1; n < 4; n++ {
+		b := append([]byte{0xf0, 0, 0, 0}, make([]byte, n)...)
+		dec := NewDecoder(b)
+		i := 0
+		for dec.Next() {
+			i++
+		}
+		if i != 1 {
+			t.Fatalf("Decode len mismatch: exp 1, got %v", i)
+		}
+		if dec.Err() == nil {
+			t.Fatalf("Expected error for %d trailing bytes, got nil", n)
+		}
+
+		dec.SetBytes(b[:4])
+		for dec.Next() {
+		}
+		if err := dec.Err(); err != nil {
+			t.Fatalf("Unexpected error after SetBytes: %v", err)
+		}
+	}
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	for _, in := range [][]uint32{nil, {1, 2, 3}, {1 << 27, 7, 0, 1 << 20}, make([]uint32, 100)} {
+		encoded, _ := EncodeAll(in)
+		b := make([]byte, 4*len(encoded))
+		for i, w := range encoded {
+			binary.BigEndian.PutUint32(b[i*4:], w)
+		}
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		src := make([]uint32, len(b)/4)
+		for i := range src {
+			src[i] = binary.BigEndian.Uint32(b[i*4:])
+		}
+
+		n, err := CountBytes(b)
+		if err != nil {
+			dec := NewDecoder(b)
+			for dec.Next() {
+			}
+			if dec.Err() == nil {
+				t.Fatalf("Expected Decoder error, got nil")
+			}
+			return
+		}
+
+		dst := make([]uint32, n)
+		if err := DecodeAll(dst, src); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if n > 0 {
+			if err := DecodeAll(make([]uint32, n-1), src); err != ErrShortBuffer {
+				t.Fatalf("Error mismatch: got %v, exp %v", err, ErrShortBuffer)
+			}
+		}
+
+		dec := NewDecoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
+
+func BenchmarkEncodeAll(b *testing.B) {
+	x := make([]uint32, 1024)
+	for i := 0; i < len(x); i++ {
+		x[i] = uint32(i % 15)
+	}
+
+	b.ResetTimer()
+	b.SetBytes(int64(len(x) * 4))
+	for i := 0; i < b.N; i++ {
+		EncodeAll(x)
+	}
+}
+
+func BenchmarkDecodeAll(b *testing.B) {
+	x := make([]uint32, 1024)
+	for i := 0; i < len(x); i++ {
+		x[i] = uint32(i % 15)
+	}
+	y, _ := EncodeAll(x)
+
+	decoded := make([]uint32, len(x))
+
+	b.ResetTimer()
+	b.SetBytes(int64(len(x) * 4))
+	for i := 0; i < b.N; i++ {
+		_ = DecodeAll(decoded, y)
+	}
+}
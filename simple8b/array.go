@@ -0,0 +1,129 @@
+package simple8b
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// arrayBlockSize is the number of words covered by each cumulative count in Array.
+const arrayBlockSize = 16
+
+// Array provides random access to values in a slice of simple8b encoded words.  It
+// records the number of values preceding every block of arrayBlockSize words so that
+// a value is found by a binary search over blocks, a short scan of word counts and
+// unpacking the single word holding it.
+type Array struct {
+	words []uint64
+
+	// offsets[k] is the index of the first value in word k*arrayBlockSize, with a
+	// final entry holding the total number of values.
+	offsets []int
+}
+
+// NewArray returns an Array over the encoded words in src.  An error is returned if
+// src contains an invalid selector.
+func NewArray(src []uint64) (*Array, error) {
+	a := &Array{
+		words:   src,
+		offsets: make([]int, 0, len(src)/arrayBlockSize+2),
+	}
+
+	count := 0
+	for i, v := range src {
+		if i%arrayBlockSize == 0 {
+			a.offsets = append(a.offsets, count)
+		}
+
+		n, err := Count(v)
+		if err != nil {
+			return nil, err
+		}
+		count += n
+	}
+	a.offsets = append(a.offsets, count)
+	return a, nil
+}
+
+// NewArrayBytes returns an Array over the encoded bytes in b, such as those returned
+// by Encoder.Bytes.
+func NewArrayBytes(b []byte) (*Array, error) {
+	if len(b)%8 != 0 {
+		return nil, fmt.Errorf("invalid slice len remaining: %v", len(b)%8)
+	}
+
+	src := make([]uint64, len(b)/8)
+	for i := range src {
+		src[i] = binary.BigEndian.Uint64(b[i*8:])
+	}
+	return NewArray(src)
+}
+
+// Len returns the number of values in the array.
+func (a *Array) Len() int {
+	return a.offsets[len(a.offsets)-1]
+}
+
+// At returns the value at index i.  It panics if i is out of range.
+func (a *Array) At(i int) uint64 {
+	w, k := a.find(i)
+	return wordValue(a.words[w], k)
+}
+
+// Slice returns the values from index i up to, but not including, index j.  It
+// panics if the indexes are out of range.
+func (a *Array) Slice(i, j int) []uint64 {
+	if i < 0 || j > a.Len() || i > j {
+		panic(fmt.Sprintf("simple8b: slice bounds out of range [%d:%d] with length %d", i, j, a.Len()))
+	}
+
+	dst := make([]uint64, 0, j-i)
+	if i == j {
+		return dst
+	}
+
+	var buf [240]uint64
+	w, k := a.find(i)
+	for len(dst) < j-i {
+		n, _ := Decode(&buf, a.words[w])
+		if rem := j - i - len(dst); n-k > rem {
+			n = k + rem
+		}
+		dst = append(dst, buf[k:n]...)
+		k = 0
+		w++
+	}
+	return dst
+}
+
+// wordValue returns the value at position k of the encoded word v.
+func wordValue(v uint64, k int) uint64 {
+	bits := uint(selector[v>>60].bit)
+	if bits == 0 {
+		// Selectors 0 and 1 encode runs of ones.
+		return 1
+	}
+	return (v >> (uint(k) * bits)) & (1<<bits - 1)
+}
+
+// find returns the word holding the value at index i and the position of the value
+// within that word.
+func (a *Array) find(i int) (word, pos int) {
+	if i < 0 || i >= a.Len() {
+		panic(fmt.Sprintf("simple8b: index out of range [%d] with length %d", i, a.Len()))
+	}
+
+	// The block holding i is the first one whose successor starts after i.
+	b := sort.Search(len(a.offsets)-1, func(k int) bool { return a.offsets[k+1] > i })
+
+	word = b * arrayBlockSize
+	pos = i - a.offsets[b]
+	for {
+		n := selector[a.words[word]>>60].n
+		if pos < n {
+			return word, pos
+		}
+		pos -= n
+		word++
+	}
+}
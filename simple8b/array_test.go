@@ -0,0 +1,127 @@
+package simple8b_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+func Test_Array_At(t *testing.T) {
+	rand.Seed(1)
+	in := make([]uint64, 5000)
+	for i := range in {
+		switch {
+		case i < 600:
+			in[i] = 1
+		case i%100 == 0:
+			in[i] = uint64(rand.Int63n(simple8b.MaxValue))
+		default:
+			in[i] = uint64(rand.Intn(1 << uint(rand.Intn(20))))
+		}
+	}
+
+	encoded, err := simple8b.EncodeAll(append([]uint64(nil), in...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	a, err := simple8b.NewArray(encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp, got := len(in), a.Len(); got != exp {
+		t.Fatalf("Len mismatch: exp %v, got %v", exp, got)
+	}
+
+	for i := range in {
+		if got := a.At(i); got != in[i] {
+			t.Fatalf("At(%d) != %v, got %v", i, in[i], got)
+		}
+	}
+
+	for k := 0; k < 1000; k++ {
+		i := rand.Intn(len(in))
+		j := i + rand.Intn(len(in)-i+1)
+		got := a.Slice(i, j)
+		if len(got) != j-i {
+			t.Fatalf("Slice(%d, %d) len mismatch: exp %v, got %v", i, j, j-i, len(got))
+		}
+		for x := range got {
+			if got[x] != in[i+x] {
+				t.Fatalf("Slice(%d, %d)[%d] != %v, got %v", i, j, x, in[i+x], got[x])
+			}
+		}
+	}
+}
+
+func Test_Array_Bytes(t *testing.T) {
+	enc := simple8b.NewEncoder()
+	for i := 0; i < 1000; i++ {
+		enc.Write(uint64(i))
+	}
+	b, _ := enc.Bytes()
+
+	a, err := simple8b.NewArrayBytes(b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := a.At(999); got != 999 {
+		t.Fatalf("At(999) != 999, got %v", got)
+	}
+
+	if _, err := simple8b.NewArrayBytes(b[:len(b)-1]); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_Array_OutOfRange(t *testing.T) {
+	a, _ := simple8b.NewArray(nil)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Expected panic")
+		}
+	}()
+	a.At(0)
+}
+
+func FuzzArray(f *testing.F) {
+	for _, in := range [][]uint64{nil, {1, 2, 3}, {1 << 59, 7, 0, 1 << 30}, make([]uint64, 500)} {
+		encoded, _ := simple8b.EncodeAll(append([]uint64(nil), in...))
+		f.Add(fuzzBytes(encoded))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		a, err := simple8b.NewArrayBytes(b)
+		if err != nil {
+			return
+		}
+
+		dst := make([]uint64, a.Len())
+		if _, err := simple8b.DecodeAll(dst, fuzzWords(b)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for i := range dst {
+			if a.At(i) != dst[i] {
+				t.Fatalf("At(%d) != %v, got %v", i, dst[i], a.At(i))
+			}
+		}
+		if got := a.Slice(0, len(dst)); len(got) != len(dst) {
+			t.Fatalf("Slice len mismatch: got %v, exp %v", len(got), len(dst))
+		}
+	})
+}
+
+func BenchmarkArrayAt(b *testing.B) {
+	x := make([]uint64, 1<<16)
+	for i := 0; i < len(x); i++ {
+		x[i] = uint64(i % 1000)
+	}
+	y, _ := simple8b.EncodeAll(x)
+	a, _ := simple8b.NewArray(y)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.At(i & (1<<16 - 1))
+	}
+}
@@ -0,0 +1,65 @@
+package simple8b
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jwilder/encoding"
+)
+
+func init() {
+	encoding.Register(Codec{})
+}
+
+// Codec implements encoding.Codec using simple8b, with words serialized as 8 byte
+// big endian integers in the same form as Encoder.Bytes.
+type Codec struct{}
+
+// Name returns "simple8b".
+func (Codec) Name() string { return "simple8b" }
+
+// ID returns encoding.Simple8b.
+func (Codec) ID() byte { return encoding.Simple8b }
+
+// MaxEncodedLen returns the maximum number of bytes used to encode n values, which
+// is one word per value.
+func (Codec) MaxEncodedLen(n int) int { return 8 * n }
+
+// Encode appends the packed words of src to dst.  If a value is over 1 << 60, an
+// error is returned.
+func (Codec) Encode(dst []byte, src []uint64) ([]byte, error) {
+	var b [8]byte
+	for len(src) > 0 {
+		v, n, err := Encode(src)
+		if err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint64(b[:], v)
+		dst = append(dst, b[:]...)
+		src = src[n:]
+	}
+	return dst, nil
+}
+
+// Decode writes the values of the packed words in src to dst.  It returns the
+// number of values written or an error.
+func (Codec) Decode(dst []uint64, src []byte) (int, error) {
+	if len(src)%8 != 0 {
+		return 0, fmt.Errorf("invalid slice len remaining: %v", len(src)%8)
+	}
+
+	var buf [240]uint64
+	j := 0
+	for i := 0; i < len(src); i += 8 {
+		n, err := Decode(&buf, binary.BigEndian.Uint64(src[i:]))
+		if err != nil {
+			return 0, err
+		}
+		if j+n > len(dst) {
+			return 0, ErrShortBuffer
+		}
+		copy(dst[j:], buf[:n])
+		j += n
+	}
+	return j, nil
+}
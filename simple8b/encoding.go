@@ -22,12 +22,21 @@ package simple8b
 // 4 most significant bits followed by 15 values encoded used 4 bits each in the remaing 60 bits.
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"unsafe"
 )
 
 const MaxValue = (1 << 60) - 1
 
+var (
+	// ErrShortBuffer is returned when dst is too small to hold the decoded values.
+	ErrShortBuffer = errors.New("simple8b: short buffer")
+
+	// ErrInvalidSelector is returned when a word has a selector outside the table.
+	ErrInvalidSelector = errors.New("simple8b: invalid selector")
+)
+
 // Encoder converts a stream of unsigned 64bit integers to a compressed byte slice.
 type Encoder struct {
 	// most recently written integers that have not been flushed
@@ -139,6 +148,7 @@ type Decoder struct {
 	buf   [240]uint64
 	i     int
 	n     int
+	err   error
 }
 
 // NewDecoder returns a Decoder from a byte slice
@@ -157,13 +167,14 @@ func (d *Decoder) Next() bool {
 		d.read()
 	}
 
-	return len(d.bytes) >= 8 || (d.i >= 0 && d.i < d.n)
+	return d.err == nil && (len(d.bytes) >= 8 || (d.i >= 0 && d.i < d.n))
 }
 
 func (d *Decoder) SetBytes(b []byte) {
 	d.bytes = b
 	d.i = 0
 	d.n = 0
+	d.err = nil
 }
 
 // Read returns the current value.  Successive calls to Read return the same
@@ -180,10 +191,15 @@ func (d *Decoder) read() {
 
 	v := binary.BigEndian.Uint64(d.bytes[:8])
 	d.bytes = d.bytes[8:]
-	d.n, _ = Decode(&d.buf, v)
+	d.n, d.err = Decode(&d.buf, v)
 	d.i = 0
 }
 
+// Err returns the first error encountered while decoding.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
 type packing struct {
 	n, bit int
 	unpack func(uint64, *[240]uint64)
@@ -233,7 +249,7 @@ func CountBytes(b []byte) (int, error) {
 func Count(v uint64) (int, error) {
 	sel := v >> 60
 	if sel >= 16 {
-		return 0, fmt.Errorf("invalid selector value: %v", sel)
+		return 0, ErrInvalidSelector
 	}
 	return selector[sel].n, nil
 }
@@ -354,26 +370,42 @@ func EncodeAll(src []uint64) ([]uint64, error) {
 	return dst[:j], nil
 }
 
+// Decode writes the uncompressed values in v to dst.  It returns the number of
+// values written or an error.
 func Decode(dst *[240]uint64, v uint64) (n int, err error) {
 	sel := v >> 60
 	if sel >= 16 {
-		return 0, fmt.Errorf("invalid selector value: %b", sel)
+		return 0, ErrInvalidSelector
 	}
 	selector[sel].unpack(v, dst)
 	return selector[sel].n, nil
 }
 
-// Decode writes the uncompressed values from src to dst.  It returns the number
-// of values written or an error.
+// DecodeAll writes the uncompressed values from src to dst.  It returns the number
+// of values written, ErrShortBuffer if dst cannot hold them or ErrInvalidSelector
+// if a word is invalid.
 func DecodeAll(dst, src []uint64) (value int, err error) {
+	var buf [240]uint64
 	j := 0
 	for _, v := range src {
 		sel := v >> 60
 		if sel >= 16 {
-			return 0, fmt.Errorf("invalid selector value: %b", sel)
+			return 0, ErrInvalidSelector
 		}
-		selector[sel].unpack(v, (*[240]uint64)(unsafe.Pointer(&dst[j])))
-		j += selector[sel].n
+
+		// Unpacking in place treats dst[j:] as 240 values, so near the end of dst
+		// words are unpacked into buf and copied instead.
+		n := selector[sel].n
+		if len(dst)-j >= len(buf) {
+			selector[sel].unpack(v, (*[240]uint64)(unsafe.Pointer(&dst[j])))
+		} else {
+			if j+n > len(dst) {
+				return 0, ErrShortBuffer
+			}
+			selector[sel].unpack(v, &buf)
+			copy(dst[j:], buf[:n])
+		}
+		j += n
 	}
 	return j, nil
 }
@@ -417,7 +449,7 @@ func pack240(src []uint64) uint64 {
 
 // pack120 packs 120 ones from in using 1 bit each
 func pack120(src []uint64) uint64 {
-	return 0
+	return 1 << 60
 }
 
 // pack60 packs 60 values from in using 1 bit each
@@ -1,6 +1,7 @@
 package simple8b_test
 
 import (
+	"encoding/binary"
 	"testing"
 
 	"github.com/jwilder/encoding/simple8b"
@@ -192,6 +193,79 @@ func Test_Decode_NotEnoughBytes(t *testing.T) {
 	}
 }
 
+func Test_DecodeAll_ShortBuffer(t *testing.T) {
+	// A single word of 240 ones must not be unpacked into a smaller dst.
+	for _, n := range []int{0, 1, 239} {
+		if _, err := simple8b.DecodeAll(make([]uint64, n), []uint64{0}); err != simple8b.ErrShortBuffer {
+			t.Fatalf("Error mismatch for dst of %d: got %v, exp %v", n, err, simple8b.ErrShortBuffer)
+		}
+	}
+
+	dst := make([]uint64, 241)
+	if n, err := simple8b.DecodeAll(dst, []uint64{15<<60 | 5, 0}); err != nil || n != 241 {
+		t.Fatalf("DecodeAll mismatch: got %v %v, exp 241", n, err)
+	}
+	if dst[0] != 5 || dst[240] != 1 {
+		t.Fatalf("Decoded mismatch: got %v and %v", dst[0], dst[240])
+	}
+}
+
+// fuzzBytes returns words as big endian bytes.
+func fuzzBytes(words []uint64) []byte {
+	b := make([]byte, 8*len(words))
+	for i, w := range words {
+		binary.BigEndian.PutUint64(b[i*8:], w)
+	}
+	return b
+}
+
+// fuzzWords returns the big endian words of b, ignoring a partial trailing word.
+func fuzzWords(b []byte) []uint64 {
+	words := make([]uint64, len(b)/8)
+	for i := range words {
+		words[i] = binary.BigEndian.Uint64(b[i*8:])
+	}
+	return words
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	for _, in := range [][]uint64{nil, {1, 2, 3}, {1 << 59, 7, 0, 1 << 30}, make([]uint64, 500)} {
+		encoded, _ := simple8b.EncodeAll(append([]uint64(nil), in...))
+		f.Add(fuzzBytes(encoded))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := simple8b.CountBytes(b)
+		if err != nil {
+			return
+		}
+		src := fuzzWords(b)
+
+		dst := make([]uint64, n)
+		got, err := simple8b.DecodeAll(dst, src)
+		if err != nil || got != n {
+			t.Fatalf("DecodeAll mismatch: got %v %v, exp %v", got, err, n)
+		}
+		if n > 0 {
+			if _, err := simple8b.DecodeAll(make([]uint64, n-1), src); err != simple8b.ErrShortBuffer {
+				t.Fatalf("Error mismatch: got %v, exp %v", err, simple8b.ErrShortBuffer)
+			}
+		}
+
+		dec := simple8b.NewDecoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
+
 func BenchmarkEncode(b *testing.B) {
 	total := 0
 	x := make([]uint64, 1024)
@@ -0,0 +1,388 @@
+package simple8b
+
+// The frame-of-reference (FOR) variant of simple8b splits the values into blocks of
+// FORBlockSize and stores each block as its minimum followed by the simple8b words
+// of the offsets of its values from that minimum:
+//
+// ┌──────────────┬──────────────────────────────────────────────────────┐
+// │   Minimum    │                    Offsets                           │
+// ├──────────────┼──────────────────────────────────────────────────────┤
+// │    1 word    │  simple8b words of FORBlockSize values, or fewer     │
+// │              │  for the last block                                  │
+// └──────────────┴──────────────────────────────────────────────────────┘
+//
+// Values clustered around a large base, such as timestamps in epoch seconds, are
+// then packed using the few bits needed for their spread rather than the bits of
+// the values themselves.  Unlike delta encoding, each value depends only on the
+// minimum of its block, so it can be read without decoding the values before it.
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// FORBlockSize is the number of values sharing a minimum in the FOR variant.
+const FORBlockSize = 256
+
+// FOREncoder converts a stream of unsigned 64bit integers to a compressed byte slice
+// using the FOR variant of simple8b.  Values may use all 64 bits, provided the
+// values of each block are within MaxValue of each other.
+type FOREncoder struct {
+	// values of the open block
+	buf []uint64
+
+	// blocks flushed so far
+	bytes []byte
+
+	// flushed blocks and the open block, returned by Bytes
+	out []byte
+}
+
+// NewFOREncoder returns a FOREncoder able to convert uint64s to compressed byte
+// slices
+func NewFOREncoder() *FOREncoder {
+	return &FOREncoder{
+		buf:   make([]uint64, 0, FORBlockSize),
+		bytes: make([]byte, 0, 128),
+	}
+}
+
+func (e *FOREncoder) Reset() {
+	e.buf = e.buf[:0]
+	e.bytes = e.bytes[:0]
+}
+
+// Write adds v to the encoder.  An error is returned if the block completed by v
+// spans more than MaxValue, and the values of that block are discarded.
+func (e *FOREncoder) Write(v uint64) error {
+	e.buf = append(e.buf, v)
+	if len(e.buf) < FORBlockSize {
+		return nil
+	}
+
+	b, err := appendFORBlock(e.bytes, e.buf)
+	e.buf = e.buf[:0]
+	if err != nil {
+		return err
+	}
+	e.bytes = b
+	return nil
+}
+
+// Bytes returns the encoded values written to the encoder.  The open block is
+// encoded without closing it, so values may continue to be written after calling
+// Bytes.
+func (e *FOREncoder) Bytes() ([]byte, error) {
+	if len(e.buf) == 0 {
+		return e.bytes, nil
+	}
+	e.out = append(e.out[:0], e.bytes...)
+	return appendFORBlock(e.out, e.buf)
+}
+
+// appendFORBlock appends the minimum of src and the packed words of the offsets from
+// it to b.
+func appendFORBlock(b []byte, src []uint64) ([]byte, error) {
+	words, err := encodeFORBlock(nil, src)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf [8]byte
+	for _, w := range words {
+		binary.BigEndian.PutUint64(buf[:], w)
+		b = append(b, buf[:]...)
+	}
+	return b, nil
+}
+
+// encodeFORBlock appends the minimum of src and the packed words of the offsets from
+// it to dst.  The input src is not modified.
+func encodeFORBlock(dst, src []uint64) ([]uint64, error) {
+	min, max := src[0], src[0]
+	for _, v := range src {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	if max-min > MaxValue {
+		return nil, fmt.Errorf("value out of bounds: block spans %d to %d", min, max)
+	}
+
+	dst = append(dst, min)
+	start := len(dst)
+	for _, v := range src {
+		dst = append(dst, v-min)
+	}
+
+	// The offsets are packed in place, as every word consumes at least one of them.
+	words, err := EncodeAll(dst[start:])
+	if err != nil {
+		return nil, err
+	}
+	return dst[:start+len(words)], nil
+}
+
+// FORDecoder converts a compressed byte slice produced by FOREncoder to a stream of
+// unsigned 64bit integers.
+type FORDecoder struct {
+	bytes []byte
+	buf   [240]uint64
+	i     int
+	n     int
+
+	// minimum of the current block and the number of its values not yet in buf
+	min  uint64
+	left int
+
+	err error
+}
+
+// NewFORDecoder returns a FORDecoder from a byte slice
+func NewFORDecoder(b []byte) *FORDecoder {
+	return &FORDecoder{
+		bytes: b,
+	}
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *FORDecoder) Next() bool {
+	d.i += 1
+
+	if d.i >= d.n {
+		d.read()
+	}
+
+	return d.i < d.n
+}
+
+func (d *FORDecoder) SetBytes(b []byte) {
+	d.bytes = b
+	d.i = 0
+	d.n = 0
+	d.left = 0
+	d.err = nil
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (d *FORDecoder) Read() uint64 {
+	return d.buf[d.i]
+}
+
+// Err returns the first error encountered while decoding.
+func (d *FORDecoder) Err() error {
+	return d.err
+}
+
+func (d *FORDecoder) read() {
+	d.i = 0
+	d.n = 0
+
+	if d.err != nil || len(d.bytes) < 8 {
+		if d.err == nil && len(d.bytes) > 0 {
+			d.err = fmt.Errorf("invalid slice len remaining: %v", len(d.bytes))
+		}
+		return
+	}
+
+	if d.left == 0 {
+		d.min = binary.BigEndian.Uint64(d.bytes[:8])
+		d.bytes = d.bytes[8:]
+		d.left = FORBlockSize
+		if len(d.bytes) < 8 {
+			d.err = fmt.Errorf("unexpected end of block: no values after minimum")
+			return
+		}
+	}
+
+	v := binary.BigEndian.Uint64(d.bytes[:8])
+	d.bytes = d.bytes[8:]
+	n, err := Decode(&d.buf, v)
+	if err != nil {
+		d.err = err
+		return
+	}
+	if n > d.left {
+		d.err = fmt.Errorf("invalid block: word of %d values overruns block with %d remaining", n, d.left)
+		return
+	}
+
+	for i := range d.buf[:n] {
+		d.buf[i] += d.min
+	}
+	d.left -= n
+	d.n = n
+}
+
+// EncodeAllFOR returns the packed blocks of the values from src using the FOR
+// variant of simple8b.  An error is returned if the values of a block span more
+// than MaxValue.  The input src is not modified.
+func EncodeAllFOR(src []uint64) ([]uint64, error) {
+	dst := make([]uint64, 0, len(src)+len(src)/FORBlockSize+1)
+	for len(src) > 0 {
+		n := FORBlockSize
+		if len(src) < n {
+			n = len(src)
+		}
+
+		var err error
+		if dst, err = encodeFORBlock(dst, src[:n]); err != nil {
+			return nil, err
+		}
+		src = src[n:]
+	}
+	return dst, nil
+}
+
+// DecodeAllFOR writes the uncompressed values from src to dst.  It returns the
+// number of values written or an error.
+func DecodeAllFOR(dst, src []uint64) (int, error) {
+	var buf [240]uint64
+	j := 0
+	for len(src) > 0 {
+		words, count, err := forBlock(src)
+		if err != nil {
+			return 0, err
+		}
+		if j+count > len(dst) {
+			return 0, ErrShortBuffer
+		}
+
+		min := src[0]
+		for _, v := range src[1:words] {
+			n, _ := Decode(&buf, v)
+			for _, u := range buf[:n] {
+				dst[j] = u + min
+				j++
+			}
+		}
+		src = src[words:]
+	}
+	return j, nil
+}
+
+// CountBytesFOR returns the number of integers encoded in the byte slice
+func CountBytesFOR(b []byte) (int, error) {
+	var count, left int
+	for len(b) >= 8 {
+		if left == 0 {
+			// Skip the minimum starting the block.
+			b = b[8:]
+			left = FORBlockSize
+			if len(b) < 8 {
+				return 0, fmt.Errorf("unexpected end of block: no values after minimum")
+			}
+			continue
+		}
+
+		n, err := Count(binary.BigEndian.Uint64(b[:8]))
+		if err != nil {
+			return 0, err
+		}
+		if n > left {
+			return 0, fmt.Errorf("invalid block: word of %d values overruns block with %d remaining", n, left)
+		}
+		b = b[8:]
+		left -= n
+		count += n
+	}
+
+	if len(b) > 0 {
+		return 0, fmt.Errorf("invalid slice len remaining: %v", len(b))
+	}
+	return count, nil
+}
+
+// forBlock returns the number of words and values of the block starting at src[0].
+func forBlock(src []uint64) (words, count int, err error) {
+	words = 1
+	for words < len(src) && count < FORBlockSize {
+		n, err := Count(src[words])
+		if err != nil {
+			return 0, 0, err
+		}
+		if count+n > FORBlockSize {
+			return 0, 0, fmt.Errorf("invalid block: word of %d values overruns block with %d remaining", n, FORBlockSize-count)
+		}
+		count += n
+		words++
+	}
+
+	if words == 1 {
+		return 0, 0, fmt.Errorf("unexpected end of block: no values after minimum")
+	}
+	return words, count, nil
+}
+
+// FORArray provides random access to values encoded with the FOR variant of
+// simple8b.  A value is found by indexing its block, which holds FORBlockSize values,
+// and scanning the word counts of that block alone.
+type FORArray struct {
+	words []uint64
+
+	// blocks[k] is the index in words of the minimum of block k
+	blocks []int
+	n      int
+}
+
+// NewFORArray returns a FORArray over the encoded words in src.  An error is returned
+// if src contains an invalid selector or block.
+func NewFORArray(src []uint64) (*FORArray, error) {
+	a := &FORArray{
+		words:  src,
+		blocks: make([]int, 0, len(src)/FORBlockSize+1),
+	}
+
+	for w := 0; w < len(src); {
+		words, count, err := forBlock(src[w:])
+		if err != nil {
+			return nil, err
+		}
+		a.blocks = append(a.blocks, w)
+		a.n += count
+		w += words
+	}
+	return a, nil
+}
+
+// NewFORArrayBytes returns a FORArray over the encoded bytes in b, such as those
+// returned by FOREncoder.Bytes.
+func NewFORArrayBytes(b []byte) (*FORArray, error) {
+	if len(b)%8 != 0 {
+		return nil, fmt.Errorf("invalid slice len remaining: %v", len(b)%8)
+	}
+
+	src := make([]uint64, len(b)/8)
+	for i := range src {
+		src[i] = binary.BigEndian.Uint64(b[i*8:])
+	}
+	return NewFORArray(src)
+}
+
+// Len returns the number of values in the array.
+func (a *FORArray) Len() int {
+	return a.n
+}
+
+// At returns the value at index i.  It panics if i is out of range.
+func (a *FORArray) At(i int) uint64 {
+	if i < 0 || i >= a.n {
+		panic(fmt.Sprintf("simple8b: index out of range [%d] with length %d", i, a.n))
+	}
+
+	w := a.blocks[i/FORBlockSize]
+	min := a.words[w]
+
+	pos := i % FORBlockSize
+	for w++; ; w++ {
+		n := selector[a.words[w]>>60].n
+		if pos < n {
+			return min + wordValue(a.words[w], pos)
+		}
+		pos -= n
+	}
+}
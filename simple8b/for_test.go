@@ -0,0 +1,226 @@
+package simple8b_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+func testEncodeFOR(t *testing.T, in []uint64) []uint64 {
+	encoded, err := simple8b.EncodeAllFOR(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded := make([]uint64, len(in))
+	n, err := simple8b.DecodeAllFOR(decoded, encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("Len mismatch: got %v, exp %v", n, len(in))
+	}
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+
+	a, err := simple8b.NewFORArray(encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if a.Len() != len(in) {
+		t.Fatalf("Array len mismatch: got %v, exp %v", a.Len(), len(in))
+	}
+	for i := range in {
+		if got := a.At(i); got != in[i] {
+			t.Fatalf("At(%d) != %v, got %v", i, in[i], got)
+		}
+	}
+
+	enc := simple8b.NewFOREncoder()
+	for _, v := range in {
+		if err := enc.Write(v); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	b, err := enc.Bytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(b) != 8*len(encoded) {
+		t.Fatalf("Encoder len mismatch: got %v, exp %v", len(b), 8*len(encoded))
+	}
+
+	if n, err := simple8b.CountBytesFOR(b); err != nil || n != len(in) {
+		t.Fatalf("Count mismatch: got %v %v, exp %v", n, err, len(in))
+	}
+
+	dec := simple8b.NewFORDecoder(b)
+	i := 0
+	for dec.Next() {
+		if i >= len(in) {
+			t.Fatalf("Decoded too many values: got %v, exp %v", i, len(in))
+		}
+		if dec.Read() != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], dec.Read())
+		}
+		i++
+	}
+	if dec.Err() != nil {
+		t.Fatalf("Unexpected error: %v", dec.Err())
+	}
+	if i != len(in) {
+		t.Fatalf("Len mismatch: got %v, exp %v", i, len(in))
+	}
+	return encoded
+}
+
+func Test_FOR_Timestamps(t *testing.T) {
+	in := make([]uint64, 1000)
+	for i := range in {
+		in[i] = 1500000000 + uint64(i)*10
+	}
+
+	encoded := testEncodeFOR(t, in)
+
+	// Each block spans 2550 seconds, which packs 5 offsets of 12 bits per word,
+	// instead of a single value of 31 bits.
+	if exp := 3*(1+256/5+1) + (1 + 232/5 + 1); len(encoded) > exp {
+		t.Fatalf("Encode len mismatch: got %v, exp at most %v", len(encoded), exp)
+	}
+
+	plain, _ := simple8b.EncodeAll(append([]uint64(nil), in...))
+	if len(encoded)*2 > len(plain) {
+		t.Fatalf("Expected FOR to be less than half the size: got %v words, plain %v words", len(encoded), len(plain))
+	}
+}
+
+func Test_FOR_WideValues(t *testing.T) {
+	in := make([]uint64, 600)
+	for i := range in {
+		in[i] = math.MaxUint64 - uint64(i%100)
+	}
+	testEncodeFOR(t, in)
+}
+
+func Test_FOR_Random(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	in := make([]uint64, 5000)
+	var base uint64
+	for i := range in {
+		if i%simple8b.FORBlockSize == 0 {
+			base = rng.Uint64() >> uint(1+rng.Intn(63))
+		}
+		in[i] = base + uint64(rng.Intn(1<<uint(rng.Intn(20))))
+	}
+
+	// Offsets of one pack into selectors 0 and 1.
+	for i := 0; i < simple8b.FORBlockSize; i++ {
+		in[i] = 1e12 + 1
+	}
+	in[1] = 1e12
+
+	testEncodeFOR(t, in)
+}
+
+func Test_FOR_NoValues(t *testing.T) {
+	testEncodeFOR(t, nil)
+}
+
+func Test_FOR_SpanTooLarge(t *testing.T) {
+	if _, err := simple8b.EncodeAllFOR([]uint64{0, math.MaxUint64}); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+
+	enc := simple8b.NewFOREncoder()
+	enc.Write(0)
+	enc.Write(math.MaxUint64)
+	if _, err := enc.Bytes(); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_FOR_InvalidBlock(t *testing.T) {
+	// A minimum with no words following it.
+	if _, err := simple8b.CountBytesFOR(make([]byte, 8)); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+	if _, err := simple8b.NewFORArray([]uint64{5}); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+
+	dec := simple8b.NewFORDecoder(make([]byte, 8))
+	if dec.Next() {
+		t.Fatalf("Expected Next to return false but it returned true")
+	}
+	if dec.Err() == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+
+	// Two words of 240 values overrun a block.
+	if _, err := simple8b.DecodeAllFOR(make([]uint64, 480), []uint64{5, 0, 0}); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_FOR_DstTooSmall(t *testing.T) {
+	encoded, _ := simple8b.EncodeAllFOR([]uint64{1, 2, 3})
+	if _, err := simple8b.DecodeAllFOR(make([]uint64, 2), encoded); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func FuzzDecodeAllFOR(f *testing.F) {
+	for _, in := range [][]uint64{nil, {1, 2, 3}, {1 << 62, 1<<62 + 5}, make([]uint64, 600)} {
+		encoded, _ := simple8b.EncodeAllFOR(in)
+		f.Add(fuzzBytes(encoded))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := simple8b.CountBytesFOR(b)
+		if err != nil {
+			if _, err := simple8b.NewFORArrayBytes(b); err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+			return
+		}
+		src := fuzzWords(b)
+
+		dst := make([]uint64, n)
+		got, err := simple8b.DecodeAllFOR(dst, src)
+		if err != nil || got != n {
+			t.Fatalf("DecodeAllFOR mismatch: got %v %v, exp %v", got, err, n)
+		}
+		if n > 0 {
+			if _, err := simple8b.DecodeAllFOR(make([]uint64, n-1), src); err != simple8b.ErrShortBuffer {
+				t.Fatalf("Error mismatch: got %v, exp %v", err, simple8b.ErrShortBuffer)
+			}
+		}
+
+		dec := simple8b.NewFORDecoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+
+		a, err := simple8b.NewFORArrayBytes(b)
+		if err != nil || a.Len() != n {
+			t.Fatalf("FORArray mismatch: got %v %v, exp %v", a, err, n)
+		}
+		for i := range dst {
+			if a.At(i) != dst[i] {
+				t.Fatalf("At(%d) != %v, got %v", i, dst[i], a.At(i))
+			}
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package simple8b
+
+import (
+	"fmt"
+
+	"github.com/jwilder/encoding/bitops"
+)
+
+const (
+	// MinInt64Value is the smallest signed value that can be encoded once zigzag
+	// encoded.
+	MinInt64Value = -1 << 59
+
+	// MaxInt64Value is the largest signed value that can be encoded once zigzag
+	// encoded.
+	MaxInt64Value = 1<<59 - 1
+)
+
+// Int64Encoder converts a stream of signed 64bit integers to a compressed byte slice.
+// Values are zigzag encoded so that small negative values stay small.
+type Int64Encoder struct {
+	enc *Encoder
+}
+
+// NewInt64Encoder returns an Int64Encoder able to convert int64s to compressed byte
+// slices
+func NewInt64Encoder() *Int64Encoder {
+	return &Int64Encoder{enc: NewEncoder()}
+}
+
+func (e *Int64Encoder) Reset() {
+	e.enc.Reset()
+}
+
+// Write adds v to the encoder.  An error is returned if v is outside the range
+// MinInt64Value to MaxInt64Value.
+func (e *Int64Encoder) Write(v int64) error {
+	if err := checkInt64(v); err != nil {
+		return err
+	}
+	return e.enc.Write(bitops.ZigZagEncode64(v))
+}
+
+func (e *Int64Encoder) Bytes() ([]byte, error) {
+	return e.enc.Bytes()
+}
+
+// Int64Decoder converts a compressed byte slice to a stream of signed 64bit integers.
+type Int64Decoder struct {
+	dec *Decoder
+}
+
+// NewInt64Decoder returns an Int64Decoder from a byte slice
+func NewInt64Decoder(b []byte) *Int64Decoder {
+	return &Int64Decoder{dec: NewDecoder(b)}
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *Int64Decoder) Next() bool {
+	return d.dec.Next()
+}
+
+func (d *Int64Decoder) SetBytes(b []byte) {
+	d.dec.SetBytes(b)
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (d *Int64Decoder) Read() int64 {
+	return bitops.ZigZagDecode64(d.dec.Read())
+}
+
+// Err returns the first error encountered while decoding.
+func (d *Int64Decoder) Err() error {
+	return d.dec.Err()
+}
+
+// EncodeAllInt64 returns a packed slice of the zigzag encoded values from src.  If a
+// value is outside the range MinInt64Value to MaxInt64Value, an error is returned.
+func EncodeAllInt64(src []int64) ([]uint64, error) {
+	dst := make([]uint64, len(src))
+	for i, v := range src {
+		if err := checkInt64(v); err != nil {
+			return nil, err
+		}
+		dst[i] = bitops.ZigZagEncode64(v)
+	}
+	return EncodeAll(dst)
+}
+
+// DecodeAllInt64 writes the uncompressed signed values from src to dst.  It returns
+// the number of values written or an error.
+func DecodeAllInt64(dst []int64, src []uint64) (int, error) {
+	var buf [240]uint64
+	j := 0
+	for _, v := range src {
+		n, err := Decode(&buf, v)
+		if err != nil {
+			return 0, err
+		}
+		if j+n > len(dst) {
+			return 0, ErrShortBuffer
+		}
+
+		for _, u := range buf[:n] {
+			dst[j] = bitops.ZigZagDecode64(u)
+			j++
+		}
+	}
+	return j, nil
+}
+
+// checkInt64 returns an error if v cannot be stored once zigzag encoded.
+func checkInt64(v int64) error {
+	if v < MinInt64Value || v > MaxInt64Value {
+		return fmt.Errorf("value out of bounds: %d does not fit in 60 bits after zigzag encoding", v)
+	}
+	return nil
+}
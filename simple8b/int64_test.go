@@ -0,0 +1,118 @@
+package simple8b_test
+
+import (
+	"testing"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+func Test_EncodeAllInt64(t *testing.T) {
+	in := []int64{0, -1, 1, -2, 2, 1000, -1000, simple8b.MinInt64Value, simple8b.MaxInt64Value}
+	for i := 0; i < 300; i++ {
+		in = append(in, int64(i%5)-2)
+	}
+
+	encoded, err := simple8b.EncodeAllInt64(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded := make([]int64, len(in))
+	n, err := simple8b.DecodeAllInt64(decoded, encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", len(in), n)
+	}
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
+func Test_EncodeAllInt64_OutOfRange(t *testing.T) {
+	for _, v := range []int64{simple8b.MinInt64Value - 1, simple8b.MaxInt64Value + 1} {
+		if _, err := simple8b.EncodeAllInt64([]int64{v}); err == nil {
+			t.Fatalf("Expected error for %v, got nil", v)
+		}
+	}
+}
+
+func Test_DecodeAllInt64_ShortBuffer(t *testing.T) {
+	encoded, _ := simple8b.EncodeAllInt64([]int64{-1, 1, -1, 1})
+	if _, err := simple8b.DecodeAllInt64(make([]int64, 2), encoded); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_Int64Encoder(t *testing.T) {
+	enc := simple8b.NewInt64Encoder()
+	in := make([]int64, 500)
+	for i := range in {
+		in[i] = int64(i*i) * int64(1-2*(i%2))
+		if err := enc.Write(in[i]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if err := enc.Write(simple8b.MaxInt64Value + 1); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+
+	b, err := enc.Bytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := simple8b.NewInt64Decoder(b)
+	i := 0
+	for dec.Next() {
+		if dec.Read() != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], dec.Read())
+		}
+		i += 1
+	}
+
+	if exp, got := len(in), i; got != exp {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", exp, got)
+	}
+}
+
+func FuzzDecodeAllInt64(f *testing.F) {
+	for _, in := range [][]int64{nil, {-1, 1, -2, 2}, {simple8b.MinInt64Value, simple8b.MaxInt64Value}} {
+		encoded, _ := simple8b.EncodeAllInt64(in)
+		f.Add(fuzzBytes(encoded))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := simple8b.CountBytes(b)
+		if err != nil {
+			return
+		}
+		src := fuzzWords(b)
+
+		dst := make([]int64, n)
+		if got, err := simple8b.DecodeAllInt64(dst, src); err != nil || got != n {
+			t.Fatalf("DecodeAllInt64 mismatch: got %v %v, exp %v", got, err, n)
+		}
+		if n > 0 {
+			if _, err := simple8b.DecodeAllInt64(make([]int64, n-1), src); err != simple8b.ErrShortBuffer {
+				t.Fatalf("Error mismatch: got %v, exp %v", err, simple8b.ErrShortBuffer)
+			}
+		}
+
+		dec := simple8b.NewInt64Decoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
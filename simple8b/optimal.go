@@ -0,0 +1,78 @@
+package simple8b
+
+import "fmt"
+
+// EncodeAllOptimal returns a packed slice of the values from src using the fewest
+// possible words.  Unlike EncodeAll, which greedily takes the densest selector that
+// fits the next values, it finds the shortest sequence of selectors covering src
+// using dynamic programming.  This is slower than EncodeAll but never produces more
+// words, and the output is decoded by DecodeAll and Decoder as usual.  If a value is
+// over 1 << 60, an error is returned.  The input src is not modified.
+func EncodeAllOptimal(src []uint64) ([]uint64, error) {
+	n := len(src)
+
+	// words[i] is the fewest words needed to encode src[i:] and sel[i] the selector
+	// starting that encoding.
+	words := make([]int, n+1)
+	sel := make([]uint8, n)
+
+	// ones is the length of the run of ones starting at i, used for selectors 0
+	// and 1.
+	ones := 0
+
+	for i := n - 1; i >= 0; i-- {
+		if src[i] > MaxValue {
+			return nil, fmt.Errorf("value out of bounds: %v", src[i])
+		}
+
+		if src[i] == 1 {
+			ones++
+		} else {
+			ones = 0
+		}
+
+		// Both run selectors are tried as a shorter run can leave the remaining
+		// ones to pack with the values following them.
+		best, bestSel := -1, uint8(0)
+		if ones >= 240 {
+			best, bestSel = 1+words[i+240], 0
+		}
+		if ones >= 120 {
+			if c := 1 + words[i+120]; best < 0 || c < best {
+				best, bestSel = c, 1
+			}
+		}
+
+		// Try selectors from the widest to the densest, tracking the bits needed
+		// by the values covered so far.
+		var max uint64
+		covered := 0
+		for s := 15; s >= 2; s-- {
+			k := selector[s].n
+			if i+k > n {
+				break
+			}
+
+			for ; covered < k; covered++ {
+				max |= src[i+covered]
+			}
+			if max>>uint(selector[s].bit) != 0 {
+				break
+			}
+
+			if c := 1 + words[i+k]; best < 0 || c <= best {
+				best, bestSel = c, uint8(s)
+			}
+		}
+
+		words[i], sel[i] = best, bestSel
+	}
+
+	dst := make([]uint64, 0, words[0])
+	for i := 0; i < n; {
+		s := selector[sel[i]]
+		dst = append(dst, s.pack(src[i:i+s.n]))
+		i += s.n
+	}
+	return dst, nil
+}
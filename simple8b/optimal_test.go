@@ -0,0 +1,127 @@
+package simple8b_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+func Test_EncodeAllOptimal_Random(t *testing.T) {
+	rand.Seed(1)
+	for k := 0; k < 50; k++ {
+		in := make([]uint64, rand.Intn(2000))
+		for i := range in {
+			switch rand.Intn(4) {
+			case 0:
+				in[i] = 1
+			case 1:
+				in[i] = uint64(rand.Intn(8))
+			case 2:
+				in[i] = uint64(rand.Intn(1 << 12))
+			default:
+				in[i] = uint64(rand.Int63n(simple8b.MaxValue))
+			}
+		}
+		testEncodeOptimal(t, in)
+	}
+}
+
+func Test_EncodeAllOptimal_WideValue(t *testing.T) {
+	// Wide values between narrow ones leave greedy encoding with badly packed
+	// words.
+	in := []uint64{0, 2, 1 << 20, 2, 3, 0, 1, 3, 2, 3, 2, 0, 3, 1 << 19, 1, 0}
+	testEncodeOptimal(t, in)
+
+	encoded, _ := simple8b.EncodeAllOptimal(in)
+	if exp, got := 4, len(encoded); got != exp {
+		t.Fatalf("Encode len mismatch: exp %v, got %v", exp, got)
+	}
+}
+
+func Test_EncodeAllOptimal_Ones(t *testing.T) {
+	in := make([]uint64, 1000)
+	for i := range in {
+		in[i] = 1
+	}
+	testEncodeOptimal(t, in)
+}
+
+func Test_EncodeAllOptimal_OnesThenDense(t *testing.T) {
+	// 120 ones fit a single run word, while greedy encoding only uses runs that end
+	// the input and spends two 60 bit words on them.
+	in := make([]uint64, 180)
+	for i := range in {
+		in[i] = 1
+		if i >= 120 && i%2 == 0 {
+			in[i] = 0
+		}
+	}
+	testEncodeOptimal(t, in)
+
+	encoded, _ := simple8b.EncodeAllOptimal(in)
+	greedy, _ := simple8b.EncodeAll(append([]uint64(nil), in...))
+	if exp, got := 2, len(encoded); got != exp {
+		t.Fatalf("Encode len mismatch: exp %v, got %v", exp, got)
+	}
+	if exp, got := 3, len(greedy); got != exp {
+		t.Fatalf("Greedy len mismatch: exp %v, got %v", exp, got)
+	}
+	if encoded[0]>>60 != 1 {
+		t.Fatalf("Selector mismatch: exp 1, got %v", encoded[0]>>60)
+	}
+
+	// With a longer run either run selector may start the encoding.
+	in = append(make([]uint64, 0, 300), in[:120]...)
+	in = append(in, in...)
+	in = append(in, 0, 0, 0)
+	testEncodeOptimal(t, in)
+}
+
+func Test_EncodeAllOptimal_TooBig(t *testing.T) {
+	if _, err := simple8b.EncodeAllOptimal([]uint64{0, simple8b.MaxValue + 1}); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func testEncodeOptimal(t *testing.T, in []uint64) {
+	encoded, err := simple8b.EncodeAllOptimal(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	greedy, err := simple8b.EncodeAll(append([]uint64(nil), in...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(encoded) > len(greedy) {
+		t.Fatalf("Optimal len larger than greedy: got %v, greedy %v", len(encoded), len(greedy))
+	}
+
+	decoded := make([]uint64, len(in)+240)
+	n, err := simple8b.DecodeAll(decoded, encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", len(in), n)
+	}
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
+func BenchmarkEncodeOptimal(b *testing.B) {
+	x := make([]uint64, 1024)
+	for i := 0; i < len(x); i++ {
+		x[i] = uint64(i % 17)
+	}
+
+	b.ResetTimer()
+	b.SetBytes(int64(len(x) * 8))
+	for i := 0; i < b.N; i++ {
+		simple8b.EncodeAllOptimal(x)
+	}
+}
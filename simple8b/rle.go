@@ -0,0 +1,367 @@
+package simple8b
+
+// The RLE variant of simple8b replaces selector 0 with a run-length encoded word,
+// in the style of the InfluxDB simple8b RLE extension.  A run packs a value of up
+// to 40 bits and a repeat count of up to 20 bits into the remaining 60 bits:
+//
+// ┌──────────────┬──────────────────────┬──────────────────────┐
+// │   Selector   │        Value         │        Count         │
+// ├──────────────┼──────────────────────┼──────────────────────┤
+// │    4 bits    │       40 bits        │       20 bits        │
+// └──────────────┴──────────────────────┴──────────────────────┘
+//
+// All other selectors are unchanged, so only the 240 ones of selector 0 are lost
+// and those are better expressed as a run.  Words produced by the RLE encoder must
+// be read with the RLE decoding functions.
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// MaxRLEValue is the largest value that can be stored in a run.
+	MaxRLEValue = (1 << 40) - 1
+
+	// MaxRLECount is the largest number of values that can be stored in a run.
+	MaxRLECount = (1 << 20) - 1
+)
+
+// RLEEncoder converts a stream of unsigned 64bit integers to a compressed byte slice
+// using the RLE variant of simple8b.
+type RLEEncoder struct {
+	// most recently written integers that have not been flushed
+	buf []uint64
+
+	// index in buf of the head of the buf
+	h int
+
+	// index in buf of the tail of the buf
+	t int
+
+	// an open run of identical values that has not been flushed
+	run  uint64
+	runN int
+
+	// current bytes written and flushed
+	bytes []byte
+}
+
+// NewRLEEncoder returns an RLEEncoder able to convert uint64s to compressed byte
+// slices
+func NewRLEEncoder() *RLEEncoder {
+	return &RLEEncoder{
+		buf:   make([]uint64, 240),
+		bytes: make([]byte, 0, 128),
+	}
+}
+
+func (e *RLEEncoder) Reset() {
+	e.t = 0
+	e.h = 0
+	e.runN = 0
+
+	e.buf = e.buf[:240]
+	e.bytes = e.bytes[:0]
+}
+
+func (e *RLEEncoder) Write(v uint64) error {
+	// Extend the open run for as long as the value repeats.
+	if e.runN > 0 {
+		if v == e.run && e.runN < MaxRLECount {
+			e.runN += 1
+			return nil
+		}
+		e.writeWord(rleWord(e.run, e.runN))
+		e.runN = 0
+	}
+
+	if e.t >= len(e.buf) {
+		if err := e.flush(); err != nil {
+			return err
+		}
+	}
+
+	// The buf is full but there is space at the front, just shift
+	// the values down for now.
+	if e.t >= len(e.buf) {
+		copy(e.buf, e.buf[e.h:])
+		e.t -= e.h
+		e.h = 0
+	}
+
+	if e.runN > 0 {
+		// flush opened a run so v must be compared against it.
+		return e.Write(v)
+	}
+
+	e.buf[e.t] = v
+	e.t += 1
+	return nil
+}
+
+func (e *RLEEncoder) flush() error {
+	if e.t == 0 {
+		return nil
+	}
+
+	// encode as many values into one as we can
+	encoded, n, err := EncodeRLE(e.buf[e.h:e.t])
+	if err != nil {
+		return err
+	}
+
+	// A run covering a full buffer is kept open since it is likely to continue.
+	if encoded>>60 == 0 && e.h+n == e.t && e.t == len(e.buf) {
+		e.run, e.runN = e.buf[e.h], n
+		e.h, e.t = 0, 0
+		return nil
+	}
+	e.writeWord(encoded)
+
+	// Move the head forward since we encoded those values
+	e.h += n
+
+	// If we encoded them all, reset the head/tail pointers to the beginning
+	if e.h == e.t {
+		e.h = 0
+		e.t = 0
+	}
+
+	return nil
+}
+
+func (e *RLEEncoder) writeWord(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	e.bytes = append(e.bytes, b[:]...)
+}
+
+func (e *RLEEncoder) Bytes() ([]byte, error) {
+	for e.t > 0 || e.runN > 0 {
+		if e.runN > 0 {
+			e.writeWord(rleWord(e.run, e.runN))
+			e.runN = 0
+		}
+		if err := e.flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.bytes, nil
+}
+
+// RLEDecoder converts a compressed byte slice produced by RLEEncoder to a stream of
+// unsigned 64bit integers.
+type RLEDecoder struct {
+	bytes []byte
+	buf   [240]uint64
+	i     int
+	n     int
+
+	// remaining values of the current run not yet in buf
+	run  uint64
+	runN int
+
+	err error
+}
+
+// NewRLEDecoder returns an RLEDecoder from a byte slice
+func NewRLEDecoder(b []byte) *RLEDecoder {
+	return &RLEDecoder{
+		bytes: b,
+	}
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *RLEDecoder) Next() bool {
+	d.i += 1
+
+	if d.i >= d.n {
+		d.read()
+	}
+
+	return d.i < d.n
+}
+
+func (d *RLEDecoder) SetBytes(b []byte) {
+	d.bytes = b
+	d.i = 0
+	d.n = 0
+	d.runN = 0
+	d.err = nil
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (d *RLEDecoder) Read() uint64 {
+	return d.buf[d.i]
+}
+
+// Err returns the first error encountered while decoding.
+func (d *RLEDecoder) Err() error {
+	return d.err
+}
+
+func (d *RLEDecoder) read() {
+	d.i = 0
+	d.n = 0
+
+	if d.runN == 0 {
+		if d.err != nil || len(d.bytes) < 8 {
+			return
+		}
+
+		v := binary.BigEndian.Uint64(d.bytes[:8])
+		d.bytes = d.bytes[8:]
+		if v>>60 != 0 {
+			d.n, d.err = Decode(&d.buf, v)
+			return
+		}
+		if d.runN, d.err = CountRLE(v); d.err != nil {
+			return
+		}
+		d.run = rleValue(v)
+	}
+
+	n := d.runN
+	if n > len(d.buf) {
+		n = len(d.buf)
+	}
+	for i := 0; i < n; i++ {
+		d.buf[i] = d.run
+	}
+	d.runN -= n
+	d.n = n
+}
+
+// CountBytesRLE returns the number of integers encoded in the byte slice
+func CountBytesRLE(b []byte) (int, error) {
+	var count int
+	for len(b) >= 8 {
+		v := binary.BigEndian.Uint64(b[:8])
+		b = b[8:]
+		n, err := CountRLE(v)
+		if err != nil {
+			return 0, err
+		}
+
+		count += n
+	}
+
+	if len(b) > 0 {
+		return 0, fmt.Errorf("invalid slice len remaining: %v", len(b))
+	}
+	return count, nil
+}
+
+// CountRLE returns the number of integers encoded within an uint64 using the RLE
+// variant of simple8b
+func CountRLE(v uint64) (int, error) {
+	if v>>60 == 0 {
+		if n := rleCount(v); n > 0 {
+			return n, nil
+		}
+		return 0, fmt.Errorf("invalid run length: 0")
+	}
+	return Count(v)
+}
+
+// EncodeRLE packs as many values into a single uint64 using the RLE variant of
+// simple8b.  A run is used when more repeated values are available than the densest
+// packed selector could hold.  It returns the packed uint64, how many values from
+// src were packed, or an error if the values exceed the maximum value range.
+func EncodeRLE(src []uint64) (value uint64, n int, err error) {
+	if len(src) == 0 {
+		return 0, 0, nil
+	}
+
+	runN := 1
+	for runN < len(src) && runN < MaxRLECount && src[runN] == src[0] {
+		runN++
+	}
+
+	value, n, err = Encode(src)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Selector 0 is not available in the RLE table, but it only packs runs of
+	// 240 ones which always fit in a run.
+	if src[0] <= MaxRLEValue && (value>>60 == 0 || runN > n) {
+		return rleWord(src[0], runN), runN, nil
+	}
+	return value, n, nil
+}
+
+// EncodeAllRLE returns a packed slice of the values from src using the RLE variant
+// of simple8b.  If a value is over 1 << 60, an error is returned.  The input src is
+// modified to avoid extra allocations.  If you need to re-use, use a copy.
+func EncodeAllRLE(src []uint64) ([]uint64, error) {
+	i := 0
+
+	// Re-use the input slice and write encoded values back in place.  Every word
+	// consumes at least one value so it never overwrites unread input.
+	dst := src
+	j := 0
+
+	for i < len(src) {
+		v, n, err := EncodeRLE(src[i:])
+		if err != nil {
+			return nil, err
+		}
+		dst[j] = v
+		i += n
+		j += 1
+	}
+	return dst[:j], nil
+}
+
+// DecodeAllRLE writes the uncompressed values from src to dst.  It returns the
+// number of values written or an error.
+func DecodeAllRLE(dst, src []uint64) (int, error) {
+	var buf [240]uint64
+	j := 0
+	for _, v := range src {
+		if v>>60 == 0 {
+			n, err := CountRLE(v)
+			if err != nil {
+				return 0, err
+			}
+			if j+n > len(dst) {
+				return 0, ErrShortBuffer
+			}
+
+			run := rleValue(v)
+			for i := j; i < j+n; i++ {
+				dst[i] = run
+			}
+			j += n
+			continue
+		}
+
+		n, err := Decode(&buf, v)
+		if err != nil {
+			return 0, err
+		}
+		if j+n > len(dst) {
+			return 0, ErrShortBuffer
+		}
+		copy(dst[j:], buf[:n])
+		j += n
+	}
+	return j, nil
+}
+
+// rleWord returns a run of n copies of v.
+func rleWord(v uint64, n int) uint64 {
+	return v<<20 | uint64(n)
+}
+
+func rleValue(v uint64) uint64 {
+	return v >> 20 & MaxRLEValue
+}
+
+func rleCount(v uint64) int {
+	return int(v & MaxRLECount)
+}
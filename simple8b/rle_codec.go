@@ -0,0 +1,56 @@
+package simple8b
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jwilder/encoding"
+)
+
+func init() {
+	encoding.Register(RLECodec{})
+}
+
+// RLECodec implements encoding.Codec using the RLE variant of simple8b, with words
+// serialized as 8 byte big endian integers in the same form as RLEEncoder.Bytes.
+type RLECodec struct{}
+
+// Name returns "simple8b-rle".
+func (RLECodec) Name() string { return "simple8b-rle" }
+
+// ID returns encoding.Simple8bRLE.
+func (RLECodec) ID() byte { return encoding.Simple8bRLE }
+
+// MaxEncodedLen returns the maximum number of bytes used to encode n values, which
+// is one word per value.
+func (RLECodec) MaxEncodedLen(n int) int { return 8 * n }
+
+// Encode appends the packed words of src to dst.  If a value is over 1 << 60, an
+// error is returned.
+func (RLECodec) Encode(dst []byte, src []uint64) ([]byte, error) {
+	var b [8]byte
+	for len(src) > 0 {
+		v, n, err := EncodeRLE(src)
+		if err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint64(b[:], v)
+		dst = append(dst, b[:]...)
+		src = src[n:]
+	}
+	return dst, nil
+}
+
+// Decode writes the values of the packed words in src to dst.  It returns the
+// number of values written or an error.
+func (RLECodec) Decode(dst []uint64, src []byte) (int, error) {
+	if len(src)%8 != 0 {
+		return 0, fmt.Errorf("invalid slice len remaining: %v", len(src)%8)
+	}
+
+	words := make([]uint64, len(src)/8)
+	for i := range words {
+		words[i] = binary.BigEndian.Uint64(src[i*8:])
+	}
+	return DecodeAllRLE(dst, words)
+}
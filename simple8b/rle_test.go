@@ -0,0 +1,182 @@
+package simple8b_test
+
+import (
+	"testing"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+func Test_RLE_Runs(t *testing.T) {
+	var in []uint64
+	for i := 0; i < 1000; i++ {
+		in = append(in, 0)
+	}
+	for i := 0; i < 500; i++ {
+		in = append(in, 10000000000)
+	}
+	for i := 0; i < 300; i++ {
+		in = append(in, uint64(i%7))
+	}
+	for i := 0; i < 240; i++ {
+		in = append(in, 1)
+	}
+	in = append(in, simple8b.MaxValue, 3, 3)
+
+	testEncodeRLE(t, in)
+
+	encoded, err := simple8b.EncodeAllRLE(append([]uint64(nil), in...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	plain, _ := simple8b.EncodeAll(append([]uint64(nil), in...))
+	if len(encoded) >= len(plain) {
+		t.Fatalf("Expected RLE to be smaller: got %v words, plain %v words", len(encoded), len(plain))
+	}
+}
+
+func Test_RLE_LongRun(t *testing.T) {
+	in := make([]uint64, simple8b.MaxRLECount+10)
+	for i := range in {
+		in[i] = 60
+	}
+	testEncodeRLE(t, in)
+
+	encoded, _ := simple8b.EncodeAllRLE(append([]uint64(nil), in...))
+	if exp, got := 2, len(encoded); got != exp {
+		t.Fatalf("Encode len mismatch: exp %v, got %v", exp, got)
+	}
+}
+
+func Test_RLE_NoRuns(t *testing.T) {
+	in := make([]uint64, 500)
+	for i := range in {
+		in[i] = uint64(i)
+	}
+	testEncodeRLE(t, in)
+}
+
+func Test_RLE_NoValues(t *testing.T) {
+	testEncodeRLE(t, nil)
+}
+
+func Test_RLE_ValueTooLarge(t *testing.T) {
+	enc := simple8b.NewRLEEncoder()
+	enc.Write(simple8b.MaxValue + 1)
+	if _, err := enc.Bytes(); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_RLE_InvalidRun(t *testing.T) {
+	if _, err := simple8b.CountBytesRLE(make([]byte, 8)); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+
+	dec := simple8b.NewRLEDecoder(make([]byte, 8))
+	if dec.Next() {
+		t.Fatalf("Expected Next to return false but it returned true")
+	}
+	if dec.Err() == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func testEncodeRLE(t *testing.T, in []uint64) {
+	enc := simple8b.NewRLEEncoder()
+	for _, v := range in {
+		if err := enc.Write(v); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	encoded, err := enc.Bytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := simple8b.NewRLEDecoder(encoded)
+	i := 0
+	for dec.Next() {
+		if i >= len(in) {
+			t.Fatalf("Decoded too many values: got %v, exp %v", i, len(in))
+		}
+
+		if dec.Read() != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], dec.Read())
+		}
+		i += 1
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if exp, got := len(in), i; got != exp {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", exp, got)
+	}
+
+	got, err := simple8b.CountBytesRLE(encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error in Count: %v", err)
+	}
+	if got != len(in) {
+		t.Fatalf("Count mismatch: got %v, exp %v", got, len(in))
+	}
+
+	words, err := simple8b.EncodeAllRLE(append([]uint64(nil), in...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded := make([]uint64, len(in))
+	n, err := simple8b.DecodeAllRLE(decoded, words)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("DecodeAll len mismatch: exp %v, got %v", len(in), n)
+	}
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("DecodeAll[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
+func FuzzDecodeAllRLE(f *testing.F) {
+	for _, in := range [][]uint64{nil, {1, 2, 3}, {5, 5, 5, 5, 5, 1 << 50}, make([]uint64, 500)} {
+		encoded, _ := simple8b.EncodeAllRLE(append([]uint64(nil), in...))
+		f.Add(fuzzBytes(encoded))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// Runs hold up to a million values each, so bound what is allocated.
+		n, err := simple8b.CountBytesRLE(b)
+		if err != nil || n > 1<<20 {
+			return
+		}
+		src := fuzzWords(b)
+
+		dst := make([]uint64, n)
+		got, err := simple8b.DecodeAllRLE(dst, src)
+		if err != nil || got != n {
+			t.Fatalf("DecodeAllRLE mismatch: got %v %v, exp %v", got, err, n)
+		}
+		if n > 0 {
+			if _, err := simple8b.DecodeAllRLE(make([]uint64, n-1), src); err != simple8b.ErrShortBuffer {
+				t.Fatalf("Error mismatch: got %v, exp %v", err, simple8b.ErrShortBuffer)
+			}
+		}
+
+		dec := simple8b.NewRLEDecoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package simple8b
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// streamBufferSize is the number of bytes buffered by StreamEncoder and
+// StreamDecoder before writing to or after reading from the underlying stream.
+const streamBufferSize = 4096
+
+// StreamEncoder converts a stream of unsigned 64bit integers to compressed words
+// written to an io.Writer.  Completed words are written as soon as they are packed,
+// so memory use is bounded regardless of the number of values written.
+type StreamEncoder struct {
+	w io.Writer
+
+	// most recently written integers that have not been packed
+	buf []uint64
+
+	// packed words not yet written to w
+	bytes []byte
+
+	err error
+}
+
+// NewStreamEncoder returns a StreamEncoder writing compressed words to w.
+func NewStreamEncoder(w io.Writer) *StreamEncoder {
+	return &StreamEncoder{
+		w:     w,
+		buf:   make([]uint64, 0, 240),
+		bytes: make([]byte, 0, streamBufferSize),
+	}
+}
+
+// Reset discards any unflushed values and switches to writing to w.
+func (e *StreamEncoder) Reset(w io.Writer) {
+	e.w = w
+	e.buf = e.buf[:0]
+	e.bytes = e.bytes[:0]
+	e.err = nil
+}
+
+// Write adds v to the encoder.  Values are packed once enough have been written to
+// fill a word, so an error for an out of range value may be returned by a later call
+// to Write or Flush.
+func (e *StreamEncoder) Write(v uint64) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	e.buf = append(e.buf, v)
+	if len(e.buf) == cap(e.buf) {
+		e.pack(false)
+	}
+	return e.err
+}
+
+// ReadFrom reads 8 byte big endian values from r until EOF and writes them to the
+// encoder.  It returns the number of bytes read.  Flush must still be called to
+// write the final words.
+func (e *StreamEncoder) ReadFrom(r io.Reader) (int64, error) {
+	var b [8]byte
+	var n int64
+	for {
+		m, err := io.ReadFull(r, b[:])
+		n += int64(m)
+		if err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, err
+		}
+
+		if err := e.Write(binary.BigEndian.Uint64(b[:])); err != nil {
+			return n, err
+		}
+	}
+}
+
+// Flush packs all buffered values and writes any remaining words to the underlying
+// writer.
+func (e *StreamEncoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+
+	e.pack(true)
+	if e.err == nil && len(e.bytes) > 0 {
+		e.write()
+	}
+	return e.err
+}
+
+// pack encodes buffered values into words.  Unless all is set, values are only
+// packed while a full selector's worth are buffered so that the densest selector can
+// be chosen.
+func (e *StreamEncoder) pack(all bool) {
+	i := 0
+	for i < len(e.buf) && (all || len(e.buf)-i == cap(e.buf)) {
+		encoded, n, err := Encode(e.buf[i:])
+		if err != nil {
+			e.err = err
+			return
+		}
+
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], encoded)
+		e.bytes = append(e.bytes, b[:]...)
+		if len(e.bytes) == cap(e.bytes) {
+			if e.write(); e.err != nil {
+				return
+			}
+		}
+		i += n
+	}
+
+	n := copy(e.buf, e.buf[i:])
+	e.buf = e.buf[:n]
+}
+
+// write writes the packed words to the underlying writer.
+func (e *StreamEncoder) write() {
+	if _, err := e.w.Write(e.bytes); err != nil {
+		e.err = err
+		return
+	}
+	e.bytes = e.bytes[:0]
+}
+
+// StreamDecoder converts compressed words read from an io.Reader to a stream of
+// unsigned 64bit integers.  Words are read on demand using a fixed size buffer.
+type StreamDecoder struct {
+	r   *bufio.Reader
+	buf [240]uint64
+	i   int
+	n   int
+	err error
+}
+
+// NewStreamDecoder returns a StreamDecoder reading compressed words from r.
+func NewStreamDecoder(r io.Reader) *StreamDecoder {
+	return &StreamDecoder{
+		r: bufio.NewReaderSize(r, streamBufferSize),
+	}
+}
+
+// Reset discards any buffered state and switches to reading from r.
+func (d *StreamDecoder) Reset(r io.Reader) {
+	d.r.Reset(r)
+	d.i = 0
+	d.n = 0
+	d.err = nil
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *StreamDecoder) Next() bool {
+	d.i += 1
+
+	if d.i >= d.n {
+		d.read()
+	}
+
+	return d.i < d.n
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (d *StreamDecoder) Read() uint64 {
+	return d.buf[d.i]
+}
+
+// Err returns the first error encountered while reading, other than io.EOF at a word
+// boundary.  A stream ending part way through a word returns io.ErrUnexpectedEOF.
+func (d *StreamDecoder) Err() error {
+	if d.err == io.EOF {
+		return nil
+	}
+	return d.err
+}
+
+// WriteTo writes the remaining values to w as 8 byte big endian integers until the
+// stream is exhausted.  It returns the number of bytes written.
+func (d *StreamDecoder) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriterSize(w, streamBufferSize)
+	var b [8]byte
+	var n int64
+	for d.Next() {
+		binary.BigEndian.PutUint64(b[:], d.Read())
+		m, err := bw.Write(b[:])
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return n, err
+	}
+	return n, d.Err()
+}
+
+func (d *StreamDecoder) read() {
+	d.i = 0
+	d.n = 0
+	if d.err != nil {
+		return
+	}
+
+	var b [8]byte
+	if _, err := io.ReadFull(d.r, b[:]); err != nil {
+		d.err = err
+		return
+	}
+
+	d.n, d.err = Decode(&d.buf, binary.BigEndian.Uint64(b[:]))
+}
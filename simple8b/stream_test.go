@@ -0,0 +1,171 @@
+package simple8b_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/jwilder/encoding/simple8b"
+)
+
+func Test_StreamEncoder_MatchesEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	senc := simple8b.NewStreamEncoder(&buf)
+	enc := simple8b.NewEncoder()
+	for i := 0; i < 10000; i++ {
+		v := uint64(i % 300)
+		if i%1000 < 500 {
+			v = 1
+		}
+		senc.Write(v)
+		enc.Write(v)
+	}
+
+	if err := senc.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp, _ := enc.Bytes()
+	if !bytes.Equal(buf.Bytes(), exp) {
+		t.Fatalf("Stream bytes mismatch: got %v bytes, exp %v bytes", buf.Len(), len(exp))
+	}
+
+	dec := simple8b.NewStreamDecoder(&buf)
+	i := 0
+	for dec.Next() {
+		v := uint64(i % 300)
+		if i%1000 < 500 {
+			v = 1
+		}
+		if dec.Read() != v {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, v, dec.Read())
+		}
+		i += 1
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exp, got := 10000, i; got != exp {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", exp, got)
+	}
+}
+
+func Test_StreamEncoder_ValueTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	enc := simple8b.NewStreamEncoder(&buf)
+	enc.Write(simple8b.MaxValue + 1)
+	if err := enc.Flush(); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_StreamEncoder_WriteError(t *testing.T) {
+	enc := simple8b.NewStreamEncoder(errWriter{})
+	var err error
+	for i := 0; i < 100000 && err == nil; i++ {
+		err = enc.Write(uint64(i))
+	}
+	if err == nil {
+		err = enc.Flush()
+	}
+	if err != errWrite {
+		t.Fatalf("Error mismatch: got %v, exp %v", err, errWrite)
+	}
+}
+
+func Test_StreamDecoder_Truncated(t *testing.T) {
+	in := []uint64{1, 2, 3, 1 << 40}
+	encoded, _ := simple8b.EncodeAll(append([]uint64(nil), in...))
+	b := make([]byte, 8*len(encoded))
+	for i, w := range encoded {
+		binary.BigEndian.PutUint64(b[i*8:], w)
+	}
+
+	dec := simple8b.NewStreamDecoder(bytes.NewReader(b[:len(b)-3]))
+	for dec.Next() {
+	}
+	if err := dec.Err(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Error mismatch: got %v, exp %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func Test_Stream_ReadFromWriteTo(t *testing.T) {
+	var raw bytes.Buffer
+	var b [8]byte
+	for i := 0; i < 1000; i++ {
+		binary.BigEndian.PutUint64(b[:], uint64(i*i))
+		raw.Write(b[:])
+	}
+	exp := append([]byte(nil), raw.Bytes()...)
+
+	var encoded bytes.Buffer
+	enc := simple8b.NewStreamEncoder(&encoded)
+	n, err := enc.ReadFrom(&raw)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != int64(len(exp)) {
+		t.Fatalf("ReadFrom len mismatch: got %v, exp %v", n, len(exp))
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var decoded bytes.Buffer
+	dec := simple8b.NewStreamDecoder(&encoded)
+	if _, err := dec.WriteTo(&decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(decoded.Bytes(), exp) {
+		t.Fatalf("WriteTo mismatch: got %v bytes, exp %v bytes", decoded.Len(), len(exp))
+	}
+}
+
+var errWrite = errors.New("write failed")
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
+
+func FuzzStreamDecoder(f *testing.F) {
+	for _, in := range [][]uint64{nil, {1, 2, 3}, {1 << 59, 7, 0, 1 << 30}} {
+		encoded, _ := simple8b.EncodeAll(append([]uint64(nil), in...))
+		f.Add(fuzzBytes(encoded))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, countErr := simple8b.CountBytes(b)
+
+		dec := simple8b.NewStreamDecoder(bytes.NewReader(b))
+		i := 0
+		for dec.Next() {
+			i++
+		}
+		if countErr == nil && (dec.Err() != nil || i != n) {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+		if countErr != nil && dec.Err() == nil {
+			t.Fatalf("Expected error, got nil")
+		}
+	})
+}
+
+func BenchmarkStreamEncoder(b *testing.B) {
+	x := make([]uint64, 1024)
+	for i := 0; i < len(x); i++ {
+		x[i] = uint64(15)
+	}
+
+	enc := simple8b.NewStreamEncoder(io.Discard)
+	b.ResetTimer()
+	b.SetBytes(int64(len(x)) * 8)
+	for i := 0; i < b.N; i++ {
+		for _, v := range x {
+			enc.Write(v)
+		}
+		enc.Flush()
+	}
+}
@@ -13,6 +13,37 @@ func toHex(x []uint64) string {
 	return s
 }
 
+func Test_Pack_Selectors(t *testing.T) {
+	var dst [240]uint64
+	for sel, p := range selector {
+		src := make([]uint64, p.n)
+		for i := range src {
+			// Selectors 0 and 1 hold runs of ones, the others use every bit.
+			src[i] = 1
+			if p.bit > 0 {
+				src[i] = uint64(1)<<uint(p.bit) - 1 - uint64(i%2)
+			}
+		}
+
+		v := p.pack(src)
+		if int(v>>60) != sel {
+			t.Fatalf("Selector %d packed with selector %d", sel, v>>60)
+		}
+		n, err := Decode(&dst, v)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if n != p.n {
+			t.Fatalf("Selector %d len mismatch: got %v, exp %v", sel, n, p.n)
+		}
+		for i := range src {
+			if dst[i] != src[i] {
+				t.Fatalf("Selector %d decoded[%d] != %v, got %v", sel, i, src[i], dst[i])
+			}
+		}
+	}
+}
+
 func BenchmarkUnpack240(b *testing.B) {
 	b.SetBytes(240 * 8)
 	var dst [240]uint64
@@ -0,0 +1,77 @@
+package simple9
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jwilder/encoding"
+)
+
+func init() {
+	encoding.Register(Codec{})
+}
+
+// Codec implements encoding.Codec using simple9, with words serialized as 4 byte
+// big endian integers in the same form as Encoder.Bytes.
+type Codec struct{}
+
+// Name returns "simple9".
+func (Codec) Name() string { return "simple9" }
+
+// ID returns encoding.Simple9.
+func (Codec) ID() byte { return encoding.Simple9 }
+
+// MaxEncodedLen returns the maximum number of bytes used to encode n values, which
+// is one word per value.
+func (Codec) MaxEncodedLen(n int) int { return 4 * n }
+
+// Encode appends the packed words of src to dst.  If a value is over MaxValue, an
+// error is returned.
+func (Codec) Encode(dst []byte, src []uint64) ([]byte, error) {
+	var values [28]uint32
+	var b [4]byte
+	for len(src) > 0 {
+		// Convert at most one word of values at a time.
+		k := 0
+		for ; k < len(values) && k < len(src); k++ {
+			if src[k] > MaxValue {
+				return nil, fmt.Errorf("value out of bounds: %v", src[k])
+			}
+			values[k] = uint32(src[k])
+		}
+
+		v, n, err := Encode(values[:k])
+		if err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint32(b[:], v)
+		dst = append(dst, b[:]...)
+		src = src[n:]
+	}
+	return dst, nil
+}
+
+// Decode writes the values of the packed words in src to dst.  It returns the
+// number of values written or an error.
+func (Codec) Decode(dst []uint64, src []byte) (int, error) {
+	if len(src)%4 != 0 {
+		return 0, fmt.Errorf("invalid slice len remaining: %v", len(src)%4)
+	}
+
+	var buf [28]uint32
+	j := 0
+	for i := 0; i < len(src); i += 4 {
+		n, err := Decode(&buf, binary.BigEndian.Uint32(src[i:]))
+		if err != nil {
+			return 0, err
+		}
+		if j+n > len(dst) {
+			return 0, ErrShortBuffer
+		}
+		for k, v := range buf[:n] {
+			dst[j+k] = uint64(v)
+		}
+		j += n
+	}
+	return j, nil
+}
@@ -1,6 +1,193 @@
+// Package simple9 implements the 32bit integer encoding algorithm as published
+// by Anh and Moffat in "Inverted Index Compressed Using Word-Aligned Binary Codes",
+// Information Retrieval, 8(1):151–166, 2005.
+//
+// It is capable of encoding multiple integers with values between 0 and 2^28 - 1 in
+// a single word.
 package simple9
 
-import "fmt"
+// Simple9 is a 32bit word-sized encoder that packs multiple integers into a single
+// word using a 4 bit selector value and 28 bits for the remaining values.  Integers
+// are encoded using the following table:
+//
+// ┌──────────────┬─────────────────────────────────────┐
+// │   Selector   │   0   1   2   3   4   5   6   7   8 │
+// ├──────────────┼─────────────────────────────────────┤
+// │     Bits     │   1   2   3   4   5   7   9  14  28 │
+// ├──────────────┼─────────────────────────────────────┤
+// │      N       │  28  14   9   7   5   4   3   2   1 │
+// ├──────────────┼─────────────────────────────────────┤
+// │   Wasted Bits│   0   0   1   0   3   0   1   0   0 │
+// └──────────────┴─────────────────────────────────────┘
+//
+// Encoded words are serialized as 4 byte big endian integers.
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const MaxValue = (1 << 28) - 1
+
+var (
+	// ErrShortBuffer is returned when dst is too small to hold the decoded values.
+	ErrShortBuffer = errors.New("simple9: short buffer")
+
+	// ErrInvalidSelector is returned when a word has a selector outside the table.
+	ErrInvalidSelector = errors.New("simple9: invalid selector")
+)
+
+// Encoder converts a stream of unsigned 32bit integers to a compressed byte slice.
+type Encoder struct {
+	// most recently written integers that have not been flushed
+	buf []uint32
+
+	// index in buf of the head of the buf
+	h int
+
+	// index in buf of the tail of the buf
+	t int
+
+	// current bytes written and flushed
+	bytes []byte
+}
+
+// NewEncoder returns an Encoder able to convert uint32s to compressed byte slices
+func NewEncoder() *Encoder {
+	return &Encoder{
+		buf:   make([]uint32, 28),
+		bytes: make([]byte, 0, 128),
+	}
+}
+
+func (e *Encoder) SetValues(v []uint32) {
+	e.buf = v
+	e.t = len(v)
+	e.h = 0
+	e.bytes = e.bytes[:0]
+}
+
+func (e *Encoder) Reset() {
+	e.t = 0
+	e.h = 0
+
+	e.buf = e.buf[:28]
+	e.bytes = e.bytes[:0]
+}
+
+func (e *Encoder) Write(v uint32) error {
+	if e.t >= len(e.buf) {
+		if err := e.flush(); err != nil {
+			return err
+		}
+	}
+
+	// The buf is full but there is space at the front, just shift
+	// the values down for now.
+	if e.t >= len(e.buf) {
+		copy(e.buf, e.buf[e.h:])
+		e.t -= e.h
+		e.h = 0
+	}
+	e.buf[e.t] = v
+	e.t += 1
+	return nil
+}
+
+func (e *Encoder) flush() error {
+	if e.t == 0 {
+		return nil
+	}
+
+	// encode as many values into one as we can
+	encoded, n, err := Encode(e.buf[e.h:e.t])
+	if err != nil {
+		return err
+	}
+
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], encoded)
+	e.bytes = append(e.bytes, b[:]...)
+
+	// Move the head forward since we encoded those values
+	e.h += n
+
+	// If we encoded them all, reset the head/tail pointers to the beginning
+	if e.h == e.t {
+		e.h = 0
+		e.t = 0
+	}
+
+	return nil
+}
+
+func (e *Encoder) Bytes() ([]byte, error) {
+	for e.t > 0 {
+		if err := e.flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.bytes, nil
+}
+
+// Decoder converts a compressed byte slice to a stream of unsigned 32bit integers.
+type Decoder struct {
+	bytes []byte
+	buf   [28]uint32
+	i     int
+	n     int
+	err   error
+}
+
+// NewDecoder returns a Decoder from a byte slice
+func NewDecoder(b []byte) *Decoder {
+	return &Decoder{
+		bytes: b,
+	}
+}
+
+// Next returns true if there are remaining values to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *Decoder) Next() bool {
+	d.i += 1
+
+	if d.i >= d.n {
+		d.read()
+	}
+
+	return d.i < d.n
+}
+
+func (d *Decoder) SetBytes(b []byte) {
+	d.bytes = b
+	d.i = 0
+	d.n = 0
+	d.err = nil
+}
+
+// Read returns the current value.  Successive calls to Read return the same
+// value.
+func (d *Decoder) Read() uint32 {
+	return d.buf[d.i]
+}
+
+func (d *Decoder) read() {
+	d.i = 0
+	d.n = 0
+	if len(d.bytes) < 4 {
+		return
+	}
+
+	v := binary.BigEndian.Uint32(d.bytes[:4])
+	d.bytes = d.bytes[4:]
+	d.n, d.err = Decode(&d.buf, v)
+}
+
+// Err returns the first error encountered while decoding.
+func (d *Decoder) Err() error {
+	return d.err
+}
 
 type packing struct {
 	n, bit int
@@ -20,7 +207,77 @@ var selector [9]packing = [9]packing{
 	packing{1, 28, unpack1, pack1},
 }
 
-// EncodeAll returns a packed slice of the values from src.  It a value is over
+// CountBytes returns the number of integers encoded in the byte slice
+func CountBytes(b []byte) (int, error) {
+	var count int
+	for len(b) >= 4 {
+		v := binary.BigEndian.Uint32(b[:4])
+		b = b[4:]
+		n, err := Count(v)
+		if err != nil {
+			return 0, err
+		}
+
+		count += n
+	}
+
+	if len(b) > 0 {
+		return 0, fmt.Errorf("invalid slice len remaining: %v", len(b))
+	}
+	return count, nil
+}
+
+// Count returns the number of integers encoded within an uint32
+func Count(v uint32) (int, error) {
+	sel := v >> 28
+	if sel >= 9 {
+		return 0, ErrInvalidSelector
+	}
+	return selector[sel].n, nil
+}
+
+// Encode packs as many values into a single uint32.  It returns the packed
+// uint32, how many values from src were packed, or an error if the values exceed
+// the maximum value range.
+func Encode(src []uint32) (value uint32, n int, err error) {
+	if canPack(src, 1, 28) {
+		return pack28(src[:28]), 28, nil
+	} else if canPack(src, 2, 14) {
+		return pack14(src[:14]), 14, nil
+	} else if canPack(src, 3, 9) {
+		return pack9(src[:9]), 9, nil
+	} else if canPack(src, 4, 7) {
+		return pack7(src[:7]), 7, nil
+	} else if canPack(src, 5, 5) {
+		return pack5(src[:5]), 5, nil
+	} else if canPack(src, 7, 4) {
+		return pack4(src[:4]), 4, nil
+	} else if canPack(src, 9, 3) {
+		return pack3(src[:3]), 3, nil
+	} else if canPack(src, 14, 2) {
+		return pack2(src[:2]), 2, nil
+	} else if canPack(src, 28, 1) {
+		return pack1(src[:1]), 1, nil
+	} else {
+		if len(src) > 0 {
+			return 0, 0, fmt.Errorf("value out of bounds: %v", src)
+		}
+		return 0, 0, nil
+	}
+}
+
+// Decode writes the uncompressed values in v to dst.  It returns the number of
+// values written or an error.
+func Decode(dst *[28]uint32, v uint32) (n int, err error) {
+	sel := v >> 28
+	if sel >= 9 {
+		return 0, ErrInvalidSelector
+	}
+	selector[sel].unpack(v, dst[:])
+	return selector[sel].n, nil
+}
+
+// EncodeAll returns a packed slice of the values from src.  If a value is over
 // 1 << 28, an error is returned.
 func EncodeAll(src []uint32) ([]uint32, error) {
 	i := 0
@@ -68,16 +325,22 @@ func EncodeAll(src []uint32) ([]uint32, error) {
 	return dst[:j], nil
 }
 
-// DecodeAll returns the uncompressed values from in
+// DecodeAll writes the uncompressed values from src to dst.  It returns
+// ErrShortBuffer if dst cannot hold them or ErrInvalidSelector if a word is
+// invalid.
 func DecodeAll(dst, src []uint32) error {
 	j := 0
 	for _, v := range src {
 		sel := v >> 28
 		if sel >= 9 {
-			return fmt.Errorf("invalid selector value: %b", sel)
+			return ErrInvalidSelector
+		}
+		n := selector[sel].n
+		if j+n > len(dst) {
+			return ErrShortBuffer
 		}
 		selector[sel].unpack(v, dst[j:])
-		j += selector[sel].n
+		j += n
 	}
 	return nil
 }
@@ -222,99 +485,99 @@ func pack1(in []uint32) uint32 {
 }
 
 func unpack28(in uint32, out []uint32) {
-	out[0] = (in >> 27) & 1
-	out[1] = (in >> 26) & 1
-	out[2] = (in >> 25) & 1
-	out[3] = (in >> 24) & 1
-	out[4] = (in >> 23) & 1
-	out[5] = (in >> 22) & 1
-	out[6] = (in >> 21) & 1
-	out[7] = (in >> 20) & 1
-	out[8] = (in >> 19) & 1
-	out[9] = (in >> 18) & 1
-	out[10] = (in >> 17) & 1
-	out[11] = (in >> 16) & 1
-	out[12] = (in >> 15) & 1
-	out[13] = (in >> 14) & 1
-	out[14] = (in >> 13) & 1
-	out[15] = (in >> 12) & 1
-	out[16] = (in >> 11) & 1
-	out[17] = (in >> 10) & 1
-	out[18] = (in >> 9) & 1
-	out[19] = (in >> 8) & 1
-	out[20] = (in >> 7) & 1
-	out[21] = (in >> 6) & 1
-	out[22] = (in >> 5) & 1
-	out[23] = (in >> 4) & 1
-	out[24] = (in >> 3) & 1
-	out[25] = (in >> 2) & 1
-	out[26] = (in >> 1) & 1
-	out[27] = in & 1
+	out[0] = in & 1
+	out[1] = (in >> 1) & 1
+	out[2] = (in >> 2) & 1
+	out[3] = (in >> 3) & 1
+	out[4] = (in >> 4) & 1
+	out[5] = (in >> 5) & 1
+	out[6] = (in >> 6) & 1
+	out[7] = (in >> 7) & 1
+	out[8] = (in >> 8) & 1
+	out[9] = (in >> 9) & 1
+	out[10] = (in >> 10) & 1
+	out[11] = (in >> 11) & 1
+	out[12] = (in >> 12) & 1
+	out[13] = (in >> 13) & 1
+	out[14] = (in >> 14) & 1
+	out[15] = (in >> 15) & 1
+	out[16] = (in >> 16) & 1
+	out[17] = (in >> 17) & 1
+	out[18] = (in >> 18) & 1
+	out[19] = (in >> 19) & 1
+	out[20] = (in >> 20) & 1
+	out[21] = (in >> 21) & 1
+	out[22] = (in >> 22) & 1
+	out[23] = (in >> 23) & 1
+	out[24] = (in >> 24) & 1
+	out[25] = (in >> 25) & 1
+	out[26] = (in >> 26) & 1
+	out[27] = (in >> 27) & 1
 }
 
 func unpack14(in uint32, out []uint32) {
-	out[0] = (in >> 26) & 3
-	out[1] = (in >> 24) & 3
-	out[2] = (in >> 22) & 3
-	out[3] = (in >> 20) & 3
-	out[4] = (in >> 18) & 3
-	out[5] = (in >> 16) & 3
-	out[6] = (in >> 14) & 3
-	out[7] = (in >> 12) & 3
-	out[8] = (in >> 10) & 3
-	out[9] = (in >> 8) & 3
-	out[10] = (in >> 6) & 3
-	out[11] = (in >> 4) & 3
-	out[12] = (in >> 2) & 3
-	out[13] = in & 3
+	out[0] = in & 3
+	out[1] = (in >> 2) & 3
+	out[2] = (in >> 4) & 3
+	out[3] = (in >> 6) & 3
+	out[4] = (in >> 8) & 3
+	out[5] = (in >> 10) & 3
+	out[6] = (in >> 12) & 3
+	out[7] = (in >> 14) & 3
+	out[8] = (in >> 16) & 3
+	out[9] = (in >> 18) & 3
+	out[10] = (in >> 20) & 3
+	out[11] = (in >> 22) & 3
+	out[12] = (in >> 24) & 3
+	out[13] = (in >> 26) & 3
 }
 
 func unpack9(in uint32, out []uint32) {
-	out[0] = (in >> 25) & 7
-	out[1] = (in >> 22) & 7
-	out[2] = (in >> 19) & 7
-	out[3] = (in >> 16) & 7
+	out[0] = (in >> 1) & 7
+	out[1] = (in >> 4) & 7
+	out[2] = (in >> 7) & 7
+	out[3] = (in >> 10) & 7
 	out[4] = (in >> 13) & 7
-	out[5] = (in >> 10) & 7
-	out[6] = (in >> 7) & 7
-	out[7] = (in >> 4) & 7
-	out[8] = (in >> 1) & 7
+	out[5] = (in >> 16) & 7
+	out[6] = (in >> 19) & 7
+	out[7] = (in >> 22) & 7
+	out[8] = (in >> 25) & 7
 }
 
 func unpack7(in uint32, out []uint32) {
-	out[0] = (in >> 24) & 15
-	out[1] = (in >> 20) & 15
-	out[2] = (in >> 16) & 15
+	out[0] = in & 15
+	out[1] = (in >> 4) & 15
+	out[2] = (in >> 8) & 15
 	out[3] = (in >> 12) & 15
-	out[4] = (in >> 8) & 15
-	out[5] = (in >> 4) & 15
-	out[6] = in & 15
+	out[4] = (in >> 16) & 15
+	out[5] = (in >> 20) & 15
+	out[6] = (in >> 24) & 15
 }
 
 func unpack5(in uint32, out []uint32) {
-	out[0] = (in >> 23) & 31
-	out[1] = (in >> 18) & 31
+	out[0] = (in >> 3) & 31
+	out[1] = (in >> 8) & 31
 	out[2] = (in >> 13) & 31
-	out[3] = (in >> 8) & 31
-	out[4] = (in >> 3) & 31
+	out[3] = (in >> 18) & 31
+	out[4] = (in >> 23) & 31
 }
 
 func unpack4(in uint32, out []uint32) {
-	out[0] = (in >> 21) & 63
-	out[1] = (in >> 14) & 63
-	out[2] = (in >> 7) & 63
-	out[3] = in & 63
+	out[0] = in & 127
+	out[1] = (in >> 7) & 127
+	out[2] = (in >> 14) & 127
+	out[3] = (in >> 21) & 127
 }
 
 func unpack3(in uint32, out []uint32) {
-	out[0] = (in >> 19) & 511
+	out[0] = (in >> 1) & 511
 	out[1] = (in >> 10) & 511
-	out[2] = (in >> 1) & 511
+	out[2] = (in >> 19) & 511
 }
 
 func unpack2(in uint32, out []uint32) {
-	out[0] = (in >> 14) & 16383
-	out[1] = in & 16383
+	out[0] = in & 16383
+	out[1] = (in >> 14) & 16383
 }
 
 func unpack1(in uint32, out []uint32) {
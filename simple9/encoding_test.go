@@ -1,6 +1,9 @@
 package simple9
 
-import "testing"
+import (
+	"encoding/binary"
+	"testing"
+)
 
 // Tests that a since canPack returns whether a uint32 is packaable int the given
 // number of bits (up to 28)
@@ -304,6 +307,28 @@ func Test_FewValues(t *testing.T) {
 	}
 }
 
+func Test_EncodeAll_Distinct(t *testing.T) {
+	// Distinct values catch words unpacked in a different order than packed.
+	in := make([]uint32, 1000)
+	for i := range in {
+		in[i] = uint32(i*i) % (1 << uint(i%29))
+	}
+	encoded, err := EncodeAll(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded := make([]uint32, len(in))
+	if err := DecodeAll(decoded, encoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
 func BenchmarkEncodeAll(b *testing.B) {
 	total := 0
 	x := make([]uint32, 1024)
@@ -337,3 +362,157 @@ func BenchmarkDecodeAll(b *testing.B) {
 		total += len(decoded)
 	}
 }
+
+func Test_Encoder_Distinct(t *testing.T) {
+	enc := NewEncoder()
+	in := make([]uint32, 1000)
+	for i := range in {
+		in[i] = uint32(i*i) % (1 << uint(i%29))
+		if err := enc.Write(in[i]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	b, err := enc.Bytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := NewDecoder(b)
+	i := 0
+	for dec.Next() {
+		if i >= len(in) {
+			t.Fatalf("Decoded too many values: got %v, exp %v", i, len(in))
+		}
+		if dec.Read() != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], dec.Read())
+		}
+		i += 1
+	}
+	if exp, got := len(in), i; got != exp {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", exp, got)
+	}
+
+	got, err := CountBytes(b)
+	if err != nil {
+		t.Fatalf("Unexpected error in Count: %v", err)
+	}
+	if got != len(in) {
+		t.Fatalf("Count mismatch: got %v, exp %v", got, len(in))
+	}
+
+	encoded, _ := EncodeAll(in)
+	if exp, got := len(b), len(encoded)*4; got != exp {
+		t.Fatalf("EncodeAll len mismatch: exp %v, got %v", exp, got)
+	}
+	decoded := make([]uint32, len(in)+28)
+	_ = DecodeAll(decoded, encoded)
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("DecodeAll[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
+func Test_Encoder_ValueTooLarge(t *testing.T) {
+	enc := NewEncoder()
+	enc.Write(MaxValue + 1)
+	if _, err := enc.Bytes(); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_CountBytes_Invalid(t *testing.T) {
+	if _, err := CountBytes([]byte{0x90, 0, 0, 0}); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+	if _, err := CountBytes([]byte{0, 0}); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_DecodeAll_Invalid(t *testing.T) {
+	if err := DecodeAll(make([]uint32, 27), []uint32{0}); err != ErrShortBuffer {
+		t.Fatalf("Error mismatch: got %v, exp %v", err, ErrShortBuffer)
+	}
+	if err := DecodeAll(make([]uint32, 28), []uint32{9 << 28}); err != ErrInvalidSelector {
+		t.Fatalf("Error mismatch: got %v, exp %v", err, ErrInvalidSelector)
+	}
+
+	dec := NewDecoder([]byte{0x80, 0, 0, 1, 0x90, 0, 0, 0})
+	n := 0
+	for dec.Next() {
+		n++
+	}
+	if n != 1 || dec.Err() != ErrInvalidSelector {
+		t.Fatalf("Decoder mismatch: got %v values and %v, exp 1 and %v", n, dec.Err(), ErrInvalidSelector)
+	}
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	for _, in := range [][]uint32{nil, {1, 2, 3}, {1 << 27, 7, 0, 1 << 20}, make([]uint32, 100)} {
+		encoded, _ := EncodeAll(in)
+		b := make([]byte, 4*len(encoded))
+		for i, w := range encoded {
+			binary.BigEndian.PutUint32(b[i*4:], w)
+		}
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		src := make([]uint32, len(b)/4)
+		for i := range src {
+			src[i] = binary.BigEndian.Uint32(b[i*4:])
+		}
+
+		n, err := CountBytes(b)
+		if err == ErrInvalidSelector {
+			if DecodeAll(make([]uint32, 28*len(src)), src) != ErrInvalidSelector {
+				t.Fatalf("Expected ErrInvalidSelector")
+			}
+		}
+		if err != nil {
+			return
+		}
+
+		dst := make([]uint32, n)
+		if err := DecodeAll(dst, src); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if n > 0 {
+			if err := DecodeAll(make([]uint32, n-1), src); err != ErrShortBuffer {
+				t.Fatalf("Error mismatch: got %v, exp %v", err, ErrShortBuffer)
+			}
+		}
+
+		dec := NewDecoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	enc := NewEncoder()
+	for i := 0; i < 1024; i++ {
+		enc.Write(15)
+	}
+	y, _ := enc.Bytes()
+
+	b.ResetTimer()
+	b.SetBytes(1024 * 4)
+
+	dec := NewDecoder(y)
+	for i := 0; i < b.N; i++ {
+		dec.SetBytes(y)
+		for dec.Next() {
+		}
+	}
+}
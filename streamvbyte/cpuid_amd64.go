@@ -0,0 +1,4 @@
+package streamvbyte
+
+//go:noescape
+func cpu_info()
@@ -0,0 +1,27 @@
+package streamvbyte
+
+// The SIMD kernels load 16 data bytes per control byte and shuffle them into four
+// values with PSHUFB, using shuffleTable.  The AVX2 kernel shuffles two control
+// bytes per instruction.  Both stop when fewer than 16 bytes, or 32 for a pair,
+// remain in data so that loads never read past its end.
+
+//go:noescape
+func decodeGroupsSSSE3(dst []uint32, ctrl, data []byte) (groups, read int)
+
+//go:noescape
+func decodeGroupsAVX2(dst []uint32, ctrl, data []byte) (groups, read int)
+
+var (
+	support_ssse3 bool
+	support_avx2  bool
+)
+
+func init() {
+	cpu_info()
+	switch {
+	case support_avx2:
+		decodeGroups = decodeGroupsAVX2
+	case support_ssse3:
+		decodeGroups = decodeGroupsSSSE3
+	}
+}
@@ -0,0 +1,119 @@
+#include "textflag.h"
+
+// Register use by the kernels:
+//
+//	DI  next value of dst          AX  control bytes decoded
+//	SI  control bytes              CX  number of control bytes
+//	DX  next data byte             R8  data bytes remaining
+//	R9  shuffleTable               R10 lengthTable
+//	R11 start of data
+
+// func decodeGroupsSSSE3(dst []uint32, ctrl, data []byte) (groups, read int)
+TEXT ·decodeGroupsSSSE3(SB), NOSPLIT, $0-88
+	MOVQ dst_base+0(FP), DI
+	MOVQ ctrl_base+24(FP), SI
+	MOVQ ctrl_len+32(FP), CX
+	MOVQ data_base+48(FP), DX
+	MOVQ data_len+56(FP), R8
+	LEAQ ·shuffleTable(SB), R9
+	LEAQ ·lengthTable(SB), R10
+	MOVQ DX, R11
+	XORQ AX, AX
+
+loop:
+	CMPQ AX, CX
+	JGE  done
+	CMPQ R8, $16
+	JLT  done
+
+	MOVBQZX (SI)(AX*1), BX
+	MOVOU   (DX), X0
+	MOVQ    BX, R12
+	SHLQ    $4, R12
+	MOVOU   (R9)(R12*1), X1
+	PSHUFB  X1, X0
+	MOVOU   X0, (DI)
+
+	MOVBQZX (R10)(BX*1), R12
+	ADDQ    R12, DX
+	SUBQ    R12, R8
+	ADDQ    $16, DI
+	INCQ    AX
+	JMP     loop
+
+done:
+	MOVQ AX, groups+72(FP)
+	SUBQ R11, DX
+	MOVQ DX, read+80(FP)
+	RET
+
+// func decodeGroupsAVX2(dst []uint32, ctrl, data []byte) (groups, read int)
+TEXT ·decodeGroupsAVX2(SB), NOSPLIT, $0-88
+	MOVQ dst_base+0(FP), DI
+	MOVQ ctrl_base+24(FP), SI
+	MOVQ ctrl_len+32(FP), CX
+	MOVQ data_base+48(FP), DX
+	MOVQ data_len+56(FP), R8
+	LEAQ ·shuffleTable(SB), R9
+	LEAQ ·lengthTable(SB), R10
+	MOVQ DX, R11
+	XORQ AX, AX
+
+pair:
+	MOVQ CX, R13
+	SUBQ AX, R13
+	CMPQ R13, $2
+	JLT  single
+	CMPQ R8, $32
+	JLT  single
+
+	// The data of the second control byte follows that of the first, so the
+	// two are loaded into separate lanes and shuffled together.
+	MOVBQZX     (SI)(AX*1), BX
+	MOVBQZX     1(SI)(AX*1), R14
+	MOVBQZX     (R10)(BX*1), R12
+	VMOVDQU     (DX), X0
+	VINSERTI128 $1, (DX)(R12*1), Y0, Y0
+	MOVQ        BX, R13
+	SHLQ        $4, R13
+	VMOVDQU     (R9)(R13*1), X1
+	MOVQ        R14, R13
+	SHLQ        $4, R13
+	VINSERTI128 $1, (R9)(R13*1), Y1, Y1
+	VPSHUFB     Y1, Y0, Y0
+	VMOVDQU     Y0, (DI)
+
+	MOVBQZX (R10)(R14*1), R13
+	ADDQ    R12, R13
+	ADDQ    R13, DX
+	SUBQ    R13, R8
+	ADDQ    $32, DI
+	ADDQ    $2, AX
+	JMP     pair
+
+single:
+	CMPQ AX, CX
+	JGE  done
+	CMPQ R8, $16
+	JLT  done
+
+	MOVBQZX (SI)(AX*1), BX
+	VMOVDQU (DX), X0
+	MOVQ    BX, R12
+	SHLQ    $4, R12
+	VPSHUFB (R9)(R12*1), X0, X0
+	VMOVDQU X0, (DI)
+
+	MOVBQZX (R10)(BX*1), R12
+	ADDQ    R12, DX
+	SUBQ    R12, R8
+	ADDQ    $16, DI
+	INCQ    AX
+	JMP     single
+
+done:
+	VZEROUPPER
+	MOVQ AX, groups+72(FP)
+	SUBQ R11, DX
+	MOVQ DX, read+80(FP)
+	RET
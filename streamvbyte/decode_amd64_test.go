@@ -0,0 +1,56 @@
+package streamvbyte
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func testDecodeGroups(t *testing.T, name string, fn func(dst []uint32, ctrl, data []byte) (int, int)) {
+	for _, n := range []int{0, 4, 8, 12, 400, 4000} {
+		src := make([]uint32, n)
+		for i := range src {
+			src[i] = uint32(rand.Uint64() >> uint(32+rand.Intn(33)))
+		}
+		b, _ := EncodeAll(src)
+		n, ctrl, data, err := readHeader(b)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		exp := make([]uint32, n)
+		_, expRead := decodeGroupsScalar(exp, ctrl, data)
+
+		got := make([]uint32, n)
+		groups, read := fn(got, ctrl, data)
+		if groups > len(ctrl) || read > len(data) {
+			t.Fatalf("%s: consumed %d groups, %d bytes of %d, %d", name, groups, read, len(ctrl), len(data))
+		}
+		if len(data)-read >= 32 && groups < len(ctrl) {
+			t.Fatalf("%s: stopped at group %d of %d with %d bytes left", name, groups, len(ctrl), len(data)-read)
+		}
+
+		_, r := decodeGroupsScalar(got[4*groups:], ctrl[groups:], data[read:])
+		if read+r != expRead {
+			t.Fatalf("%s: read %d bytes, exp %d", name, read+r, expRead)
+		}
+		for i := range exp {
+			if got[i] != exp[i] {
+				t.Fatalf("%s: got[%d] = %d, exp %d", name, i, got[i], exp[i])
+			}
+		}
+	}
+}
+
+func TestDecodeGroupsSSSE3(t *testing.T) {
+	if !support_ssse3 {
+		t.Skip("SSSE3 not supported")
+	}
+	testDecodeGroups(t, "decodeGroupsSSSE3", decodeGroupsSSSE3)
+}
+
+func TestDecodeGroupsAVX2(t *testing.T) {
+	if !support_avx2 {
+		t.Skip("AVX2 not supported")
+	}
+	testDecodeGroups(t, "decodeGroupsAVX2", decodeGroupsAVX2)
+}
@@ -0,0 +1,279 @@
+// Package streamvbyte implements the Stream VByte integer encoding as published by
+// Lemire, Kurz and Rupp in "Stream VByte: Faster Byte-Oriented Integer
+// Compression", Information Processing Letters 130, 2018.
+//
+// Each 32bit value is stored in 1 to 4 little endian bytes.  Unlike a varint, the
+// lengths are kept apart from the data in a stream of control bytes, each holding a
+// 2 bit length code for four values.  A control byte then selects a shuffle that
+// expands the next 16 data bytes into four values with a single SIMD instruction.
+package streamvbyte
+
+// The encoded form starts with the number of values as a uvarint, followed by
+// ⌈n/4⌉ control bytes and then the data bytes.  The code of value i is held in bits
+// 2*(i%4) to 2*(i%4)+1 of control byte i/4 and is the number of data bytes less
+// one.  Unused codes of the last control byte are zero and have no data bytes.
+//
+// ┌─────────┬──────────────────────┬────────────────────────────────┐
+// │  Count  │    Control bytes     │           Data bytes           │
+// ├─────────┼──────────────────────┼────────────────────────────────┤
+// │ uvarint │       ⌈n/4⌉          │  1 to 4 per value, LE order    │
+// └─────────┴──────────────────────┴────────────────────────────────┘
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jwilder/encoding/bitops"
+)
+
+// shuffleTable holds the byte shuffle expanding the data bytes of a control byte
+// into four values, and lengthTable the number of data bytes it covers.  Shuffle
+// entries of 0xff zero the destination byte.
+var (
+	shuffleTable [256][16]uint8
+	lengthTable  [256]uint8
+)
+
+// decodeGroups decodes whole control bytes from ctrl into dst, four values each.
+// It returns the number of control bytes and data bytes consumed, which may stop
+// short of the end when a SIMD kernel is near the end of data.
+var decodeGroups = decodeGroupsScalar
+
+func init() {
+	for c := 0; c < 256; c++ {
+		var k uint8
+		for i := 0; i < 4; i++ {
+			n := uint8(c>>(2*uint(i))&3) + 1
+			for j := uint8(0); j < 4; j++ {
+				if j < n {
+					shuffleTable[c][4*i+int(j)] = k + j
+				} else {
+					shuffleTable[c][4*i+int(j)] = 0xff
+				}
+			}
+			k += n
+		}
+		lengthTable[c] = k
+	}
+}
+
+// MaxEncodedLen returns the maximum number of bytes used to encode n values.
+func MaxEncodedLen(n int) int {
+	return binary.MaxVarintLen64 + (n+3)/4 + 4*n
+}
+
+// EncodeAll returns the values from src encoded as Stream VByte.  Any uint32 fits
+// in the 1 to 4 data bytes a control code can describe, so encoding cannot fail.
+func EncodeAll(src []uint32) ([]byte, error) {
+	dst := make([]byte, MaxEncodedLen(len(src)))
+	return dst[:encode(dst, src)], nil
+}
+
+// EncodeAllDelta returns the differences between consecutive values from src
+// encoded as Stream VByte.  The first value is relative to zero.  It is best suited
+// to ascending values, though any values may be encoded.
+func EncodeAllDelta(src []uint32) ([]byte, error) {
+	deltas := make([]uint32, len(src))
+	var prev uint32
+	for i, v := range src {
+		deltas[i] = v - prev
+		prev = v
+	}
+	return EncodeAll(deltas)
+}
+
+// EncodeAllZigZag returns the signed values from src zig zag encoded and stored as
+// Stream VByte, so that values close to zero use few bytes.
+func EncodeAllZigZag(src []int32) ([]byte, error) {
+	values := make([]uint32, len(src))
+	for i, v := range src {
+		values[i] = bitops.ZigZagEncode32(v)
+	}
+	return EncodeAll(values)
+}
+
+// DecodeAll writes the values encoded in src to dst.  It returns the number of
+// values written or an error.
+func DecodeAll(dst []uint32, src []byte) (int, error) {
+	n, ctrl, data, err := readHeader(src)
+	if err != nil {
+		return 0, err
+	}
+	if n > len(dst) {
+		return 0, fmt.Errorf("dst too small: need %d values, have %d", n, len(dst))
+	}
+
+	if err := checkData(n, ctrl, data); err != nil {
+		return 0, err
+	}
+	decode(dst[:n], ctrl, data)
+	return n, nil
+}
+
+// DecodeAllDelta writes the values encoded by EncodeAllDelta in src to dst.  It
+// returns the number of values written or an error.
+func DecodeAllDelta(dst []uint32, src []byte) (int, error) {
+	n, err := DecodeAll(dst, src)
+	if err != nil {
+		return 0, err
+	}
+
+	var prev uint32
+	for i := range dst[:n] {
+		prev += dst[i]
+		dst[i] = prev
+	}
+	return n, nil
+}
+
+// DecodeAllZigZag writes the signed values encoded by EncodeAllZigZag in src to dst.
+// It returns the number of values written or an error.
+func DecodeAllZigZag(dst []int32, src []byte) (int, error) {
+	n, ctrl, data, err := readHeader(src)
+	if err != nil {
+		return 0, err
+	}
+	if n > len(dst) {
+		return 0, fmt.Errorf("dst too small: need %d values, have %d", n, len(dst))
+	}
+
+	if err := checkData(n, ctrl, data); err != nil {
+		return 0, err
+	}
+
+	values := make([]uint32, n)
+	decode(values, ctrl, data)
+	for i, v := range values {
+		dst[i] = bitops.ZigZagDecode32(v)
+	}
+	return n, nil
+}
+
+// Count returns the number of values encoded in src.
+func Count(src []byte) (int, error) {
+	n, _, _, err := readHeader(src)
+	return n, err
+}
+
+// encode writes the header, control and data bytes for src to dst, which must hold
+// at least MaxEncodedLen(len(src)) bytes.  It returns the number of bytes written.
+func encode(dst []byte, src []uint32) int {
+	h := binary.PutUvarint(dst, uint64(len(src)))
+	ctrl := dst[h : h+(len(src)+3)/4]
+	for i := range ctrl {
+		ctrl[i] = 0
+	}
+
+	p := h + len(ctrl)
+	for i, v := range src {
+		var code uint8
+		switch {
+		case v < 1<<8:
+			code = 0
+		case v < 1<<16:
+			code = 1
+		case v < 1<<24:
+			code = 2
+		default:
+			code = 3
+		}
+		ctrl[i/4] |= code << (2 * uint(i%4))
+
+		// All 4 bytes are written, and the unused ones overwritten by the next value.
+		binary.LittleEndian.PutUint32(dst[p:], v)
+		p += int(code) + 1
+	}
+	return p
+}
+
+// decode writes the values of ctrl and data to dst, which has the length of the
+// encoded values.  The data must have been validated with checkData.
+func decode(dst []uint32, ctrl, data []byte) {
+	full := len(dst) / 4
+	groups, read := decodeGroups(dst[:4*full], ctrl[:full], data)
+	if groups < full {
+		_, r := decodeGroupsScalar(dst[4*groups:4*full], ctrl[groups:full], data[read:])
+		read += r
+	}
+
+	// The last control byte may be partially used.
+	if full < len(ctrl) {
+		c := ctrl[full]
+		for i := 4 * full; i < len(dst); i++ {
+			read += decodeValue(&dst[i], c&3, data[read:])
+			c >>= 2
+		}
+	}
+}
+
+// decodeGroupsScalar decodes all of ctrl into dst.
+func decodeGroupsScalar(dst []uint32, ctrl, data []byte) (groups, read int) {
+	for i, c := range ctrl {
+		for j := 0; j < 4; j++ {
+			read += decodeValue(&dst[4*i+j], c&3, data[read:])
+			c >>= 2
+		}
+	}
+	return len(ctrl), read
+}
+
+// decodeValue reads a value with the length code from data into v and returns the
+// number of bytes read.
+func decodeValue(v *uint32, code byte, data []byte) int {
+	switch code {
+	case 0:
+		*v = uint32(data[0])
+	case 1:
+		*v = uint32(data[0]) | uint32(data[1])<<8
+	case 2:
+		*v = uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16
+	default:
+		*v = binary.LittleEndian.Uint32(data)
+	}
+	return int(code) + 1
+}
+
+// readHeader returns the number of values, control bytes and data bytes of src.
+func readHeader(src []byte) (n int, ctrl, data []byte, err error) {
+	if len(src) == 0 {
+		return 0, nil, nil, nil
+	}
+
+	count, h := binary.Uvarint(src)
+	if h <= 0 {
+		return 0, nil, nil, fmt.Errorf("invalid count header")
+	}
+	src = src[h:]
+
+	if count > uint64(len(src)) {
+		// Every value uses at least one data byte.
+		return 0, nil, nil, fmt.Errorf("invalid count %d for %d bytes", count, len(src))
+	}
+	n = int(count)
+
+	c := (n + 3) / 4
+	if c > len(src) {
+		return 0, nil, nil, fmt.Errorf("unexpected end of control bytes")
+	}
+	return n, src[:c], src[c:], nil
+}
+
+// checkData returns an error if data is shorter than the lengths given by ctrl for
+// n values.
+func checkData(n int, ctrl, data []byte) error {
+	need := 0
+	for _, c := range ctrl[:n/4] {
+		need += int(lengthTable[c])
+	}
+	if n%4 != 0 {
+		c := ctrl[n/4]
+		for i := 0; i < n%4; i++ {
+			need += int(c&3) + 1
+			c >>= 2
+		}
+	}
+
+	if need > len(data) {
+		return fmt.Errorf("unexpected end of data: need %d bytes, have %d", need, len(data))
+	}
+	return nil
+}
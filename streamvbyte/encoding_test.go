@@ -0,0 +1,181 @@
+package streamvbyte_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/jwilder/encoding/streamvbyte"
+)
+
+func Test_EncodeAll(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 4, 5, 31, 32, 33, 1000} {
+		in := make([]uint32, n)
+		for i := range in {
+			in[i] = uint32(rand.Uint64() >> uint(32+rand.Intn(33)))
+		}
+
+		encoded, err := streamvbyte.EncodeAll(in)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(encoded) > streamvbyte.MaxEncodedLen(n) {
+			t.Fatalf("Encoded len %d exceeds max %d", len(encoded), streamvbyte.MaxEncodedLen(n))
+		}
+		if count, err := streamvbyte.Count(encoded); err != nil || count != n {
+			t.Fatalf("Count mismatch: got %v %v, exp %v", count, err, n)
+		}
+
+		decoded := make([]uint32, n)
+		m, err := streamvbyte.DecodeAll(decoded, encoded)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if m != n {
+			t.Fatalf("Len mismatch: got %v, exp %v", m, n)
+		}
+		for i := range in {
+			if decoded[i] != in[i] {
+				t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+			}
+		}
+	}
+}
+
+func Test_EncodeAll_Lengths(t *testing.T) {
+	in := []uint32{0, 0xff, 0x100, 0xffff, 0x10000, 0xffffff, 0x1000000, math.MaxUint32}
+	encoded, err := streamvbyte.EncodeAll(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 1 count byte, 2 control bytes and 1+1+2+2+3+3+4+4 data bytes.
+	if exp := 1 + 2 + 20; len(encoded) != exp {
+		t.Fatalf("Encoded len mismatch: got %v, exp %v", len(encoded), exp)
+	}
+	if encoded[1] != 0x50 || encoded[2] != 0xfa {
+		t.Fatalf("Control bytes mismatch: got %x %x", encoded[1], encoded[2])
+	}
+}
+
+func Test_EncodeAllDelta(t *testing.T) {
+	in := make([]uint32, 1000)
+	var v uint32
+	for i := range in {
+		v += uint32(rand.Intn(300))
+		in[i] = v
+	}
+
+	encoded, err := streamvbyte.EncodeAllDelta(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if plain, _ := streamvbyte.EncodeAll(in); len(encoded) >= len(plain) {
+		t.Fatalf("Delta encoding not smaller: %d >= %d", len(encoded), len(plain))
+	}
+
+	decoded := make([]uint32, len(in))
+	n, err := streamvbyte.DecodeAllDelta(decoded, encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("Len mismatch: got %v, exp %v", n, len(in))
+	}
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
+func Test_EncodeAllZigZag(t *testing.T) {
+	in := []int32{0, -1, 1, -128, 127, math.MinInt32, math.MaxInt32, -70000}
+	encoded, err := streamvbyte.EncodeAllZigZag(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded := make([]int32, len(in))
+	n, err := streamvbyte.DecodeAllZigZag(decoded, encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("Len mismatch: got %v, exp %v", n, len(in))
+	}
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
+func Test_DecodeAll_Invalid(t *testing.T) {
+	in := make([]uint32, 100)
+	for i := range in {
+		in[i] = uint32(i * 1000)
+	}
+	encoded, err := streamvbyte.EncodeAll(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := streamvbyte.DecodeAll(make([]uint32, 99), encoded); err == nil {
+		t.Fatalf("Expected error for short dst, got nil")
+	}
+	for _, n := range []int{1, 10, len(encoded) - 1} {
+		if _, err := streamvbyte.DecodeAll(make([]uint32, 100), encoded[:n]); err == nil {
+			t.Fatalf("Expected error for input truncated to %d bytes, got nil", n)
+		}
+	}
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	for _, in := range [][]uint32{nil, {1, 2, 3}, {1 << 8, 1 << 16, 1 << 24, math.MaxUint32, 0}} {
+		encoded, _ := streamvbyte.EncodeAll(in)
+		f.Add(encoded)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := streamvbyte.Count(b)
+		if err != nil || n > 1<<20 {
+			return
+		}
+
+		dst := make([]uint32, n)
+		got, err := streamvbyte.DecodeAll(dst, b)
+		if err != nil {
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAll mismatch: got %v, exp %v", got, n)
+		}
+		if n > 0 {
+			if _, err := streamvbyte.DecodeAll(make([]uint32, n-1), b); err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+		}
+		if _, err := streamvbyte.DecodeAllDelta(dst, b); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := streamvbyte.DecodeAllZigZag(make([]int32, n), b); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func BenchmarkDecodeAll(b *testing.B) {
+	x := make([]uint32, 1024)
+	for i := range x {
+		x[i] = uint32(rand.Uint64() >> uint(32+rand.Intn(33)))
+	}
+	encoded, _ := streamvbyte.EncodeAll(x)
+	dst := make([]uint32, len(x))
+
+	b.SetBytes(int64(len(x) * 4))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		streamvbyte.DecodeAll(dst, encoded)
+	}
+}
@@ -0,0 +1,407 @@
+// Package timestamp implements compression of 64bit timestamps, such as Unix
+// nanosecond times, using delta-of-delta encoding.
+//
+// Timestamps in time series are usually taken at regular intervals and rounded to
+// some unit of time.  The encoder removes the largest power of ten that divides
+// every delta (ns→µs→ms→s and beyond) and stores constant intervals as a single
+// run.  Otherwise the zigzagged delta-of-deltas are packed with simple8b, falling
+// back to uncompressed values when they exceed its range.
+package timestamp
+
+// The first byte of the encoded form holds the encoding type in the high 4 bits
+// and the base 10 logarithm of the common divisor in the low 4 bits.  The first
+// timestamp follows as 8 bytes.
+//
+// ┌──────────────┬────────────────────────────────────────────────────────────────┐
+// │     Type     │                           Layout                               │
+// ├──────────────┼────────────────────────────────────────────────────────────────┤
+// │ Uncompressed │ 8 bytes for every remaining timestamp                          │
+// ├──────────────┼────────────────────────────────────────────────────────────────┤
+// │ Packed       │ simple8b words of the zigzagged first delta and delta-of-deltas│
+// ├──────────────┼────────────────────────────────────────────────────────────────┤
+// │ RLE          │ uvarint zigzagged delta followed by a uvarint count of deltas  │
+// └──────────────┴────────────────────────────────────────────────────────────────┘
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jwilder/encoding/bitops"
+	"github.com/jwilder/encoding/simple8b"
+)
+
+const (
+	// timeUncompressed stores every timestamp as 8 bytes.
+	timeUncompressed = 0
+
+	// timePacked stores delta-of-deltas using simple8b.
+	timePacked = 1
+
+	// timeRLE stores a run of timestamps at a constant interval.
+	timeRLE = 2
+
+	// maxDivisorExp is the largest power of ten tried as a common divisor.
+	maxDivisorExp = 12
+)
+
+// Encoder converts a stream of 64bit timestamps to a compressed byte slice.
+type Encoder struct {
+	ts    []int64
+	bytes []byte
+}
+
+// NewEncoder returns an Encoder able to convert timestamps to compressed byte slices
+func NewEncoder() *Encoder {
+	return &Encoder{
+		ts: make([]int64, 0, 64),
+	}
+}
+
+// Reset clears the encoder so it can be reused.
+func (e *Encoder) Reset() {
+	e.ts = e.ts[:0]
+	e.bytes = e.bytes[:0]
+}
+
+// Write adds a timestamp to the encoder.
+func (e *Encoder) Write(v int64) {
+	e.ts = append(e.ts, v)
+}
+
+// WriteTime adds t to the encoder as Unix nanoseconds.
+func (e *Encoder) WriteTime(t time.Time) {
+	e.Write(t.UnixNano())
+}
+
+// Bytes returns the encoded timestamps written to the encoder.
+func (e *Encoder) Bytes() ([]byte, error) {
+	e.bytes = encode(e.bytes[:0], e.ts)
+	return e.bytes, nil
+}
+
+// EncodeAll returns the compressed form of the timestamps in src.
+func EncodeAll(src []int64) ([]byte, error) {
+	return encode(nil, src), nil
+}
+
+// EncodeTimes returns the compressed form of the times in src as Unix nanoseconds.
+func EncodeTimes(src []time.Time) ([]byte, error) {
+	ts := make([]int64, len(src))
+	for i, t := range src {
+		ts[i] = t.UnixNano()
+	}
+	return encode(nil, ts), nil
+}
+
+// DecodeAll writes the uncompressed timestamps from src to dst.  It returns the number
+// of timestamps written or an error.
+func DecodeAll(dst []int64, src []byte) (int, error) {
+	n, err := CountBytes(src)
+	if err != nil {
+		return 0, err
+	}
+	if len(dst) < n {
+		return 0, fmt.Errorf("dst too small: need %d values, have %d", n, len(dst))
+	}
+
+	dec := NewDecoder(src)
+	j := 0
+	for dec.Next() {
+		dst[j] = dec.Read()
+		j++
+	}
+	return j, dec.Err()
+}
+
+// CountBytes returns the number of timestamps encoded in the byte slice
+func CountBytes(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	typ, _, err := header(b)
+	if err != nil {
+		return 0, err
+	}
+	b = b[9:]
+
+	switch typ {
+	case timeUncompressed:
+		if len(b)%8 != 0 {
+			return 0, fmt.Errorf("invalid slice len remaining: %v", len(b)%8)
+		}
+		return 1 + len(b)/8, nil
+	case timePacked:
+		n, err := simple8b.CountBytes(b)
+		if err != nil {
+			return 0, err
+		}
+		return 1 + n, nil
+	default:
+		_, count, err := readRLE(b)
+		if err != nil {
+			return 0, err
+		}
+		return 1 + int(count), nil
+	}
+}
+
+// Decoder converts a compressed byte slice to a stream of 64bit timestamps.
+type Decoder struct {
+	bytes []byte
+	typ   byte
+	div   int64
+
+	// current timestamp and delta
+	v     int64
+	delta int64
+
+	// first reports whether the first timestamp has not yet been read
+	first bool
+
+	// remaining number of RLE deltas
+	remaining uint64
+
+	dec *simple8b.Decoder
+	err error
+}
+
+// NewDecoder returns a Decoder from a byte slice
+func NewDecoder(b []byte) *Decoder {
+	d := &Decoder{}
+	d.SetBytes(b)
+	return d
+}
+
+// SetBytes resets the decoder to read from b.
+func (d *Decoder) SetBytes(b []byte) {
+	d.bytes = nil
+	d.first = false
+	d.delta = 0
+	d.remaining = 0
+	d.err = nil
+
+	if len(b) == 0 {
+		return
+	}
+
+	typ, exp, err := header(b)
+	if err != nil {
+		d.err = err
+		return
+	}
+
+	d.typ = typ
+	d.div = pow10(exp)
+	d.v = int64(binary.BigEndian.Uint64(b[1:9]))
+	d.first = true
+	d.bytes = b[9:]
+
+	switch typ {
+	case timeUncompressed:
+		if len(d.bytes)%8 != 0 {
+			d.err = fmt.Errorf("invalid slice len remaining: %v", len(d.bytes)%8)
+		}
+	case timePacked:
+		// The simple8b decoder stops at a partial word, so it is rejected here.
+		if len(d.bytes)%8 != 0 {
+			d.err = fmt.Errorf("invalid slice len remaining: %v", len(d.bytes)%8)
+			return
+		}
+		if d.dec == nil {
+			d.dec = simple8b.NewDecoder(d.bytes)
+		} else {
+			d.dec.SetBytes(d.bytes)
+		}
+	case timeRLE:
+		delta, count, err := readRLE(d.bytes)
+		if err != nil {
+			d.err = err
+			return
+		}
+		d.delta = bitops.ZigZagDecode64(delta) * d.div
+		d.remaining = count
+	}
+}
+
+// Next returns true if there are remaining timestamps to be read.  Successive
+// calls to Next advance the current element pointer.
+func (d *Decoder) Next() bool {
+	if d.err != nil {
+		return false
+	}
+
+	if d.first {
+		d.first = false
+		return true
+	}
+
+	switch d.typ {
+	case timeUncompressed:
+		if len(d.bytes) < 8 {
+			return false
+		}
+		d.v = int64(binary.BigEndian.Uint64(d.bytes[:8]))
+		d.bytes = d.bytes[8:]
+	case timePacked:
+		if d.dec == nil || !d.dec.Next() {
+			if d.dec != nil {
+				d.err = d.dec.Err()
+			}
+			return false
+		}
+		d.delta += bitops.ZigZagDecode64(d.dec.Read()) * d.div
+		d.v += d.delta
+	case timeRLE:
+		if d.remaining == 0 {
+			return false
+		}
+		d.remaining--
+		d.v += d.delta
+	default:
+		return false
+	}
+	return true
+}
+
+// Read returns the current timestamp.  Successive calls to Read return the same
+// value.
+func (d *Decoder) Read() int64 {
+	return d.v
+}
+
+// ReadTime returns the current timestamp as a time.Time.
+func (d *Decoder) ReadTime() time.Time {
+	return time.Unix(0, d.v)
+}
+
+// Err returns the first error encountered while decoding.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// encode appends the compressed form of src to dst.
+func encode(dst []byte, src []int64) []byte {
+	if len(src) == 0 {
+		return dst
+	}
+
+	// Deltas are computed with wrapping arithmetic so any sequence of timestamps
+	// can be encoded, although only sorted ones compress well.
+	deltas := make([]uint64, len(src)-1)
+	rle := true
+	for i := 1; i < len(src); i++ {
+		deltas[i-1] = uint64(src[i] - src[i-1])
+		if deltas[i-1] != deltas[0] {
+			rle = false
+		}
+	}
+
+	exp := divisorExp(deltas)
+	div := pow10(exp)
+
+	var b [binary.MaxVarintLen64]byte
+	if rle && len(deltas) > 0 {
+		dst = appendHeader(dst, timeRLE, exp, src[0])
+		delta := bitops.ZigZagEncode64(int64(deltas[0]) / div)
+		dst = append(dst, b[:binary.PutUvarint(b[:], delta)]...)
+		return append(dst, b[:binary.PutUvarint(b[:], uint64(len(deltas)))]...)
+	}
+
+	var prev int64
+	for i, v := range deltas {
+		delta := int64(v) / div
+		deltas[i] = bitops.ZigZagEncode64(delta - prev)
+		if deltas[i] > simple8b.MaxValue {
+			return appendUncompressed(dst, src)
+		}
+		prev = delta
+	}
+
+	words, err := simple8b.EncodeAll(deltas)
+	if err != nil {
+		return appendUncompressed(dst, src)
+	}
+
+	dst = appendHeader(dst, timePacked, exp, src[0])
+	for _, w := range words {
+		binary.BigEndian.PutUint64(b[:8], w)
+		dst = append(dst, b[:8]...)
+	}
+	return dst
+}
+
+// appendUncompressed appends every timestamp in src to dst as 8 bytes.
+func appendUncompressed(dst []byte, src []int64) []byte {
+	dst = appendHeader(dst, timeUncompressed, 0, src[0])
+	var b [8]byte
+	for _, v := range src[1:] {
+		binary.BigEndian.PutUint64(b[:], uint64(v))
+		dst = append(dst, b[:]...)
+	}
+	return dst
+}
+
+// appendHeader appends the type, divisor and first timestamp to dst.
+func appendHeader(dst []byte, typ byte, exp int, first int64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(first))
+	dst = append(dst, typ<<4|byte(exp))
+	return append(dst, b[:]...)
+}
+
+// header returns the type and divisor exponent of the encoded timestamps in b.
+func header(b []byte) (typ byte, exp int, err error) {
+	if len(b) < 9 {
+		return 0, 0, fmt.Errorf("header truncated: %d bytes", len(b))
+	}
+
+	typ, exp = b[0]>>4, int(b[0]&0xf)
+	if typ > timeRLE || exp > maxDivisorExp {
+		return 0, 0, fmt.Errorf("invalid header: %x", b[0])
+	}
+	return typ, exp, nil
+}
+
+// readRLE returns the zigzagged, scaled delta and count of an RLE encoded run.
+func readRLE(b []byte) (delta, count uint64, err error) {
+	delta, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, 0, fmt.Errorf("invalid RLE delta")
+	}
+
+	count, m := binary.Uvarint(b[n:])
+	if m <= 0 || n+m != len(b) || count > math.MaxInt32 {
+		return 0, 0, fmt.Errorf("invalid RLE count")
+	}
+	return delta, count, nil
+}
+
+// divisorExp returns the exponent of the largest power of ten dividing every delta.
+func divisorExp(deltas []uint64) int {
+	exp := maxDivisorExp
+	for _, v := range deltas {
+		// Negative deltas are divided using their two's complement magnitude.
+		if int64(v) < 0 {
+			v = -v
+		}
+
+		for exp > 0 && v%uint64(pow10(exp)) != 0 {
+			exp--
+		}
+		if exp == 0 {
+			break
+		}
+	}
+	return exp
+}
+
+// pow10 returns 10 to the power of exp.
+func pow10(exp int) int64 {
+	v := int64(1)
+	for i := 0; i < exp; i++ {
+		v *= 10
+	}
+	return v
+}
@@ -0,0 +1,258 @@
+package timestamp_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/jwilder/encoding/timestamp"
+)
+
+func Test_Encode_NoValues(t *testing.T) {
+	testEncode(t, nil)
+}
+
+func Test_Encode_Single(t *testing.T) {
+	testEncode(t, []int64{1444238178437870000})
+}
+
+func Test_Encode_Constant_Interval(t *testing.T) {
+	in := make([]int64, 1000)
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+	for i := range in {
+		in[i] = start + int64(i)*int64(10*time.Second)
+	}
+	testEncode(t, in)
+
+	b, _ := timestamp.EncodeAll(in)
+	// header, first timestamp and two short uvarints
+	if got, max := len(b), 13; got > max {
+		t.Fatalf("Encoded len too large: got %v, exp <= %v", got, max)
+	}
+}
+
+func Test_Encode_Descending_Interval(t *testing.T) {
+	in := make([]int64, 10)
+	for i := range in {
+		in[i] = 1e18 - int64(i)*1e9
+	}
+	testEncode(t, in)
+}
+
+func Test_Encode_Jitter(t *testing.T) {
+	rand.Seed(1)
+	in := make([]int64, 500)
+	v := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+	for i := range in {
+		v += int64(time.Second) + int64(rand.Intn(100))*int64(time.Millisecond)
+		in[i] = v
+	}
+	testEncode(t, in)
+
+	b, _ := timestamp.EncodeAll(in)
+	if got, max := len(b), len(in)*2; got > max {
+		t.Fatalf("Encoded len too large: got %v, exp <= %v", got, max)
+	}
+}
+
+func Test_Encode_Unsorted(t *testing.T) {
+	rand.Seed(1)
+	in := make([]int64, 100)
+	for i := range in {
+		in[i] = rand.Int63()
+	}
+	testEncode(t, in)
+}
+
+func Test_Encode_Extremes(t *testing.T) {
+	testEncode(t, []int64{math.MinInt64, math.MaxInt64, 0, math.MinInt64, -1})
+}
+
+func Test_Encode_Times(t *testing.T) {
+	in := []time.Time{
+		time.Unix(0, 1000),
+		time.Unix(10, 0),
+		time.Unix(20, 5),
+	}
+	b, err := timestamp.EncodeTimes(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := timestamp.NewDecoder(b)
+	for i := range in {
+		if !dec.Next() {
+			t.Fatalf("Expected Next to return true")
+		}
+		if got := dec.ReadTime(); !got.Equal(in[i]) {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], got)
+		}
+	}
+	if dec.Next() {
+		t.Fatalf("Expected Next to return false")
+	}
+}
+
+func Test_Decode_Invalid(t *testing.T) {
+	dec := timestamp.NewDecoder([]byte{0xf0, 0, 0, 0, 0, 0, 0, 0, 0})
+	if dec.Next() {
+		t.Fatalf("Expected Next to return false but it returned true")
+	}
+	if dec.Err() == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
+func Test_Decode_PackedTrailingBytes(t *testing.T) {
+	encoded, _ := timestamp.EncodeAll([]int64{1, 2, 4, 8, 16, 32})
+	if encoded[0]>>4 != 1 {
+		t.Fatalf("Expected packed encoding, got type %d", encoded[0]>>4)
+	}
+
+	for _, n := range []int{1, 7} {
+		b := append(append([]byte(nil), encoded...), make([]byte, n)...)
+		if _, err := timestamp.DecodeAll(make([]int64, 64), b); err == nil {
+			t.Fatalf("Expected error for %d trailing bytes, got nil", n)
+		}
+
+		dec := timestamp.NewDecoder(b)
+		for dec.Next() {
+		}
+		if dec.Err() == nil {
+			t.Fatalf("Expected decoder error for %d trailing bytes, got nil", n)
+		}
+	}
+}
+
+func testEncode(t *testing.T, in []int64) {
+	enc := timestamp.NewEncoder()
+	for _, v := range in {
+		enc.Write(v)
+	}
+
+	encoded, err := enc.Bytes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := timestamp.NewDecoder(encoded)
+	i := 0
+	for dec.Next() {
+		if i >= len(in) {
+			t.Fatalf("Decoded too many values: got %v, exp %v", i, len(in))
+		}
+
+		if dec.Read() != in[i] {
+			t.Fatalf("Decoded[%d] != %v, got %v", i, in[i], dec.Read())
+		}
+		i += 1
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if exp, got := len(in), i; got != exp {
+		t.Fatalf("Decode len mismatch: exp %v, got %v", exp, got)
+	}
+
+	got, err := timestamp.CountBytes(encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error in Count: %v", err)
+	}
+	if got != len(in) {
+		t.Fatalf("Count mismatch: got %v, exp %v", got, len(in))
+	}
+
+	decoded := make([]int64, len(in))
+	n, err := timestamp.DecodeAll(decoded, encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != len(in) {
+		t.Fatalf("DecodeAll len mismatch: exp %v, got %v", len(in), n)
+	}
+	for i := range in {
+		if decoded[i] != in[i] {
+			t.Fatalf("DecodeAll[%d] != %v, got %v", i, in[i], decoded[i])
+		}
+	}
+}
+
+func FuzzDecodeAll(f *testing.F) {
+	for _, in := range [][]int64{nil, {1, 2, 3}, {1500000000, 1500000010, 1500000020}, {5, -3, math.MaxInt64, math.MinInt64}} {
+		encoded, _ := timestamp.EncodeAll(in)
+		f.Add(encoded)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		n, err := timestamp.CountBytes(b)
+		if err != nil || n > 1<<20 {
+			return
+		}
+
+		dst := make([]int64, n)
+		got, err := timestamp.DecodeAll(dst, b)
+		if err != nil {
+			// The count is read from the header, so the values may still be invalid.
+			dec := timestamp.NewDecoder(b)
+			for dec.Next() {
+			}
+			if dec.Err() == nil {
+				t.Fatalf("Expected Decoder error, got nil")
+			}
+			return
+		}
+		if got != n {
+			t.Fatalf("DecodeAll mismatch: got %v, exp %v", got, n)
+		}
+		if n > 0 {
+			if _, err := timestamp.DecodeAll(make([]int64, n-1), b); err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+		}
+
+		dec := timestamp.NewDecoder(b)
+		i := 0
+		for dec.Next() {
+			if i >= n || dec.Read() != dst[i] {
+				t.Fatalf("Decoder mismatch at %d", i)
+			}
+			i++
+		}
+		if dec.Err() != nil || i != n {
+			t.Fatalf("Decoder len mismatch: got %v %v, exp %v", i, dec.Err(), n)
+		}
+	})
+}
+
+func BenchmarkEncode(b *testing.B) {
+	x := make([]int64, 1024)
+	for i := 0; i < len(x); i++ {
+		x[i] = int64(i)*int64(time.Second) + int64(i%3)
+	}
+
+	b.ResetTimer()
+	b.SetBytes(int64(len(x) * 8))
+	for i := 0; i < b.N; i++ {
+		timestamp.EncodeAll(x)
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	x := make([]int64, 1024)
+	for i := 0; i < len(x); i++ {
+		x[i] = int64(i)*int64(time.Second) + int64(i%3)
+	}
+	y, _ := timestamp.EncodeAll(x)
+
+	b.ResetTimer()
+	b.SetBytes(int64(len(x) * 8))
+
+	dec := timestamp.NewDecoder(y)
+	for i := 0; i < b.N; i++ {
+		dec.SetBytes(y)
+		for dec.Next() {
+		}
+	}
+}